@@ -0,0 +1,265 @@
+// Package classify guesses what kind of device a host is and which OS it
+// runs from its vendor, open ports, banners, TTL and name.
+package classify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	_ "embed"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+//go:embed rules.json
+var defaultRules []byte
+
+// Config is a rules file: the built-in rules.json, or classify.json next to
+// ports.json, whose rules are checked before the built-in ones.
+type Config struct {
+	Devices map[string]Kind `json:"devices"`
+	Rules   []Rule          `json:"rules"`
+}
+
+// Kind is how a device type is shown.
+type Kind struct {
+	Icon  string `json:"icon"`
+	Label string `json:"label"`
+}
+
+// Rule sets a device type, an OS or both when every condition it lists
+// holds. A condition holds when any one of its values matches.
+type Rule struct {
+	Device string `json:"device,omitempty"`
+	OS     string `json:"os,omitempty"`
+
+	Vendor []string `json:"vendor,omitempty"` // Substrings of the OUI vendor.
+	Ports  []int    `json:"ports,omitempty"`  // Open TCP ports.
+	UDP    []int    `json:"udp,omitempty"`    // Answering UDP ports.
+	Banner []string `json:"banner,omitempty"` // Substrings of banners, products, page titles, mDNS services and UPnP descriptions.
+	Name   []string `json:"name,omitempty"`   // Substrings of the host name.
+	TTL    int      `json:"ttl,omitempty"`    // Initial TTL guessed from replies: 64, 128 or 255.
+}
+
+// Rules is a checked rule set ready to classify hosts.
+type Rules struct {
+	devices map[string]Kind
+	rules   []Rule
+}
+
+func ConfigPath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "nibble", "classify.json"), nil
+}
+
+// LoadConfig reads the user's rules, a missing file means none.
+func LoadConfig() (Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Load combines the user's rules with the built-in ones.
+func Load() (*Rules, error) {
+	user, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading classify rules: %w", err)
+	}
+	return Compile(user)
+}
+
+// Compile checks user rules and puts them ahead of the built-in ones.
+// User device types replace built-in types with the same key.
+func Compile(user Config) (*Rules, error) {
+	var builtin Config
+	if err := json.Unmarshal(defaultRules, &builtin); err != nil {
+		return nil, err
+	}
+
+	devices := builtin.Devices
+	for key, kind := range user.Devices {
+		devices[key] = kind
+	}
+	for i, rule := range user.Rules {
+		if err := checkRule(rule, devices); err != nil {
+			return nil, fmt.Errorf("invalid classify rule %d: %w", i+1, err)
+		}
+	}
+	return &Rules{
+		devices: devices,
+		rules:   append(slices.Clone(user.Rules), builtin.Rules...),
+	}, nil
+}
+
+func checkRule(rule Rule, devices map[string]Kind) error {
+	if rule.Device == "" && rule.OS == "" {
+		return errors.New("sets neither device nor os")
+	}
+	if _, ok := devices[rule.Device]; rule.Device != "" && !ok {
+		return fmt.Errorf("unknown device %q", rule.Device)
+	}
+	if len(rule.Vendor)+len(rule.Ports)+len(rule.UDP)+len(rule.Banner)+len(rule.Name) == 0 && rule.TTL == 0 {
+		return errors.New("has no conditions")
+	}
+	switch rule.TTL {
+	case 0, 64, 128, 255:
+	default:
+		return fmt.Errorf("ttl %d is not 64, 128 or 255", rule.TTL)
+	}
+	return nil
+}
+
+// Classify fills in host.Device. The first matching rule that sets a device
+// type decides it, and the first that sets an OS decides that, so TTL-only
+// rules at the end act as fallbacks. A nil Rules leaves hosts alone.
+func (r *Rules) Classify(host *scanner.HostResult) {
+	if r == nil {
+		return
+	}
+	facts := newFacts(host)
+	var device scanner.Device
+	for _, rule := range r.rules {
+		if (rule.Device == "" || device.Type != "") && (rule.OS == "" || device.OS != "") {
+			continue
+		}
+		if !facts.match(rule) {
+			continue
+		}
+		if rule.Device != "" && device.Type == "" {
+			kind := r.devices[rule.Device]
+			device.Type, device.Label, device.Icon = rule.Device, kind.Label, kind.Icon
+		}
+		if rule.OS != "" && device.OS == "" {
+			device.OS = rule.OS
+		}
+		if device.Type != "" && device.OS != "" {
+			break
+		}
+	}
+	if device != (scanner.Device{}) {
+		host.Device = &device
+	}
+}
+
+// facts are the lowercased parts of a host that rules match against.
+type facts struct {
+	vendor string
+	name   string
+	texts  []string
+	tcp    []int
+	udp    []int
+	ttl    int
+}
+
+func newFacts(host *scanner.HostResult) facts {
+	f := facts{
+		vendor: strings.ToLower(host.Hardware),
+		name:   strings.ToLower(host.Name),
+		ttl:    initialTTL(host.TTL),
+	}
+	for _, p := range host.Ports {
+		if p.Proto == scanner.ProtoUDP {
+			f.udp = append(f.udp, p.Port)
+		} else {
+			f.tcp = append(f.tcp, p.Port)
+		}
+		f.texts = append(f.texts, p.Banner, p.Product)
+		if p.HTTP != nil {
+			f.texts = append(f.texts, p.HTTP.Title, p.HTTP.Server, p.HTTP.PoweredBy, p.HTTP.Realm)
+		}
+		if p.TLS != nil {
+			f.texts = append(f.texts, p.TLS.Subject, p.TLS.Issuer)
+		}
+	}
+	for _, m := range host.MDNS {
+		f.texts = append(f.texts, m.Type, m.Instance, m.Model, m.FriendlyName)
+	}
+	if u := host.UPnP; u != nil {
+		f.texts = append(f.texts, u.DeviceType, u.FriendlyName, u.Manufacturer, u.ModelName, u.Server)
+	}
+	for i, text := range f.texts {
+		f.texts[i] = strings.ToLower(text)
+	}
+	return f
+}
+
+func (f facts) match(rule Rule) bool {
+	if len(rule.Vendor) > 0 && !containsAny([]string{f.vendor}, rule.Vendor) {
+		return false
+	}
+	if len(rule.Name) > 0 && !containsAny([]string{f.name}, rule.Name) {
+		return false
+	}
+	if len(rule.Banner) > 0 && !containsAny(f.texts, rule.Banner) {
+		return false
+	}
+	if len(rule.Ports) > 0 && !overlaps(f.tcp, rule.Ports) {
+		return false
+	}
+	if len(rule.UDP) > 0 && !overlaps(f.udp, rule.UDP) {
+		return false
+	}
+	return rule.TTL == 0 || rule.TTL == f.ttl
+}
+
+// initialTTL rounds an observed TTL up to the usual starting values:
+// 64 for Linux, macOS and most embedded systems, 128 for Windows and 255
+// for network gear. Zero means the TTL is unknown.
+func initialTTL(ttl int) int {
+	switch {
+	case ttl <= 0:
+		return 0
+	case ttl <= 64:
+		return 64
+	case ttl <= 128:
+		return 128
+	default:
+		return 255
+	}
+}
+
+func containsAny(texts, needles []string) bool {
+	for _, text := range texts {
+		if text == "" {
+			continue
+		}
+		for _, needle := range needles {
+			if strings.Contains(text, strings.ToLower(needle)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func overlaps(open, wanted []int) bool {
+	for _, port := range wanted {
+		if slices.Contains(open, port) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package classify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+func classifyHost(t *testing.T, rules *Rules, host scanner.HostResult) scanner.Device {
+	t.Helper()
+	rules.Classify(&host)
+	if host.Device == nil {
+		return scanner.Device{}
+	}
+	return *host.Device
+}
+
+func TestClassifyBuiltin(t *testing.T) {
+	rules, err := Compile(Config{})
+	if err != nil {
+		t.Fatalf("built-in rules: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		host     scanner.HostResult
+		wantType string
+		wantOS   string
+	}{
+		{
+			name:     "camera by vendor",
+			host:     scanner.HostResult{Hardware: "Hangzhou Hikvision Digital Technology Co.,Ltd."},
+			wantType: "camera",
+			wantOS:   "Embedded Linux",
+		},
+		{
+			name:     "printer by port",
+			host:     scanner.HostResult{Ports: []scanner.PortInfo{{Port: 80}, {Port: 9100}}},
+			wantType: "printer",
+		},
+		{
+			name: "printer by snmp banner",
+			host: scanner.HostResult{Ports: []scanner.PortInfo{
+				{Port: 161, Proto: scanner.ProtoUDP, Banner: "HP ETHERNET MULTI-ENVIRONMENT,JETDIRECT"},
+			}},
+			wantType: "printer",
+		},
+		{
+			name: "raspberry pi running home assistant",
+			host: scanner.HostResult{
+				Hardware: "Raspberry Pi Trading Ltd",
+				Ports:    []scanner.PortInfo{{Port: 8123, Banner: "Home Assistant"}},
+			},
+			wantType: "hub",
+			wantOS:   "Linux",
+		},
+		{
+			name: "ubuntu server",
+			host: scanner.HostResult{Ports: []scanner.PortInfo{
+				{Port: 22, Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"},
+			}},
+			wantType: "server",
+			wantOS:   "Ubuntu Linux",
+		},
+		{
+			name: "router from page title",
+			host: scanner.HostResult{Ports: []scanner.PortInfo{
+				{Port: 80, HTTP: &scanner.HTTPInfo{Title: "OpenWrt - LuCI"}},
+			}},
+			wantType: "router",
+			wantOS:   "OpenWrt",
+		},
+		{
+			name:     "windows by ttl only",
+			host:     scanner.HostResult{TTL: 127},
+			wantType: "",
+			wantOS:   "Windows",
+		},
+		{
+			name: "chromecast by mdns service",
+			host: scanner.HostResult{MDNS: []scanner.MDNSService{
+				{Type: "_googlecast._tcp", Port: 8009, Model: "Chromecast Ultra"},
+			}},
+			wantType: "media",
+		},
+		{
+			name: "tv by upnp device type",
+			host: scanner.HostResult{UPnP: &scanner.UPnPDevice{
+				DeviceType: "urn:schemas-upnp-org:device:MediaRenderer:1",
+				ModelName:  "QE55Q80T",
+			}},
+			wantType: "media",
+		},
+		{
+			name:     "phone by name",
+			host:     scanner.HostResult{Name: "Janes-iPhone.local"},
+			wantType: "phone",
+		},
+		{
+			name: "unknown",
+			host: scanner.HostResult{Hardware: "Acme Widgets"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyHost(t, rules, tt.host)
+			if got.Type != tt.wantType || got.OS != tt.wantOS {
+				t.Fatalf("got %+v, want type %q os %q", got, tt.wantType, tt.wantOS)
+			}
+			if got.Type != "" && (got.Label == "" || got.Icon == "") {
+				t.Fatalf("device %q has no label or icon", got.Type)
+			}
+		})
+	}
+}
+
+func TestClassifyUserRulesComeFirst(t *testing.T) {
+	rules, err := Compile(Config{
+		Devices: map[string]Kind{"plc": {Icon: "🏭", Label: "PLC"}},
+		Rules: []Rule{
+			{Device: "plc", OS: "Siemens", Ports: []int{102}},
+			{Device: "camera", Vendor: []string{"raspberry pi"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	got := classifyHost(t, rules, scanner.HostResult{Ports: []scanner.PortInfo{{Port: 102}}})
+	if got != (scanner.Device{Type: "plc", Label: "PLC", Icon: "🏭", OS: "Siemens"}) {
+		t.Fatalf("user device type: %+v", got)
+	}
+
+	// The user's camera rule wins over the built-in sbc rule, the OS still
+	// comes from the built-in one.
+	got = classifyHost(t, rules, scanner.HostResult{Hardware: "Raspberry Pi Trading Ltd"})
+	if got.Type != "camera" || got.OS != "Linux" {
+		t.Fatalf("user rule precedence: %+v", got)
+	}
+}
+
+func TestCompileRejectsBadRules(t *testing.T) {
+	tests := map[string]Rule{
+		"unknown device": {Device: "toaster", Ports: []int{80}},
+		"sets neither":   {Ports: []int{80}},
+		"no conditions":  {Device: "camera"},
+		"ttl":            {OS: "Linux", TTL: 60},
+	}
+	for want, rule := range tests {
+		_, err := Compile(Config{Rules: []Rule{rule}})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("rule %+v: got %v, want error containing %q", rule, err, want)
+		}
+	}
+}
+
+func TestNilRulesLeaveHostAlone(t *testing.T) {
+	var rules *Rules
+	host := scanner.HostResult{TTL: 128}
+	rules.Classify(&host)
+	if host.Device != nil {
+		t.Fatalf("nil rules classified a host: %+v", host.Device)
+	}
+}
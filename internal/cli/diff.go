@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/diff"
+	"github.com/backendsystems/nibble/internal/history"
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+// diffRecord is the serialized form of a diff between two stored scans.
+type diffRecord struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	diff.Report
+}
+
+// Diff compares two stored scans, given by ID or "latest~N", and writes
+// the hosts that are new, gone or changed in the newer one.
+func Diff(olderRef, newerRef, output string, out io.Writer) error {
+	store, err := history.Open()
+	if err != nil {
+		return err
+	}
+	older, err := store.Find(olderRef)
+	if err != nil {
+		return err
+	}
+	newer, err := store.Find(newerRef)
+	if err != nil {
+		return err
+	}
+	devices, err := loadInventory()
+	if err != nil {
+		return err
+	}
+	devices.ApplyAll(older.Hosts)
+	devices.ApplyAll(newer.Hosts)
+	report := diff.Compare(older.Hosts, newer.Hosts)
+
+	switch output {
+	case "", OutputText:
+		return writeDiffText(out, older, newer, report)
+	case OutputJSON, OutputJSONL:
+		return json.NewEncoder(out).Encode(diffRecord{From: older.ID, To: newer.ID, Report: report})
+	default:
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+}
+
+// writeDiffText prints one block per host, marked + for new, - for gone
+// and ~ for changed, with what changed indented below it.
+func writeDiffText(out io.Writer, older, newer history.Scan, report diff.Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "from %s  %s\n", older.ID, older.Summary())
+	fmt.Fprintf(&b, "to   %s  %s\n", newer.ID, newer.Summary())
+	if report.Empty() {
+		b.WriteString("No changes\n")
+	}
+	writeHost := func(mark string, host scanner.HostResult, changes []string) {
+		lines := strings.Split(scanner.FormatHost(host), "\n")
+		fmt.Fprintf(&b, "%s %s\n", mark, lines[0])
+		for _, line := range changes {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	for _, host := range report.New {
+		// Everything about a new host is news.
+		writeHost("+", host, strings.Split(scanner.FormatHost(host), "\n")[1:])
+	}
+	for _, host := range report.Gone {
+		writeHost("-", host, nil)
+	}
+	for _, change := range report.Changed {
+		writeHost("~", change.Host, change.Lines())
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
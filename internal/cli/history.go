@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/backendsystems/nibble/internal/history"
+)
+
+// History lists the stored scans, newest first, or with an ID or
+// "latest~N" reference writes that scan's hosts in the requested output
+// format.
+func History(id, output string, out io.Writer) error {
+	store, err := history.Open()
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		scans, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(scans) == 0 {
+			_, err := fmt.Fprintln(out, "No scans saved yet")
+			return err
+		}
+		for _, scan := range scans {
+			if _, err := fmt.Fprintf(out, "%-18s %s\n", scan.ID, scan.Summary()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	scan, err := store.Find(id)
+	if err != nil {
+		return err
+	}
+	writer, err := newHostWriter(output, out)
+	if err != nil {
+		return err
+	}
+	devices, err := loadInventory()
+	if err != nil {
+		return err
+	}
+	devices.ApplyAll(scan.Hosts)
+	for _, host := range scan.Hosts {
+		if err := writer.Host(hostRecord{HostResult: host, Time: scan.Finished}); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/notify"
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/watch"
+)
+
+// NotifyTest sends a made up host_joined event to every configured sink,
+// whatever event types the config selects, to check the setup.
+func NotifyTest(ctx context.Context, out io.Writer) error {
+	path, err := notify.ConfigPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := notify.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading notify config: %w", err)
+	}
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		return fmt.Errorf("notify config: %w", err)
+	}
+	if len(notifier.Sinks) == 0 {
+		_, err := fmt.Fprintln(out, "No notifications configured in", path)
+		return err
+	}
+
+	host := scanner.HostResult{IP: "192.0.2.10", MAC: "02:00:00:00:00:10", Name: "nibble-test", Ports: []scanner.PortInfo{{Port: 22}}}
+	event := watch.Event{Type: watch.EventJoined, Time: time.Now(), IP: host.IP, MAC: host.MAC, Host: &host}
+	notifier.Events = []string{event.Type}
+	if err := notifier.Notify(ctx, []watch.Event{event}); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Sent a test notification to %d sinks\n", len(notifier.Sinks))
+	return err
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+// Output formats accepted by --output.
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputJSONL = "jsonl"
+)
+
+// hostRecord is the serialized form of a found host.
+type hostRecord struct {
+	scanner.HostResult
+	Phase string    `json:"phase,omitempty"` // Empty for hosts read back from history.
+	Time  time.Time `json:"time"`
+}
+
+// hostWriter receives hosts as they are found and flushes them on Close.
+// A host sent again with the same IP carries more detail than before.
+type hostWriter interface {
+	Host(record hostRecord) error
+	Close() error
+}
+
+func newHostWriter(format string, out io.Writer) (hostWriter, error) {
+	switch format {
+	case "", OutputText:
+		return &textWriter{out: out}, nil
+	case OutputJSON:
+		return &jsonWriter{out: out}, nil
+	case OutputJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(out)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// textWriter prints one host per block, with port lines indented below it.
+type textWriter struct {
+	out   io.Writer
+	hosts []scanner.HostResult
+}
+
+func (w *textWriter) Host(record hostRecord) error {
+	w.hosts = scanner.UpsertHost(w.hosts, record.HostResult)
+	return nil
+}
+
+func (w *textWriter) Close() error {
+	for _, host := range w.hosts {
+		lines := strings.Split(scanner.FormatHost(host), "\n")
+		if _, err := fmt.Fprintln(w.out, lines[0]); err != nil {
+			return err
+		}
+		for _, line := range lines[1:] {
+			if _, err := fmt.Fprintf(w.out, "    %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonWriter prints all hosts as a single JSON array once the scan is done.
+type jsonWriter struct {
+	out   io.Writer
+	hosts []scanner.HostResult
+	found map[string]hostRecord // The phase and time each IP was last sent with.
+}
+
+func (w *jsonWriter) Host(record hostRecord) error {
+	w.hosts = scanner.UpsertHost(w.hosts, record.HostResult)
+	if w.found == nil {
+		w.found = make(map[string]hostRecord)
+	}
+	w.found[record.IP] = hostRecord{Phase: record.Phase, Time: record.Time}
+	return nil
+}
+
+func (w *jsonWriter) Close() error {
+	records := make([]hostRecord, 0, len(w.hosts))
+	for _, host := range w.hosts {
+		record := w.found[host.IP]
+		record.HostResult = host
+		records = append(records, record)
+	}
+	enc := json.NewEncoder(w.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// jsonlWriter streams one JSON object per host as soon as it is found, and
+// another whenever the host is sent again.
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Host(record hostRecord) error {
+	return w.enc.Encode(record)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,334 @@
+// Package cli runs scans without the terminal UI, for cron, CI and SSH sessions.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/classify"
+	"github.com/backendsystems/nibble/internal/demo"
+	"github.com/backendsystems/nibble/internal/history"
+	"github.com/backendsystems/nibble/internal/inventory"
+	"github.com/backendsystems/nibble/internal/ports"
+	"github.com/backendsystems/nibble/internal/scan"
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
+)
+
+// Options selects what a headless scan targets.
+type Options struct {
+	Iface   string // Interface to scan from, optional when Targets is set.
+	Targets string // Target spec, defaults to the interface's first IPv4, then global IPv6, network.
+	Output  string // One of OutputText, OutputJSON or OutputJSONL.
+	Exclude string // Extra IPs, CIDRs and MAC prefixes to skip, on top of the saved exclude list.
+	Confirm bool   // Allow sweeps larger than the saved host limit.
+}
+
+// Scan runs a single scan and writes the found hosts to out.
+// Cancelling ctx stops the scan early and still writes the hosts found so far.
+func Scan(ctx context.Context, networkScanner scanner.Scanner, ifaces []net.Interface, addrsByIface map[string][]net.Addr, opts Options, out io.Writer) error {
+	writer, err := newHostWriter(opts.Output, out)
+	if err != nil {
+		return err
+	}
+	if err := applyPortConfig(networkScanner); err != nil {
+		return err
+	}
+	if err := applyClassifier(networkScanner); err != nil {
+		return err
+	}
+
+	ifaceName, targets, err := resolveTarget(ifaces, addrsByIface, opts)
+	if err != nil {
+		return err
+	}
+	if err := applyScope(networkScanner, targets, opts); err != nil {
+		return err
+	}
+
+	devices, err := loadInventory()
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	hosts, err := writeHosts(ctx, networkScanner, ifaceName, targets, devices, writer)
+	if err != nil {
+		return err
+	}
+	return saveScan(networkScanner, ifaceName, targets, started, hosts)
+}
+
+// Analyze reads a capture file instead of scanning and writes the hosts
+// found in it like Scan does. opts.Targets limits the hosts, the interface
+// and the sweep limit do not apply.
+func Analyze(ctx context.Context, path string, opts Options, out io.Writer) error {
+	writer, err := newHostWriter(opts.Output, out)
+	if err != nil {
+		return err
+	}
+	capture, err := scan.OpenCapture(path)
+	if err != nil {
+		return err
+	}
+	if err := applyClassifier(capture); err != nil {
+		return err
+	}
+	if err := applyScope(capture, opts.Targets, opts); err != nil {
+		return err
+	}
+	devices, err := loadInventory()
+	if err != nil {
+		return err
+	}
+	if _, err := writeHosts(ctx, capture, "", opts.Targets, devices, writer); err != nil {
+		return err
+	}
+	if capture.Err != nil {
+		return fmt.Errorf("reading %s: %w", path, capture.Err)
+	}
+	return nil
+}
+
+// writeHosts runs the scan, writes each host as it is found, labeled from
+// the device inventory, and returns them all. Cancelling ctx stops the scan
+// early and still writes the hosts found so far.
+func writeHosts(ctx context.Context, networkScanner scanner.Scanner, ifaceName, targets string, devices *inventory.Inventory, writer hostWriter) ([]scanner.HostResult, error) {
+	progressChan := make(chan scanner.ProgressUpdate, 256)
+	go networkScanner.ScanNetwork(ctx, ifaceName, targets, progressChan)
+
+	var hosts []scanner.HostResult
+	var writeErr error
+	for update := range progressChan {
+		var host *scanner.HostResult
+		phase := ""
+		switch p := update.(type) {
+		case scanner.NeighborProgress:
+			host, phase = p.Host, scanner.PhaseNeighbor
+		case scanner.SweepProgress:
+			host, phase = p.Host, scanner.PhaseSweep
+		}
+		// Keep draining after a write error so the scanner never blocks.
+		if host == nil || writeErr != nil {
+			continue
+		}
+		labeled := *host
+		devices.Apply(&labeled)
+		hosts = scanner.UpsertHost(hosts, labeled)
+		writeErr = writer.Host(hostRecord{HostResult: labeled, Phase: phase, Time: time.Now()})
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("scan interrupted")
+	}
+	return hosts, nil
+}
+
+// saveScan records a completed scan in the history store. Demo scans are
+// not kept, their hosts would show up in diffs against the real network.
+func saveScan(networkScanner scanner.Scanner, ifaceName, targets string, started time.Time, hosts []scanner.HostResult) error {
+	netScanner, ok := networkScanner.(*scan.NetScanner)
+	if !ok {
+		return nil
+	}
+	store, err := history.Open()
+	if err != nil {
+		return err
+	}
+	record := &history.Scan{
+		Iface:    ifaceName,
+		Targets:  targets,
+		Ports:    netScanner.Ports,
+		UDPPorts: netScanner.UDPPorts,
+		Started:  started,
+		Finished: time.Now(),
+		Hosts:    hosts,
+	}
+	if err := store.Save(record); err != nil {
+		return fmt.Errorf("saving scan history: %w", err)
+	}
+	return nil
+}
+
+// loadInventory reads the user's device labels, shown with every host.
+func loadInventory() (*inventory.Inventory, error) {
+	devices, err := inventory.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading inventory: %w", err)
+	}
+	return devices, nil
+}
+
+// applyPortConfig honors the port selection saved from the TUI.
+func applyPortConfig(networkScanner scanner.Scanner) error {
+	cfg, err := ports.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading port config: %w", err)
+	}
+	resolvedPorts, err := ports.Resolve(cfg.Pack(), cfg.Custom, "")
+	if err != nil {
+		return err
+	}
+	resolvedUDP, err := ports.ResolveUDP(cfg.Pack(), cfg.Custom, "")
+	if err != nil {
+		return err
+	}
+	switch typed := networkScanner.(type) {
+	case *scan.NetScanner:
+		typed.Ports = resolvedPorts
+		typed.UDPPorts = resolvedUDP
+	case *demo.DemoScanner:
+		typed.Ports = resolvedPorts
+		typed.UDPPorts = resolvedUDP
+	}
+	return nil
+}
+
+// applyClassifier loads the device rules, including the user's classify.json.
+func applyClassifier(networkScanner scanner.Scanner) error {
+	rules, err := classify.Load()
+	if err != nil {
+		return err
+	}
+	switch typed := networkScanner.(type) {
+	case *scan.NetScanner:
+		typed.Classifier = rules
+	case *demo.DemoScanner:
+		typed.Classifier = rules
+	case *scan.CaptureScanner:
+		typed.Classifier = rules
+	}
+	return nil
+}
+
+// applyScope honors the saved exclude list and refuses oversized sweeps
+// unless they were confirmed.
+func applyScope(networkScanner scanner.Scanner, targets string, opts Options) error {
+	cfg, err := scope.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading scope config: %w", err)
+	}
+	entries := append(cfg.Exclude, strings.Split(opts.Exclude, ",")...)
+	rules, err := scope.Compile(entries)
+	if err != nil {
+		return err
+	}
+	switch typed := networkScanner.(type) {
+	case *scan.NetScanner:
+		typed.Exclude = rules
+		if typed.Passive > 0 {
+			return nil // Nothing is sent, so there is no sweep to limit.
+		}
+	case *demo.DemoScanner:
+		typed.Exclude = rules
+	case *scan.CaptureScanner:
+		typed.Exclude = rules
+		return nil // A capture is only read.
+	}
+
+	hosts, err := scope.SweepSize(targets, rules)
+	if err != nil {
+		return err
+	}
+	if err := scope.CheckLimit(hosts, cfg.Limit(), opts.Confirm); err != nil {
+		return fmt.Errorf("%w, pass --confirm-large to scan anyway", err)
+	}
+	return nil
+}
+
+// resolveTarget picks the interface and target spec to scan from the options.
+func resolveTarget(ifaces []net.Interface, addrsByIface map[string][]net.Addr, opts Options) (string, string, error) {
+	var first netip.Addr
+	if opts.Targets != "" {
+		if prefix, ok := target.Prefix6(opts.Targets); ok {
+			first = prefix.Addr()
+		} else {
+			targets, err := target.Parse(opts.Targets)
+			if err != nil {
+				return "", "", err
+			}
+			first = targets.First()
+		}
+		// Link-local targets such as fe80::1%eth0 name their interface.
+		zone, err := target.Zone(opts.Targets)
+		if err != nil {
+			return "", "", err
+		}
+		if opts.Iface == "" {
+			opts.Iface = zone
+		} else if err := target.CheckZone(opts.Targets, opts.Iface); err != nil {
+			return "", "", err
+		}
+	}
+
+	if opts.Iface != "" {
+		for _, iface := range ifaces {
+			if iface.Name != opts.Iface {
+				continue
+			}
+			if opts.Targets != "" {
+				return iface.Name, opts.Targets, nil
+			}
+			cidr := scanner.ScanTarget(addrsByIface[iface.Name])
+			if cidr == "" {
+				return "", "", fmt.Errorf("interface %s has no valid IPv4 or IPv6 addresses", iface.Name)
+			}
+			return iface.Name, cidr, nil
+		}
+		return "", "", fmt.Errorf("interface %s not found", opts.Iface)
+	}
+
+	if name := attachedIface(ifaces, addrsByIface, first); name != "" {
+		return name, opts.Targets, nil
+	}
+	if name := attachedIface(ifaces, addrsByIface, routeSource(first)); name != "" {
+		return name, opts.Targets, nil
+	}
+	return "", "", fmt.Errorf("no interface routes to %s, use --iface", first)
+}
+
+// attachedIface returns the interface whose subnet contains ip.
+func attachedIface(ifaces []net.Interface, addrsByIface map[string][]net.Addr, ip netip.Addr) string {
+	if !ip.IsValid() {
+		return ""
+	}
+	for _, iface := range ifaces {
+		for _, addr := range addrsByIface[iface.Name] {
+			prefix, err := netip.ParsePrefix(addr.String())
+			if err == nil && prefix.Masked().Contains(ip) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
+
+// routeSource asks the OS which local address it would use to reach ip.
+// Connecting a UDP socket picks a route without sending any packets.
+func routeSource(ip netip.Addr) netip.Addr {
+	if !ip.IsValid() {
+		return netip.Addr{}
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(ip.String(), "9"))
+	if err != nil {
+		return netip.Addr{}
+	}
+	defer conn.Close()
+	local, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return netip.Addr{}
+	}
+	addr, _ := netip.AddrFromSlice(local.IP)
+	return addr.Unmap()
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/notify"
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/watch"
+)
+
+// NewWatcher prepares a watch of the network the options select, with the
+// same saved ports, device rules and scope as a headless scan.
+func NewWatcher(networkScanner scanner.Scanner, ifaces []net.Interface, addrsByIface map[string][]net.Addr, opts Options, interval time.Duration, misses int) (*watch.Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", interval)
+	}
+	if err := applyPortConfig(networkScanner); err != nil {
+		return nil, err
+	}
+	if err := applyClassifier(networkScanner); err != nil {
+		return nil, err
+	}
+	ifaceName, targets, err := resolveTarget(ifaces, addrsByIface, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyScope(networkScanner, targets, opts); err != nil {
+		return nil, err
+	}
+	devices, err := loadInventory()
+	if err != nil {
+		return nil, err
+	}
+	w := &watch.Watcher{
+		Scanner:   networkScanner,
+		Iface:     ifaceName,
+		Targets:   targets,
+		Interval:  interval,
+		Inventory: &watch.Inventory{Misses: misses},
+		Devices:   devices,
+	}
+
+	cfg, err := notify.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading notify config: %w", err)
+	}
+	if !cfg.Empty() {
+		notifier, err := notify.New(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("notify config: %w", err)
+		}
+		w.Notifier = notifier
+	}
+	return w, nil
+}
+
+// Watch rescans until ctx ends and writes every event as a JSON line. A
+// failed write, such as a closed pipe, stops the watch. Notifications that
+// could not be sent are reported on errOut and do not.
+func Watch(ctx context.Context, w *watch.Watcher, out, errOut io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rounds := make(chan watch.Round)
+	go w.Run(ctx, rounds)
+
+	enc := json.NewEncoder(out)
+	var writeErr error
+	for round := range rounds {
+		if round.Err != nil {
+			fmt.Fprintln(errOut, "Error sending notifications:", round.Err)
+		}
+		for _, event := range round.Events {
+			if writeErr != nil {
+				break
+			}
+			if writeErr = enc.Encode(event); writeErr != nil {
+				cancel()
+			}
+		}
+	}
+	return writeErr
+}
@@ -1,5 +1,7 @@
 package demo
 
+import "github.com/backendsystems/nibble/internal/scanner"
+
 type Port struct {
 	Port   int
 	Banner string
@@ -8,24 +10,44 @@ type Port struct {
 type Host struct {
 	IP       string
 	Hardware string
+	Names    []scanner.HostName
 	Ports    []Port
+	UDP      []Port
+	MDNS     []scanner.MDNSService
+	UPnP     *scanner.UPnPDevice
 }
 
 // Hosts defines fake hosts with real MAC addresses so demo uses the OUI lookup.
 var Hosts = []Host{
 	{
 		IP: "192.168.1.1", Hardware: "f0:9f:c2:1a:22:01",
+		Names: []scanner.HostName{{Name: "unifi.lan", Source: scanner.NameDNS}},
 		Ports: []Port{
 			{22, "SSH-2.0-OpenSSH_8.4"},
 			{80, "UniFi OS 3.2.12"},
 			{443, ""},
 		},
+		UDP: []Port{
+			{53, "dnsmasq-2.89"},
+			{1900, "Linux/5.4 UPnP/1.0 MiniUPnPd/2.3.3"},
+		},
+		UPnP: &scanner.UPnPDevice{
+			FriendlyName: "UniFi Dream Machine",
+			Manufacturer: "Ubiquiti Networks",
+			ModelName:    "UDM-Pro",
+			DeviceType:   "urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+			Server:       "Linux/5.4 UPnP/1.0 MiniUPnPd/2.3.3",
+		},
 	},
 	{
 		IP: "192.168.1.50", Hardware: "48:b0:2d:5e:a3:10",
+		Names: []scanner.HostName{
+			{Name: "db01.lan", Source: scanner.NameDNS},
+			{Name: "db01.local", Source: scanner.NameMDNS},
+		},
 		Ports: []Port{
 			{22, "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"},
-			{5432, ""},
+			{5432, "PostgreSQL, ssl, scram auth"},
 		},
 	},
 	{
@@ -36,18 +58,29 @@ var Hosts = []Host{
 	},
 	{
 		IP: "192.168.1.100", Hardware: "f0:ee:7a:ab:cd:ef",
+		Names: []scanner.HostName{{Name: "OFFICE-PRINTER", Source: scanner.NameNetBIOS}},
 		Ports: []Port{
 			{80, "Apache/2.4.56"},
 			{443, ""},
 			{8080, "Jetty 11.0.15"},
 		},
+		UDP: []Port{
+			{161, "HP ETHERNET MULTI-ENVIRONMENT,ROM none,JETDIRECT,JD153"},
+		},
+		MDNS: []scanner.MDNSService{
+			{Instance: "HP LaserJet M404", Type: "_ipp._tcp", Host: "office-printer.local", Port: 631, Model: "HP LaserJet Pro M404dn"},
+		},
 	},
 	{
 		IP: "10.0.0.42", Hardware: "d8:3a:dd:11:22:33",
+		Names: []scanner.HostName{{Name: "raspberrypi.local", Source: scanner.NameMDNS}},
 		Ports: []Port{
 			{22, "SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u2"},
 			{80, "lighttpd/1.4.69"},
-			{1883, ""},
+			{1883, "mosquitto 2.0.18, anonymous access"},
+		},
+		MDNS: []scanner.MDNSService{
+			{Instance: "Home", Type: "_home-assistant._tcp", Host: "raspberrypi.local", Port: 8123},
 		},
 	},
 }
@@ -1,46 +1,69 @@
 package demo
 
 import (
-	"net"
+	"context"
+	"net/netip"
 	"time"
 
+	"github.com/backendsystems/nibble/internal/classify"
 	"github.com/backendsystems/nibble/internal/ports"
 	"github.com/backendsystems/nibble/internal/scan"
 	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
 )
 
 // DemoScanner simulates a scan with fake host data.
 type DemoScanner struct {
-	Ports []int
+	Ports      []int
+	UDPPorts   []int
+	Exclude    *scope.Rules
+	Classifier *classify.Rules
 }
 
-func (s *DemoScanner) ScanNetwork(ifaceName, subnet string, progressChan chan<- scanner.ProgressUpdate) {
-	_, ipnet, err := net.ParseCIDR(subnet)
-	if err != nil {
-		close(progressChan)
-		return
+func (s *DemoScanner) ScanNetwork(ctx context.Context, ifaceName, spec string, progressChan chan<- scanner.ProgressUpdate) {
+	defer close(progressChan)
+
+	// Large IPv6 prefixes are not swept, matching the real scanner.
+	var contains func(netip.Addr) bool
+	totalHosts := 0
+	if prefix, ok := target.Prefix6(spec); ok {
+		contains = prefix.Contains
+	} else {
+		targets, err := target.Parse(spec)
+		if err != nil {
+			return
+		}
+		if s.Exclude != nil {
+			targets = targets.Exclude(s.Exclude.Prefixes)
+		}
+		contains = targets.Contains
+		totalHosts = targets.Count()
 	}
 
-	totalHosts := scanner.TotalScanHosts(ipnet)
-	selected := selectedPorts(s.Ports)
-	selectedSet := make(map[int]struct{}, len(selected))
-	for _, p := range selected {
-		selectedSet[p] = struct{}{}
-	}
-	hostOnly := len(s.Ports) == 0
+	selectedSet := portSet(selectedPorts(s.Ports, ports.DefaultPorts()))
+	selectedUDP := portSet(selectedPorts(s.UDPPorts, ports.DefaultUDPPorts()))
+	hostOnly := len(s.Ports) == 0 && len(s.UDPPorts) == 0
 	hosts := hostsForInterface(ifaceName)
 	neighborDelay, sweepDelay := demoDelaysForInterface(ifaceName)
 
-	// Pick which demo hosts belong to this subnet.
+	// Pick which demo hosts belong to the targets.
 	var subnetHosts []scanner.HostResult
 	for _, h := range hosts {
-		ip := net.ParseIP(h.IP)
-		if ip == nil || !ipnet.Contains(ip) {
+		addr, err := netip.ParseAddr(h.IP)
+		if err != nil || !contains(addr) || s.Exclude.Excludes(h.IP, h.Hardware) {
 			continue
 		}
 		resolved := scanner.HostResult{
 			IP:       h.IP,
+			MAC:      h.Hardware,
 			Hardware: scan.VendorFromMac(h.Hardware),
+			Names:    h.Names,
+			MDNS:     h.MDNS,
+			UPnP:     h.UPnP,
+		}
+		if len(h.Names) > 0 {
+			resolved.Name = h.Names[0].Name
 		}
 		if !hostOnly {
 			for _, p := range h.Ports {
@@ -52,10 +75,21 @@ func (s *DemoScanner) ScanNetwork(ifaceName, subnet string, progressChan chan<-
 					Banner: p.Banner,
 				})
 			}
+			for _, p := range h.UDP {
+				if _, ok := selectedUDP[p.Port]; !ok {
+					continue
+				}
+				resolved.Ports = append(resolved.Ports, scanner.PortInfo{
+					Port:   p.Port,
+					Proto:  scanner.ProtoUDP,
+					Banner: p.Banner,
+				})
+			}
 			if len(resolved.Ports) == 0 {
 				continue
 			}
 		}
+		s.Classifier.Classify(&resolved)
 		subnetHosts = append(subnetHosts, resolved)
 	}
 
@@ -71,9 +105,11 @@ func (s *DemoScanner) ScanNetwork(ifaceName, subnet string, progressChan chan<-
 	neighbors := subnetHosts[:neighborCount]
 	remaining := subnetHosts[neighborCount:]
 	for i, h := range neighbors {
-		time.Sleep(neighborDelay)
+		if !sleep(ctx, neighborDelay) {
+			return
+		}
 		progressChan <- scanner.NeighborProgress{
-			Host:       scanner.FormatHost(h),
+			Host:       &h,
 			TotalHosts: totalHosts,
 			Seen:       i + 1,
 			Total:      neighborCount,
@@ -95,11 +131,13 @@ func (s *DemoScanner) ScanNetwork(ifaceName, subnet string, progressChan chan<-
 	hostIdx := 0
 
 	for i := 1; i <= totalHosts; i++ {
-		time.Sleep(sweepDelay)
+		if !sleep(ctx, sweepDelay) {
+			return
+		}
 
-		host := ""
+		var host *scanner.HostResult
 		if hostInterval > 0 && hostIdx < len(remaining) && i == hostInterval*(hostIdx+1) {
-			host = scanner.FormatHost(remaining[hostIdx])
+			host = &remaining[hostIdx]
 			hostIdx++
 		}
 
@@ -110,8 +148,18 @@ func (s *DemoScanner) ScanNetwork(ifaceName, subnet string, progressChan chan<-
 			Total:      totalHosts,
 		}
 	}
+}
 
-	close(progressChan)
+// sleep waits for d and reports false if ctx was cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 func hostsForInterface(ifaceName string) []Host {
@@ -128,9 +176,17 @@ func demoDelaysForInterface(ifaceName string) (neighborDelay, sweepDelay time.Du
 	return 180 * time.Millisecond, 10 * time.Millisecond
 }
 
-func selectedPorts(configured []int) []int {
+func selectedPorts(configured, defaults []int) []int {
 	if configured != nil {
 		return configured
 	}
-	return ports.DefaultPorts()
+	return defaults
+}
+
+func portSet(list []int) map[int]struct{} {
+	set := make(map[int]struct{}, len(list))
+	for _, p := range list {
+		set[p] = struct{}{}
+	}
+	return set
 }
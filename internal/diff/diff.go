@@ -0,0 +1,178 @@
+// Package diff compares two scans of a network: hosts that joined or left,
+// hosts that moved to another IP, and ports that opened, closed or changed
+// their banner.
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+// Report is what changed from an older scan to a newer one. Hosts are in
+// the order of the scan they come from.
+type Report struct {
+	New     []scanner.HostResult `json:"new"`
+	Gone    []scanner.HostResult `json:"gone"`
+	Changed []HostChange         `json:"changed"`
+}
+
+// Empty reports whether the scans found the same hosts and services.
+func (r Report) Empty() bool {
+	return len(r.New) == 0 && len(r.Gone) == 0 && len(r.Changed) == 0
+}
+
+// HostChange is a host found by both scans that is not the same as before.
+type HostChange struct {
+	Host    scanner.HostResult `json:"host"`             // As the newer scan found it.
+	OldIP   string             `json:"old_ip,omitempty"` // Set when the host's MAC moved to another IP.
+	Opened  []scanner.PortInfo `json:"opened,omitempty"`
+	Closed  []scanner.PortInfo `json:"closed,omitempty"`
+	Banners []BannerChange     `json:"banners,omitempty"`
+}
+
+// BannerChange is a service that answers differently than before, e.g.
+// after an upgrade.
+type BannerChange struct {
+	Port scanner.PortInfo `json:"port"` // As the newer scan found it.
+	Old  string           `json:"old"`
+	New  string           `json:"new"`
+}
+
+// Lines describes each change on its own line.
+func (c HostChange) Lines() []string {
+	var lines []string
+	if c.OldIP != "" {
+		lines = append(lines, fmt.Sprintf("ip %s -> %s", c.OldIP, c.Host.IP))
+	}
+	if len(c.Opened) > 0 {
+		lines = append(lines, "opened "+portLabels(c.Opened))
+	}
+	if len(c.Closed) > 0 {
+		lines = append(lines, "closed "+portLabels(c.Closed))
+	}
+	for _, b := range c.Banners {
+		lines = append(lines, fmt.Sprintf("port %s: %q -> %q", b.Port.Label(), b.Old, b.New))
+	}
+	return lines
+}
+
+// Compare matches the hosts of two scans as Match does and reports what
+// changed.
+func Compare(older, newer []scanner.HostResult) Report {
+	var report Report
+	matched := make([]bool, len(older))
+	for j, i := range Match(older, newer) {
+		host := newer[j]
+		if i < 0 {
+			report.New = append(report.New, host)
+			continue
+		}
+		matched[i] = true
+		if change, changed := CompareHost(older[i], host); changed {
+			report.Changed = append(report.Changed, change)
+		}
+	}
+	for i, host := range older {
+		if !matched[i] {
+			report.Gone = append(report.Gone, host)
+		}
+	}
+	return report
+}
+
+// Match pairs the hosts of two scans by MAC, or by IP when either side has
+// no MAC, as for routed hosts. A known MAC on both sides that differs means
+// another device took over the address: the old one is gone and the new one
+// is new. It returns the index in older of each newer host, or -1 for hosts
+// that are new. An IP may appear more than once in older, for a device that
+// left and the one that took over its address.
+func Match(older, newer []scanner.HostResult) []int {
+	byMAC := make(map[string]int)
+	byIP := make(map[string][]int)
+	for i, host := range older {
+		if mac := macKey(host.MAC); mac != "" {
+			byMAC[mac] = i
+		}
+		byIP[host.IP] = append(byIP[host.IP], i)
+	}
+
+	matches := make([]int, len(newer))
+	matched := make([]bool, len(older))
+	for j, host := range newer {
+		mac := macKey(host.MAC)
+		i, ok := byMAC[mac]
+		if !ok || matched[i] {
+			i = slices.IndexFunc(byIP[host.IP], func(i int) bool {
+				return !matched[i] && (mac == "" || macKey(older[i].MAC) == "")
+			})
+			if ok = i >= 0; ok {
+				i = byIP[host.IP][i]
+			}
+		}
+		if !ok {
+			matches[j] = -1
+			continue
+		}
+		matched[i] = true
+		matches[j] = i
+	}
+	return matches
+}
+
+// CompareHost reports what changed between two scans of the same host.
+func CompareHost(older, newer scanner.HostResult) (HostChange, bool) {
+	change := HostChange{Host: newer}
+	if older.IP != newer.IP {
+		change.OldIP = older.IP
+	}
+
+	oldPorts := make(map[string]scanner.PortInfo, len(older.Ports))
+	for _, p := range older.Ports {
+		oldPorts[p.Label()] = p
+	}
+	seen := make(map[string]bool, len(newer.Ports))
+	for _, p := range newer.Ports {
+		seen[p.Label()] = true
+		old, ok := oldPorts[p.Label()]
+		if !ok {
+			change.Opened = append(change.Opened, p)
+			continue
+		}
+		// A banner that could not be read this time is not a change.
+		if oldBanner, newBanner := banner(old), banner(p); oldBanner != "" && newBanner != "" && oldBanner != newBanner {
+			change.Banners = append(change.Banners, BannerChange{Port: p, Old: oldBanner, New: newBanner})
+		}
+	}
+	for _, p := range older.Ports {
+		if !seen[p.Label()] {
+			change.Closed = append(change.Closed, p)
+		}
+	}
+
+	changed := change.OldIP != "" || len(change.Opened) > 0 || len(change.Closed) > 0 || len(change.Banners) > 0
+	return change, changed
+}
+
+// banner is what identifies the service on a port: its banner, or the
+// product and version a protocol probe found.
+func banner(p scanner.PortInfo) string {
+	if p.Banner != "" {
+		return p.Banner
+	}
+	return strings.TrimSpace(p.Product + " " + p.Version)
+}
+
+func macKey(mac string) string {
+	return strings.ToLower(mac)
+}
+
+func portLabels(ports []scanner.PortInfo) string {
+	labels := make([]string, 0, len(ports))
+	for _, p := range ports {
+		labels = append(labels, p.Label())
+	}
+	return strings.Join(labels, ",")
+}
@@ -0,0 +1,83 @@
+package diff
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+func TestCompare(t *testing.T) {
+	older := []scanner.HostResult{
+		{IP: "192.168.1.1", MAC: "aa:00:00:00:00:01", Ports: []scanner.PortInfo{{Port: 80}, {Port: 443}}},
+		{IP: "192.168.1.10", MAC: "aa:00:00:00:00:10", Ports: []scanner.PortInfo{{Port: 22, Banner: "SSH-2.0-OpenSSH_8.9"}}},
+		{IP: "192.168.1.20", MAC: "aa:00:00:00:00:20"},
+		{IP: "192.168.1.30", MAC: "aa:00:00:00:00:30"},
+		{IP: "10.0.0.5", Ports: []scanner.PortInfo{{Port: 53, Proto: scanner.ProtoUDP}}},
+	}
+	newer := []scanner.HostResult{
+		{IP: "192.168.1.1", MAC: "AA:00:00:00:00:01", Ports: []scanner.PortInfo{{Port: 80}, {Port: 443}}},
+		{IP: "192.168.1.10", MAC: "aa:00:00:00:00:10", Ports: []scanner.PortInfo{{Port: 22, Banner: "SSH-2.0-OpenSSH_9.6"}, {Port: 8080}}},
+		{IP: "192.168.1.21", MAC: "aa:00:00:00:00:20"},                                               // Same device, new lease.
+		{IP: "192.168.1.30", MAC: "aa:00:00:00:00:99"},                                               // Another device on the old address.
+		{IP: "10.0.0.5", Ports: []scanner.PortInfo{{Port: 53, Proto: scanner.ProtoUDP}, {Port: 53}}}, // Routed, matched by IP.
+	}
+
+	report := Compare(older, newer)
+	if len(report.New) != 1 || report.New[0].MAC != "aa:00:00:00:00:99" {
+		t.Fatalf("new: %+v", report.New)
+	}
+	if len(report.Gone) != 1 || report.Gone[0].MAC != "aa:00:00:00:00:30" {
+		t.Fatalf("gone: %+v", report.Gone)
+	}
+	if len(report.Changed) != 3 {
+		t.Fatalf("changed: %+v", report.Changed)
+	}
+
+	ssh := report.Changed[0]
+	want := []string{"opened 8080", `port 22: "SSH-2.0-OpenSSH_8.9" -> "SSH-2.0-OpenSSH_9.6"`}
+	if !reflect.DeepEqual(ssh.Lines(), want) {
+		t.Fatalf("ssh host lines %q, want %q", ssh.Lines(), want)
+	}
+	if moved := report.Changed[1]; moved.OldIP != "192.168.1.20" || moved.Host.IP != "192.168.1.21" {
+		t.Fatalf("moved host: %+v", moved)
+	}
+	if routed := report.Changed[2]; len(routed.Opened) != 1 || routed.Opened[0].Label() != "53" {
+		t.Fatalf("routed host: %+v", routed)
+	}
+}
+
+func TestCompareIgnoresMissingBanners(t *testing.T) {
+	older := []scanner.HostResult{{IP: "192.168.1.5", Ports: []scanner.PortInfo{{Port: 3306, Product: "MariaDB", Version: "10.11"}}}}
+	newer := []scanner.HostResult{{IP: "192.168.1.5", Ports: []scanner.PortInfo{{Port: 3306}}}}
+	if report := Compare(older, newer); !report.Empty() {
+		t.Fatalf("report %+v", report)
+	}
+
+	closed := Compare(older, []scanner.HostResult{{IP: "192.168.1.5"}})
+	if len(closed.Changed) != 1 || closed.Changed[0].Lines()[0] != "closed 3306" {
+		t.Fatalf("closed: %+v", closed)
+	}
+}
+
+func TestMatchPrefersUnmatchedIP(t *testing.T) {
+	// A watch keeps the device that left next to the one that took its IP.
+	older := []scanner.HostResult{
+		{IP: "192.168.1.5", MAC: "bb:00:00:00:00:05"},
+		{IP: "192.168.1.5", MAC: "aa:00:00:00:00:05"},
+		{IP: "10.0.0.1"},
+		{IP: "10.0.0.1"},
+	}
+	newer := []scanner.HostResult{
+		{IP: "192.168.1.5", MAC: "BB:00:00:00:00:05"},
+		{IP: "10.0.0.1"},
+		{IP: "10.0.0.1"},
+		{IP: "192.168.1.5", MAC: "cc:00:00:00:00:05"},
+	}
+	got := Match(older, newer)
+	want := []int{0, 2, 3, -1}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Match = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,259 @@
+// Package history keeps completed scans on disk so they can be browsed,
+// reopened and compared later.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+// Retention defaults: enough for a daily scan over a few months.
+const (
+	DefaultMaxScans = 200
+	DefaultMaxDays  = 180
+)
+
+// Scan is one completed scan as stored on disk.
+type Scan struct {
+	ID       string               `json:"id"`
+	Iface    string               `json:"iface,omitempty"`
+	Targets  string               `json:"targets"`
+	Ports    []int                `json:"ports,omitempty"`
+	UDPPorts []int                `json:"udp_ports,omitempty"`
+	Started  time.Time            `json:"started"`
+	Finished time.Time            `json:"finished"`
+	Hosts    []scanner.HostResult `json:"hosts"`
+}
+
+// Summary is a one line description for listings.
+func (s Scan) Summary() string {
+	iface := s.Iface
+	if iface == "" {
+		iface = "-"
+	}
+	hosts := fmt.Sprintf("%d hosts", len(s.Hosts))
+	if len(s.Hosts) == 1 {
+		hosts = "1 host"
+	}
+	return fmt.Sprintf("%s  %s  %s  %s", s.Started.Local().Format("2006-01-02 15:04"), iface, s.Targets, hosts)
+}
+
+// Config is the saved retention, stored next to ports.json.
+type Config struct {
+	MaxScans int `json:"max_scans"` // Oldest scans beyond this many are removed, 0 uses DefaultMaxScans.
+	MaxDays  int `json:"max_days"`  // Scans older than this are removed, 0 uses DefaultMaxDays.
+}
+
+// Scans returns the configured scan cap, falling back to DefaultMaxScans.
+func (cfg Config) Scans() int {
+	if cfg.MaxScans <= 0 {
+		return DefaultMaxScans
+	}
+	return cfg.MaxScans
+}
+
+// MaxAge returns the configured age cap, falling back to DefaultMaxDays.
+func (cfg Config) MaxAge() time.Duration {
+	days := cfg.MaxDays
+	if days <= 0 {
+		days = DefaultMaxDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func ConfigPath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "nibble", "history.json"), nil
+}
+
+func LoadConfig() (Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Store is a directory with one JSON file per scan.
+type Store struct {
+	Dir    string
+	Config Config
+}
+
+// Open returns the store in the user's config dir with the saved retention.
+func Open() (*Store, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading history config: %w", err)
+	}
+	return &Store{Dir: filepath.Join(base, "nibble", "history"), Config: cfg}, nil
+}
+
+// Save stores a scan under a new ID taken from its start time, then
+// removes the scans that fall outside the retention limits.
+func (s *Store) Save(scan *Scan) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	base := scan.Started.Local().Format("20060102-150405")
+	scan.ID = base
+	for n := 2; s.exists(scan.ID); n++ {
+		scan.ID = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	data, err := json.MarshalIndent(scan, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(s.path(scan.ID), data, 0o644); err != nil {
+		return err
+	}
+	return s.Prune(time.Now())
+}
+
+// List returns every stored scan, newest first.
+func (s *Store) List() ([]Scan, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var scans []Scan
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		scan, err := s.Load(id)
+		if err != nil {
+			continue // A damaged file should not hide the rest.
+		}
+		scans = append(scans, scan)
+	}
+	sort.SliceStable(scans, func(i, j int) bool {
+		if !scans[i].Started.Equal(scans[j].Started) {
+			return scans[i].Started.After(scans[j].Started)
+		}
+		return scans[i].ID > scans[j].ID
+	})
+	return scans, nil
+}
+
+// Load reads one scan by ID.
+func (s *Store) Load(id string) (Scan, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return Scan{}, fmt.Errorf("invalid scan id: %q", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Scan{}, fmt.Errorf("no scan %s in history", id)
+	}
+	if err != nil {
+		return Scan{}, err
+	}
+	var scan Scan
+	if err := json.Unmarshal(data, &scan); err != nil {
+		return Scan{}, fmt.Errorf("reading scan %s: %w", id, err)
+	}
+	scan.ID = id
+	return scan, nil
+}
+
+// Find loads a scan by ID, or by "latest" for the newest scan and
+// "latest~N" for the Nth one before it.
+func (s *Store) Find(ref string) (Scan, error) {
+	back, ok := strings.CutPrefix(ref, "latest")
+	if !ok {
+		return s.Load(ref)
+	}
+	n := 0
+	if back != "" {
+		steps, ok := strings.CutPrefix(back, "~")
+		var err error
+		if n, err = strconv.Atoi(steps); !ok || err != nil || n < 0 {
+			return Scan{}, fmt.Errorf("invalid scan reference: %q", ref)
+		}
+	}
+	scans, err := s.List()
+	if err != nil {
+		return Scan{}, err
+	}
+	if n >= len(scans) {
+		return Scan{}, fmt.Errorf("history has %d scans, no %s", len(scans), ref)
+	}
+	return scans[n], nil
+}
+
+// Previous returns the newest scan of the same targets, nil when there is
+// none.
+func (s *Store) Previous(targets string) (*Scan, error) {
+	scans, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, scan := range scans {
+		if scan.Targets == targets {
+			return &scan, nil
+		}
+	}
+	return nil, nil
+}
+
+// Prune removes scans beyond the configured count and age.
+func (s *Store) Prune(now time.Time) error {
+	scans, err := s.List()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-s.Config.MaxAge())
+	for i, scan := range scans {
+		if i < s.Config.Scans() && !scan.Started.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(s.path(scan.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+func (s *Store) exists(id string) bool {
+	_, err := os.Stat(s.path(id))
+	return err == nil
+}
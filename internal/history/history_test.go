@@ -0,0 +1,83 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+func TestStoreSaveListLoad(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+	started := time.Now().Add(-time.Hour)
+
+	first := &Scan{Iface: "eth0", Targets: "192.168.1.0/24", Ports: []int{22, 80}, Started: started, Finished: started.Add(time.Minute),
+		Hosts: []scanner.HostResult{{IP: "192.168.1.1", MAC: "aa:bb:cc:dd:ee:ff", Ports: []scanner.PortInfo{{Port: 80}}}}}
+	second := &Scan{Iface: "eth0", Targets: "192.168.1.0/24", Started: started, Finished: started.Add(2 * time.Minute)}
+	third := &Scan{Iface: "wlan0", Targets: "10.0.0.0/24", Started: started.Add(time.Minute)}
+	for _, scan := range []*Scan{first, second, third} {
+		if err := store.Save(scan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.ID == second.ID || second.ID != first.ID+"-2" {
+		t.Fatalf("ids %q and %q for the same start second", first.ID, second.ID)
+	}
+
+	scans, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 3 || scans[0].ID != third.ID || scans[1].ID != second.ID || scans[2].ID != first.ID {
+		t.Fatalf("list order: %+v", scans)
+	}
+
+	loaded, err := store.Load(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Hosts) != 1 || loaded.Hosts[0].Ports[0].Port != 80 || len(loaded.Ports) != 2 || !loaded.Started.Equal(started) {
+		t.Fatalf("loaded %+v", loaded)
+	}
+
+	if _, err := store.Load("../ports"); err == nil {
+		t.Fatal("loaded a path outside the store")
+	}
+
+	for ref, want := range map[string]string{"latest": third.ID, "latest~2": first.ID, first.ID: first.ID} {
+		scan, err := store.Find(ref)
+		if err != nil || scan.ID != want {
+			t.Fatalf("find %s: %s, %v", ref, scan.ID, err)
+		}
+	}
+	for _, ref := range []string{"latest~3", "latest2", "latest~-1"} {
+		if _, err := store.Find(ref); err == nil {
+			t.Fatalf("found %s", ref)
+		}
+	}
+
+	previous, err := store.Previous("192.168.1.0/24")
+	if err != nil || previous == nil || previous.ID != second.ID {
+		t.Fatalf("previous: %+v, %v", previous, err)
+	}
+	if previous, err := store.Previous("172.16.0.0/12"); err != nil || previous != nil {
+		t.Fatalf("previous of unscanned targets: %+v, %v", previous, err)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	store := &Store{Dir: t.TempDir(), Config: Config{MaxScans: 2, MaxDays: 30}}
+	now := time.Now()
+	for _, age := range []time.Duration{40 * 24 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour} {
+		if err := store.Save(&Scan{Targets: "192.168.1.0/24", Started: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scans, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 2 || !scans[0].Started.Equal(now.Add(-time.Hour)) || !scans[1].Started.Equal(now.Add(-2*time.Hour)) {
+		t.Fatalf("kept %+v", scans)
+	}
+}
@@ -0,0 +1,150 @@
+// Package inventory keeps what the user knows about their devices: a label,
+// owner, tags and notes, keyed by MAC or, for devices without one, by IP.
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+// Device is one entry of the inventory.
+type Device struct {
+	MAC   string   `json:"mac,omitempty"` // Lowercase, the key of every device that has one.
+	IP    string   `json:"ip,omitempty"`  // The key of devices without a MAC, such as routed hosts.
+	Label string   `json:"label,omitempty"`
+	Owner string   `json:"owner,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Notes string   `json:"notes,omitempty"`
+}
+
+// Empty reports whether nothing is noted about the device.
+func (d Device) Empty() bool {
+	return d.Label == "" && d.Owner == "" && len(d.Tags) == 0 && d.Notes == ""
+}
+
+// Key identifies the device: its MAC, or its IP when it has none.
+func (d Device) Key() string {
+	if d.MAC != "" {
+		return strings.ToLower(d.MAC)
+	}
+	return d.IP
+}
+
+// For returns the inventory key of a host, with nothing noted about it.
+func For(host scanner.HostResult) Device {
+	if mac := strings.ToLower(host.MAC); mac != "" {
+		return Device{MAC: mac}
+	}
+	return Device{IP: host.IP}
+}
+
+// Inventory is the saved device list, stored next to ports.json.
+type Inventory struct {
+	Devices []Device `json:"devices"`
+}
+
+func ConfigPath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "nibble", "inventory.json"), nil
+}
+
+// Load reads the saved inventory, which is empty until a device is saved.
+func Load() (*Inventory, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Inventory{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var inv Inventory
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (inv *Inventory) Save() error {
+	path, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Find returns the device a host is, by MAC, or by IP for hosts without one.
+func (inv *Inventory) Find(host scanner.HostResult) (Device, bool) {
+	if i := inv.index(For(host)); i >= 0 {
+		return inv.Devices[i], true
+	}
+	return Device{}, false
+}
+
+// Set adds or replaces a device, and removes it when nothing is noted.
+func (inv *Inventory) Set(device Device) {
+	device.MAC = strings.ToLower(device.MAC)
+	if device.MAC != "" {
+		device.IP = ""
+	}
+	i := inv.index(device)
+	switch {
+	case i >= 0 && device.Empty():
+		inv.Devices = slices.Delete(inv.Devices, i, i+1)
+	case i >= 0:
+		inv.Devices[i] = device
+	case !device.Empty():
+		inv.Devices = append(inv.Devices, device)
+	}
+}
+
+func (inv *Inventory) index(key Device) int {
+	return slices.IndexFunc(inv.Devices, func(d Device) bool { return d.Key() == key.Key() })
+}
+
+// Apply labels a host with what the inventory notes about it, or flags it
+// as unknown. An empty or nil inventory flags nothing, before the user
+// saves a first device every host would be unknown.
+func (inv *Inventory) Apply(host *scanner.HostResult) {
+	if inv == nil {
+		return
+	}
+	host.Label = nil
+	host.Unknown = false
+	device, ok := inv.Find(*host)
+	if !ok {
+		host.Unknown = len(inv.Devices) > 0
+		return
+	}
+	host.Label = &scanner.Label{Name: device.Label, Owner: device.Owner, Tags: device.Tags, Notes: device.Notes}
+}
+
+// ApplyAll labels every host in place.
+func (inv *Inventory) ApplyAll(hosts []scanner.HostResult) {
+	for i := range hosts {
+		inv.Apply(&hosts[i])
+	}
+}
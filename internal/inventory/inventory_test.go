@@ -0,0 +1,71 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+func TestApply(t *testing.T) {
+	inv := &Inventory{}
+	printer := scanner.HostResult{IP: "192.168.1.100", MAC: "00:1B:63:00:00:01", Hardware: "Apple, Inc."}
+	inv.Apply(&printer)
+	if printer.Label != nil || printer.Unknown {
+		t.Fatalf("an empty inventory labeled %+v", printer)
+	}
+
+	inv.Set(Device{MAC: "00:1b:63:00:00:01", Label: "Office printer", Owner: "it", Tags: []string{"office", "printer"}})
+	inv.Set(Device{IP: "10.0.0.5", Label: "VPN gateway"})
+
+	inv.Apply(&printer)
+	if printer.Label == nil || printer.Label.Name != "Office printer" || printer.Unknown {
+		t.Fatalf("printer %+v", printer)
+	}
+	lines := strings.Split(scanner.FormatHost(printer), "\n")
+	want := []string{"192.168.1.100 - Office printer (Apple, Inc.)", "owner it", "tags office, printer"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("printer shown as %q", lines)
+	}
+
+	routed := scanner.HostResult{IP: "10.0.0.5"}
+	inv.Apply(&routed)
+	if routed.Label == nil || routed.Label.Name != "VPN gateway" {
+		t.Fatalf("routed %+v", routed)
+	}
+
+	// An IP entry does not label another device that took the address.
+	stranger := scanner.HostResult{IP: "10.0.0.5", MAC: "24:0a:c4:00:00:01", Hardware: "Espressif Inc."}
+	inv.Apply(&stranger)
+	if stranger.Label != nil || !stranger.Unknown || !strings.HasSuffix(scanner.FormatHost(stranger), "⚠ unknown") {
+		t.Fatalf("stranger %+v", stranger)
+	}
+
+	// Forgetting the printer takes its label away.
+	inv.Set(Device{MAC: "00:1b:63:00:00:01"})
+	inv.Apply(&printer)
+	if len(inv.Devices) != 1 || printer.Label != nil || !printer.Unknown {
+		t.Fatalf("forgotten printer %+v, inventory %+v", printer, inv.Devices)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	inv, err := Load()
+	if err != nil || len(inv.Devices) != 0 {
+		t.Fatalf("load without a file: %+v, %v", inv, err)
+	}
+	inv.Set(Device{MAC: "AA:BB:CC:00:00:01", Label: "NAS", Notes: "rack 2"})
+	if err := inv.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device, ok := loaded.Find(scanner.HostResult{IP: "192.168.1.5", MAC: "aa:bb:cc:00:00:01"}); !ok || device.Label != "NAS" || device.Notes != "rack 2" {
+		t.Fatalf("loaded %+v", loaded.Devices)
+	}
+}
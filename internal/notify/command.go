@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/watch"
+)
+
+// Command runs a local program for every event, with the event in its
+// environment: NIBBLE_EVENT, NIBBLE_TIME, NIBBLE_IP, NIBBLE_MAC,
+// NIBBLE_PORT, NIBBLE_OLD, NIBBLE_NEW, NIBBLE_SUMMARY and NIBBLE_JSON.
+type Command struct {
+	Run []string `json:"run"` // The program and its arguments, not run through a shell.
+}
+
+func (c Command) Send(ctx context.Context, event watch.Event) error {
+	cmd := exec.CommandContext(ctx, c.Run[0], c.Run[1:]...)
+	env, err := eventEnv(event)
+	if err != nil {
+		return err
+	}
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fmt.Errorf("command %s: %w", filepath.Base(c.Run[0]), err)
+	}
+	return nil
+}
+
+func eventEnv(event watch.Event) ([]string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	port := ""
+	if event.Port != nil {
+		port = event.Port.Label()
+	}
+	return []string{
+		"NIBBLE_EVENT=" + event.Type,
+		"NIBBLE_TIME=" + event.Time.Format(time.RFC3339),
+		"NIBBLE_IP=" + event.IP,
+		"NIBBLE_MAC=" + event.MAC,
+		"NIBBLE_PORT=" + port,
+		"NIBBLE_OLD=" + event.Old,
+		"NIBBLE_NEW=" + event.New,
+		"NIBBLE_SUMMARY=" + event.Summary(),
+		"NIBBLE_JSON=" + string(data),
+	}, nil
+}
+
+// Desktop shows events with notify-send.
+type Desktop struct {
+	Path string // notify-send.
+}
+
+func (d Desktop) Send(ctx context.Context, event watch.Event) error {
+	cmd := exec.CommandContext(ctx, d.Path, "--app-name=nibble", Title(event.Type), event.Summary())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fmt.Errorf("notify-send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package notify sends watch events on to webhooks, local commands and the
+// desktop, as configured in notify.json.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/watch"
+)
+
+// DefaultEvents are new hosts and port changes, the events worth a ping.
+var DefaultEvents = []string{watch.EventJoined, watch.EventPortOpened, watch.EventPortClosed}
+
+// Timeout caps how long one sink may take for one event.
+const Timeout = 10 * time.Second
+
+// Config is the saved notification setup, stored next to ports.json.
+type Config struct {
+	Events   []string  `json:"events,omitempty"` // Event types to send, empty uses DefaultEvents.
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	Commands []Command `json:"commands,omitempty"`
+	Desktop  bool      `json:"desktop,omitempty"` // Show desktop notifications when notify-send is installed.
+}
+
+// Empty reports whether the config sends nothing anywhere.
+func (cfg Config) Empty() bool {
+	return len(cfg.Webhooks) == 0 && len(cfg.Commands) == 0 && !cfg.Desktop
+}
+
+func ConfigPath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "nibble", "notify.json"), nil
+}
+
+func LoadConfig() (Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Sink delivers one event somewhere.
+type Sink interface {
+	Send(ctx context.Context, event watch.Event) error
+}
+
+// Notifier sends the events it is configured for to every sink.
+type Notifier struct {
+	Events []string
+	Sinks  []Sink
+}
+
+// New builds the sinks of cfg. Desktop notifications are left out when
+// notify-send is not installed.
+func New(cfg Config) (*Notifier, error) {
+	n := &Notifier{Events: cfg.Events}
+	if len(n.Events) == 0 {
+		n.Events = DefaultEvents
+	}
+	for _, webhook := range cfg.Webhooks {
+		if err := webhook.validate(); err != nil {
+			return nil, err
+		}
+		n.Sinks = append(n.Sinks, webhook)
+	}
+	for _, command := range cfg.Commands {
+		if len(command.Run) == 0 {
+			return nil, errors.New("notify command without run")
+		}
+		n.Sinks = append(n.Sinks, command)
+	}
+	if cfg.Desktop {
+		if path, err := exec.LookPath("notify-send"); err == nil {
+			n.Sinks = append(n.Sinks, Desktop{Path: path})
+		}
+	}
+	return n, nil
+}
+
+// Notify sends every event of a configured type to each sink and returns
+// the errors of all failed deliveries.
+func (n *Notifier) Notify(ctx context.Context, events []watch.Event) error {
+	var errs []error
+	for _, event := range events {
+		if !slices.Contains(n.Events, event.Type) {
+			continue
+		}
+		for _, sink := range n.Sinks {
+			sinkCtx, cancel := context.WithTimeout(ctx, Timeout)
+			if err := sink.Send(sinkCtx, event); err != nil {
+				errs = append(errs, err)
+			}
+			cancel()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Title names the event type for notification headings.
+func Title(eventType string) string {
+	switch eventType {
+	case watch.EventJoined:
+		return "New host"
+	case watch.EventLeft:
+		return "Host left"
+	case watch.EventMoved:
+		return "Host moved"
+	case watch.EventPortOpened:
+		return "Port opened"
+	case watch.EventPortClosed:
+		return "Port closed"
+	case watch.EventBannerChanged:
+		return "Service changed"
+	default:
+		return fmt.Sprintf("nibble %s", eventType)
+	}
+}
@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/watch"
+)
+
+type request struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// stub records every POST it gets.
+func stub(t *testing.T, status int) (*httptest.Server, func() []request) {
+	var mu sync.Mutex
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("body %q: %v", data, err)
+		}
+		mu.Lock()
+		requests = append(requests, request{path: r.URL.Path, header: r.Header, body: body})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request(nil), requests...)
+	}
+}
+
+func testEvents() []watch.Event {
+	now := time.Now()
+	camera := scanner.HostResult{IP: "192.168.1.9", MAC: "aa:00:00:00:00:09"}
+	return []watch.Event{
+		{Type: watch.EventJoined, Time: now, IP: camera.IP, MAC: camera.MAC, Host: &camera},
+		{Type: watch.EventPortClosed, Time: now, IP: "192.168.1.1", Port: &scanner.PortInfo{Port: 23}},
+		{Type: watch.EventScan, Time: now, Hosts: 2},
+	}
+}
+
+func TestWebhookTemplates(t *testing.T) {
+	server, requests := stub(t, http.StatusOK)
+	n, err := New(Config{Webhooks: []Webhook{
+		{URL: server.URL + "/hook", Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{URL: server.URL + "/slack", Template: TemplateSlack},
+		{URL: server.URL + "/discord", Template: TemplateDiscord},
+		{URL: server.URL + "/lan-alerts", Template: TemplateNtfy},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 8 {
+		t.Fatalf("%d requests, want 2 events for 4 webhooks", len(got))
+	}
+	joined, slack, discord, ntfy := got[0], got[1], got[2], got[3]
+	if joined.body["type"] != watch.EventJoined || joined.body["ip"] != "192.168.1.9" || joined.header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("json webhook: %+v", joined)
+	}
+	if text, _ := slack.body["text"].(string); slack.path != "/slack" || !strings.Contains(text, "joined: 192.168.1.9") {
+		t.Errorf("slack webhook: %+v", slack)
+	}
+	if text, _ := discord.body["content"].(string); !strings.Contains(text, "joined: 192.168.1.9") {
+		t.Errorf("discord webhook: %+v", discord)
+	}
+	if ntfy.path != "/" || ntfy.body["topic"] != "lan-alerts" || ntfy.body["title"] != "New host" {
+		t.Errorf("ntfy webhook: %+v", ntfy)
+	}
+	if closed := got[4]; closed.body["type"] != watch.EventPortClosed {
+		t.Errorf("second event: %+v", closed)
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	server, _ := stub(t, http.StatusForbidden)
+	n, err := New(Config{Events: []string{watch.EventPortClosed}, Webhooks: []Webhook{{URL: server.URL + "/T000/B000/secret", Template: TemplateSlack}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(context.Background(), testEvents())
+	if err == nil || !strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("error %v", err)
+	}
+
+	for _, webhook := range []Webhook{{URL: "ftp://example.com"}, {URL: server.URL, Template: "teams"}, {URL: server.URL, Template: TemplateNtfy}} {
+		if _, err := New(Config{Webhooks: []Webhook{webhook}}); err == nil {
+			t.Errorf("accepted %+v", webhook)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event")
+	script := `printf '%s %s %s' "$NIBBLE_EVENT" "$NIBBLE_IP" "$NIBBLE_PORT" >> "$0"`
+	n, err := New(Config{Events: []string{watch.EventPortClosed}, Commands: []Command{{Run: []string{"sh", "-c", script, out}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "port_closed 192.168.1.1 23" {
+		t.Fatalf("command saw %q", got)
+	}
+
+	failing := Command{Run: []string{"sh", "-c", "echo no route >&2; exit 3"}}
+	if err := failing.Send(context.Background(), testEvents()[0]); err == nil || !strings.Contains(err.Error(), "no route") {
+		t.Fatalf("failing command: %v", err)
+	}
+}
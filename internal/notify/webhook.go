@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/watch"
+)
+
+// Webhook templates. The default posts the event as nibble writes it in
+// watch output.
+const (
+	TemplateJSON    = "json"
+	TemplateSlack   = "slack"
+	TemplateDiscord = "discord"
+	TemplateNtfy    = "ntfy"
+)
+
+// Webhook POSTs every event as JSON to a URL.
+type Webhook struct {
+	URL      string            `json:"url"`
+	Template string            `json:"template,omitempty"` // json, slack, discord or ntfy, empty is json.
+	Topic    string            `json:"topic,omitempty"`    // ntfy topic, empty takes the last part of URL.
+	Headers  map[string]string `json:"headers,omitempty"`  // E.g. an Authorization header.
+}
+
+func (w Webhook) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url %q: must be an http or https URL", w.URL)
+	}
+	switch w.Template {
+	case "", TemplateJSON, TemplateSlack, TemplateDiscord, TemplateNtfy:
+	default:
+		return fmt.Errorf("webhook template %q: must be json, slack, discord or ntfy", w.Template)
+	}
+	if w.Template == TemplateNtfy && w.Topic == "" && strings.Trim(u.Path, "/") == "" {
+		return fmt.Errorf("webhook url %q: ntfy needs a topic in the URL or in topic", w.URL)
+	}
+	return nil
+}
+
+// Send posts the event. Errors name only the webhook's host, Slack and
+// Discord URLs carry their secret in the path.
+func (w Webhook) Send(ctx context.Context, event watch.Event) error {
+	target, body, err := w.request(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.Headers {
+		req.Header.Set(name, value)
+	}
+
+	host := req.URL.Host
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("webhook %s: %w", host, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: %s", host, resp.Status)
+	}
+	return nil
+}
+
+// request returns the URL to post to and the body the template makes of
+// the event.
+func (w Webhook) request(event watch.Event) (string, []byte, error) {
+	text := "nibble: " + event.Summary()
+	var payload any
+	target := w.URL
+	switch w.Template {
+	case TemplateSlack:
+		payload = map[string]string{"text": text}
+	case TemplateDiscord:
+		payload = map[string]string{"content": text}
+	case TemplateNtfy:
+		// ntfy takes JSON on its root URL with the topic in the body.
+		u, err := url.Parse(w.URL)
+		if err != nil {
+			return "", nil, err
+		}
+		topic := w.Topic
+		if topic == "" {
+			path := strings.TrimSuffix(u.Path, "/")
+			topic = path[strings.LastIndex(path, "/")+1:]
+			u.Path = strings.TrimSuffix(path, topic)
+		}
+		target = u.String()
+		payload = map[string]any{
+			"topic":   topic,
+			"title":   Title(event.Type),
+			"message": event.Summary(),
+			"tags":    []string{"nibble", event.Type},
+		}
+	default:
+		payload = event
+	}
+	body, err := json.Marshal(payload)
+	return target, body, err
+}
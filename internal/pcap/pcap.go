@@ -0,0 +1,321 @@
+// Package pcap reads packet captures in the classic pcap and the pcapng
+// format, as written by tcpdump, Wireshark and dumpcap.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Link types of captured packets.
+const (
+	LinkEthernet  = 1
+	LinkRaw       = 101 // Bare IPv4 or IPv6 packets.
+	LinkLinuxSLL  = 113 // Linux "any" interface captures.
+	LinkLinuxSLL2 = 276
+)
+
+// maxBlock bounds records and blocks so a corrupt length cannot make the
+// reader allocate gigabytes.
+const maxBlock = 16 << 20
+
+var ErrFormat = errors.New("not a pcap or pcapng file")
+
+// Packet is one captured frame.
+type Packet struct {
+	LinkType int
+	Time     time.Time
+	Data     []byte
+}
+
+// Ethernet returns the packet as an Ethernet frame, rewriting the Linux
+// cooked and raw IP headers, so callers only need to decode one link type.
+// The destination MAC of a rewritten frame is unknown and left as zeros.
+func (p Packet) Ethernet() ([]byte, bool) {
+	var src []byte
+	var etherType uint16
+	var payload []byte
+	switch p.LinkType {
+	case LinkEthernet:
+		return p.Data, len(p.Data) >= 14
+	case LinkRaw:
+		if len(p.Data) == 0 {
+			return nil, false
+		}
+		switch p.Data[0] >> 4 {
+		case 4:
+			etherType = 0x0800
+		case 6:
+			etherType = 0x86dd
+		default:
+			return nil, false
+		}
+		payload = p.Data
+	case LinkLinuxSLL:
+		if len(p.Data) < 16 {
+			return nil, false
+		}
+		if binary.BigEndian.Uint16(p.Data[4:6]) == 6 {
+			src = p.Data[6:12]
+		}
+		etherType, payload = binary.BigEndian.Uint16(p.Data[14:16]), p.Data[16:]
+	case LinkLinuxSLL2:
+		if len(p.Data) < 20 {
+			return nil, false
+		}
+		if p.Data[11] == 6 {
+			src = p.Data[12:18]
+		}
+		etherType, payload = binary.BigEndian.Uint16(p.Data[0:2]), p.Data[20:]
+	default:
+		return nil, false
+	}
+	frame := make([]byte, 14, 14+len(payload))
+	copy(frame[6:12], src)
+	binary.BigEndian.PutUint16(frame[12:14], etherType)
+	return append(frame, payload...), true
+}
+
+// Reader returns the packets of a capture in file order.
+type Reader struct {
+	r    *bufio.Reader
+	next func() (Packet, error)
+
+	// Classic pcap.
+	order    binary.ByteOrder
+	linkType int
+	nanos    bool
+
+	// pcapng, per section.
+	interfaces []ngInterface
+}
+
+type ngInterface struct {
+	linkType int
+	snapLen  uint32
+	unit     time.Duration // Length of one timestamp tick, zero for sub-nanosecond ticks.
+	perSec   uint64        // Ticks per second when unit is zero.
+}
+
+// NewReader detects the capture format from its first bytes.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	rd := &Reader{r: br}
+	switch {
+	case binary.BigEndian.Uint32(magic) == 0x0a0d0d0a:
+		rd.next = rd.nextNG
+		return rd, nil
+	default:
+		return rd, rd.readClassicHeader()
+	}
+}
+
+// Next returns the next packet, or io.EOF after the last one.
+func (r *Reader) Next() (Packet, error) {
+	return r.next()
+}
+
+func (r *Reader) readClassicHeader() error {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return ErrFormat
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header) {
+		case 0xa1b2c3d4:
+			r.order = order
+		case 0xa1b23c4d:
+			r.order, r.nanos = order, true
+		default:
+			continue
+		}
+		r.linkType = int(order.Uint32(header[20:24]) & 0x0fffffff)
+		r.next = r.nextClassic
+		return nil
+	}
+	return ErrFormat
+}
+
+func (r *Reader) nextClassic() (Packet, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return Packet{}, eof(err)
+	}
+	sec := int64(r.order.Uint32(header[0:4]))
+	frac := int64(r.order.Uint32(header[4:8]))
+	length := r.order.Uint32(header[8:12])
+	if length > maxBlock {
+		return Packet{}, fmt.Errorf("pcap record of %d bytes", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return Packet{}, eof(err)
+	}
+	if !r.nanos {
+		frac *= 1000
+	}
+	return Packet{LinkType: r.linkType, Time: time.Unix(sec, frac), Data: data}, nil
+}
+
+// pcapng block types.
+const (
+	blockSection         = 0x0a0d0d0a
+	blockInterface       = 0x00000001
+	blockSimplePacket    = 0x00000003
+	blockEnhancedPacket  = 0x00000006
+	optionEnd            = 0
+	optionTimeResolution = 9
+)
+
+func (r *Reader) nextNG() (Packet, error) {
+	for {
+		blockType, body, err := r.readBlock()
+		if err != nil {
+			return Packet{}, err
+		}
+		switch blockType {
+		case blockSection:
+			r.interfaces = nil
+		case blockInterface:
+			if len(body) < 8 {
+				return Packet{}, ErrFormat
+			}
+			iface := ngInterface{
+				linkType: int(r.order.Uint16(body[0:2])),
+				snapLen:  r.order.Uint32(body[4:8]),
+				unit:     time.Microsecond,
+			}
+			r.readInterfaceOptions(&iface, body[8:])
+			r.interfaces = append(r.interfaces, iface)
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return Packet{}, ErrFormat
+			}
+			id := r.order.Uint32(body[0:4])
+			if int(id) >= len(r.interfaces) {
+				return Packet{}, fmt.Errorf("packet on unknown interface %d", id)
+			}
+			iface := r.interfaces[id]
+			ticks := uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12]))
+			length := r.order.Uint32(body[12:16])
+			if int(length) > len(body)-20 {
+				return Packet{}, ErrFormat
+			}
+			return Packet{LinkType: iface.linkType, Time: iface.time(ticks), Data: body[20 : 20+length]}, nil
+		case blockSimplePacket:
+			if len(body) < 4 || len(r.interfaces) == 0 {
+				return Packet{}, ErrFormat
+			}
+			iface := r.interfaces[0]
+			length := r.order.Uint32(body[0:4])
+			if iface.snapLen > 0 && length > iface.snapLen {
+				length = iface.snapLen
+			}
+			if int(length) > len(body)-4 {
+				length = uint32(len(body) - 4)
+			}
+			return Packet{LinkType: iface.linkType, Data: body[4 : 4+length]}, nil
+		}
+	}
+}
+
+// readBlock reads one pcapng block and returns its body. A section header
+// also sets the byte order for the blocks after it.
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return 0, nil, eof(err)
+	}
+	if binary.BigEndian.Uint32(header) == blockSection {
+		magic, err := r.r.Peek(4)
+		if err != nil {
+			return 0, nil, ErrFormat
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == 0x1a2b3c4d:
+			r.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == 0x1a2b3c4d:
+			r.order = binary.BigEndian
+		default:
+			return 0, nil, ErrFormat
+		}
+	}
+	if r.order == nil {
+		return 0, nil, ErrFormat
+	}
+	blockType := r.order.Uint32(header[0:4])
+	total := r.order.Uint32(header[4:8])
+	if total < 12 || total%4 != 0 || total > maxBlock {
+		return 0, nil, fmt.Errorf("pcapng block of %d bytes", total)
+	}
+	rest := make([]byte, total-8)
+	if _, err := io.ReadFull(r.r, rest); err != nil {
+		return 0, nil, eof(err)
+	}
+	// The body is followed by a copy of the total length.
+	return blockType, rest[:len(rest)-4], nil
+}
+
+func (r *Reader) readInterfaceOptions(iface *ngInterface, opts []byte) {
+	for len(opts) >= 4 {
+		code := r.order.Uint16(opts[0:2])
+		length := int(r.order.Uint16(opts[2:4]))
+		if code == optionEnd || len(opts) < 4+length {
+			return
+		}
+		if code == optionTimeResolution && length >= 1 {
+			iface.setResolution(opts[4])
+		}
+		opts = opts[4+(length+3)/4*4:]
+	}
+}
+
+// setResolution applies if_tsresol: a negative power of ten, or of two when
+// the top bit is set.
+func (i *ngInterface) setResolution(res byte) {
+	exp := uint(res & 0x7f)
+	i.unit = 0
+	if res&0x80 == 0 {
+		if exp <= 9 {
+			i.unit = time.Duration(math.Pow10(9 - int(exp)))
+			return
+		}
+		if exp < 20 {
+			i.perSec = uint64(math.Pow10(int(exp)))
+			return
+		}
+	} else if exp < 64 {
+		i.perSec = 1 << exp
+		return
+	}
+	i.unit = time.Microsecond
+}
+
+func (i ngInterface) time(ticks uint64) time.Time {
+	if i.unit > 0 {
+		return time.Unix(0, 0).Add(time.Duration(ticks) * i.unit)
+	}
+	sec := ticks / i.perSec
+	nanos := float64(ticks%i.perSec) / float64(i.perSec) * 1e9
+	return time.Unix(int64(sec), int64(nanos))
+}
+
+// eof turns a clean end of file into io.EOF and a cut short one into an error.
+func eof(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("capture is truncated: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func classicCapture(order binary.AppendByteOrder, magic uint32, frames ...[]byte) []byte {
+	header := order.AppendUint32(nil, magic)
+	header = order.AppendUint16(header, 2)
+	header = order.AppendUint16(header, 4)
+	header = append(header, make([]byte, 8)...)
+	header = order.AppendUint32(header, 65535)
+	header = order.AppendUint32(header, LinkEthernet)
+	for i, frame := range frames {
+		header = order.AppendUint32(header, 1700000000+uint32(i))
+		header = order.AppendUint32(header, 250)
+		header = order.AppendUint32(header, uint32(len(frame)))
+		header = order.AppendUint32(header, uint32(len(frame)))
+		header = append(header, frame...)
+	}
+	return header
+}
+
+func ngBlock(blockType uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	total := uint32(12 + len(body))
+	block := binary.LittleEndian.AppendUint32(nil, blockType)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	block = append(block, body...)
+	return binary.LittleEndian.AppendUint32(block, total)
+}
+
+func ngCapture(ticks uint64, frame []byte) []byte {
+	section := binary.LittleEndian.AppendUint32(nil, 0x1a2b3c4d)
+	section = binary.LittleEndian.AppendUint16(section, 1)
+	section = binary.LittleEndian.AppendUint16(section, 0)
+	section = binary.LittleEndian.AppendUint64(section, ^uint64(0))
+
+	// An SLL interface with nanosecond timestamps.
+	iface := binary.LittleEndian.AppendUint16(nil, LinkLinuxSLL)
+	iface = append(iface, 0, 0)
+	iface = binary.LittleEndian.AppendUint32(iface, 0)
+	iface = binary.LittleEndian.AppendUint16(iface, optionTimeResolution)
+	iface = binary.LittleEndian.AppendUint16(iface, 1)
+	iface = append(iface, 9, 0, 0, 0)
+	iface = append(iface, 0, 0, 0, 0)
+
+	packet := binary.LittleEndian.AppendUint32(nil, 0)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(ticks>>32))
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(ticks))
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(frame)))
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(frame)))
+	packet = append(packet, frame...)
+
+	simple := binary.LittleEndian.AppendUint32(nil, uint32(len(frame)))
+	simple = append(simple, frame...)
+
+	capture := ngBlock(blockSection, section)
+	capture = append(capture, ngBlock(blockInterface, iface)...)
+	capture = append(capture, ngBlock(0x0bad, []byte("custom"))...)
+	capture = append(capture, ngBlock(blockEnhancedPacket, packet)...)
+	return append(capture, ngBlock(blockSimplePacket, simple)...)
+}
+
+func readAll(t *testing.T, capture []byte) []Packet {
+	t.Helper()
+	reader, err := NewReader(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var packets []Packet
+	for {
+		packet, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return packets
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, packet)
+	}
+}
+
+func TestReadClassic(t *testing.T) {
+	for _, order := range []binary.AppendByteOrder{binary.LittleEndian, binary.BigEndian} {
+		packets := readAll(t, classicCapture(order, 0xa1b2c3d4, []byte("first"), []byte("second frame")))
+		if len(packets) != 2 || string(packets[1].Data) != "second frame" || packets[0].LinkType != LinkEthernet {
+			t.Fatalf("%v: %+v", order, packets)
+		}
+		if want := time.Unix(1700000000, 250000); !packets[0].Time.Equal(want) {
+			t.Fatalf("%v: time %v, want %v", order, packets[0].Time, want)
+		}
+	}
+
+	packets := readAll(t, classicCapture(binary.LittleEndian, 0xa1b23c4d, []byte("nanos")))
+	if want := time.Unix(1700000000, 250); len(packets) != 1 || !packets[0].Time.Equal(want) {
+		t.Fatalf("nanosecond capture: %+v", packets)
+	}
+}
+
+func TestReadNG(t *testing.T) {
+	packets := readAll(t, ngCapture(1700000000_123456789, []byte("frame")))
+	if len(packets) != 2 {
+		t.Fatalf("got %d packets: %+v", len(packets), packets)
+	}
+	for _, packet := range packets {
+		if packet.LinkType != LinkLinuxSLL || string(packet.Data) != "frame" {
+			t.Fatalf("packet %+v", packet)
+		}
+	}
+	if want := time.Unix(1700000000, 123456789); !packets[0].Time.Equal(want) {
+		t.Fatalf("time %v, want %v", packets[0].Time, want)
+	}
+}
+
+func TestReadRejectsOtherFiles(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte("host,mac\n192.168.1.1,aa:bb\n"))); !errors.Is(err, ErrFormat) {
+		t.Fatalf("got %v", err)
+	}
+
+	capture := classicCapture(binary.LittleEndian, 0xa1b2c3d4, []byte("cut short"))
+	reader, err := NewReader(bytes.NewReader(capture[:len(capture)-3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("truncated record: %v", err)
+	}
+}
+
+func TestEthernet(t *testing.T) {
+	mac := []byte{0xd8, 0x3a, 0xdd, 0x11, 0x22, 0x33}
+	sll := append([]byte{0, 0, 0, 1, 0, 6}, mac...)
+	sll = append(sll, 0, 0, 0x08, 0x06)
+	frame, ok := Packet{LinkType: LinkLinuxSLL, Data: append(sll, "arp"...)}.Ethernet()
+	if !ok || !bytes.Equal(frame[6:12], mac) || !bytes.Equal(frame[12:], []byte{0x08, 0x06, 'a', 'r', 'p'}) {
+		t.Fatalf("sll: %x", frame)
+	}
+
+	frame, ok = Packet{LinkType: LinkRaw, Data: []byte{0x45, 0, 0, 20}}.Ethernet()
+	if !ok || !bytes.Equal(frame[12:16], []byte{0x08, 0x00, 0x45, 0}) {
+		t.Fatalf("raw: %x", frame)
+	}
+
+	if _, ok := (Packet{LinkType: 127, Data: make([]byte, 64)}).Ethernet(); ok {
+		t.Fatal("radiotap capture converted")
+	}
+}
@@ -12,6 +12,14 @@ type Config struct {
 	Custom string `json:"custom"`
 }
 
+// Pack returns the saved port pack, falling back to the default pack.
+func (cfg Config) Pack() string {
+	if cfg.Mode == "" || !IsValidPack(cfg.Mode) {
+		return ModeDefault
+	}
+	return cfg.Mode
+}
+
 func ConfigPath() (string, error) {
 	base, err := os.UserConfigDir()
 	if err != nil {
@@ -28,6 +28,14 @@ var defaultPorts = []int{
 	8443, // Alt HTTPS
 }
 
+// defaultUDPPorts is empty: UDP has no handshake, so every port waits out a
+// timeout on hosts that stay silent, and the SNMP hello sends a community
+// string. UDP is only probed for the "u:" entries of a port list.
+var defaultUDPPorts []int
+
+// UDPPrefix marks a UDP port in port lists, e.g. "u:53,u:161".
+const UDPPrefix = "u:"
+
 func IsValidPack(name string) bool {
 	return name == ModeDefault || name == ModeCustom
 }
@@ -38,8 +46,24 @@ func DefaultPorts() []int {
 	return out
 }
 
-// Resolve returns the final port list from a named pack plus optional add/remove lists.
+func DefaultUDPPorts() []int {
+	out := make([]int, len(defaultUDPPorts))
+	copy(out, defaultUDPPorts)
+	return out
+}
+
+// Resolve returns the final TCP port list from a named pack plus optional add/remove lists.
+// UDP entries in the lists are validated but left to ResolveUDP.
 func Resolve(packName, addPorts, removePorts string) ([]int, error) {
+	return resolve(packName, addPorts, removePorts, defaultPorts, false)
+}
+
+// ResolveUDP returns the final UDP port list, taken from the "u:" entries.
+func ResolveUDP(packName, addPorts, removePorts string) ([]int, error) {
+	return resolve(packName, addPorts, removePorts, defaultUDPPorts, true)
+}
+
+func resolve(packName, addPorts, removePorts string, defaults []int, udp bool) ([]int, error) {
 	if packName == "" {
 		packName = ModeDefault
 	}
@@ -47,18 +71,18 @@ func Resolve(packName, addPorts, removePorts string) ([]int, error) {
 	var base []int
 	switch packName {
 	case ModeDefault:
-		base = defaultPorts
+		base = defaults
 	case ModeCustom:
 		base = nil
 	default:
 		return nil, fmt.Errorf("unknown port pack: %s", packName)
 	}
 
-	add, err := parseList(addPorts)
+	add, err := parseProtoList(addPorts, udp)
 	if err != nil {
 		return nil, err
 	}
-	remove, err := parseList(removePorts)
+	remove, err := parseProtoList(removePorts, udp)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +106,14 @@ func Resolve(packName, addPorts, removePorts string) ([]int, error) {
 	return out, nil
 }
 
+// parseList parses the TCP ports of a list.
 func parseList(raw string) ([]int, error) {
+	return parseProtoList(raw, false)
+}
+
+// parseProtoList parses either the plain (TCP) or the "u:" (UDP) entries of a
+// list. Entries of the other protocol are still validated so errors name them.
+func parseProtoList(raw string, udp bool) ([]int, error) {
 	if strings.TrimSpace(raw) == "" {
 		return nil, nil
 	}
@@ -95,12 +126,15 @@ func parseList(raw string) ([]int, error) {
 		if s == "" {
 			continue
 		}
-		ports, err := parseToken(s)
+		token, isUDP := cutUDP(s)
+		ports, err := parseToken(token)
 		if err != nil {
 			invalid = append(invalid, s)
 			continue
 		}
-		out = append(out, ports...)
+		if isUDP == udp {
+			out = append(out, ports...)
+		}
 	}
 	if len(invalid) > 0 {
 		return nil, fmt.Errorf("invalid ports: %s", strings.Join(invalid, ","))
@@ -141,6 +175,7 @@ func NormalizeCustom(raw string) (string, error) {
 
 	fields := strings.Split(raw, ",")
 	ranges := make([]portRange, 0, len(fields))
+	udpRanges := make([]portRange, 0, 2)
 	invalid := make([]string, 0, 2)
 
 	for _, f := range fields {
@@ -149,16 +184,37 @@ func NormalizeCustom(raw string) (string, error) {
 			continue
 		}
 
-		start, end, err := parseTokenBounds(s)
+		token, isUDP := cutUDP(s)
+		start, end, err := parseTokenBounds(token)
 		if err != nil {
 			invalid = append(invalid, s)
 			continue
 		}
+		if isUDP {
+			udpRanges = append(udpRanges, portRange{start: start, end: end})
+			continue
+		}
 		ranges = append(ranges, portRange{start: start, end: end})
 	}
 
 	if len(invalid) > 0 {
 		return "", fmt.Errorf("invalid ports: %s", strings.Join(invalid, ","))
 	}
-	return normalizeRanges(ranges), nil
+
+	tokens := []string{}
+	if tcp := normalizeRanges(ranges); tcp != "" {
+		tokens = append(tokens, tcp)
+	}
+	if udp := normalizeRanges(udpRanges); udp != "" {
+		tokens = append(tokens, UDPPrefix+strings.ReplaceAll(udp, ",", ","+UDPPrefix))
+	}
+	return strings.Join(tokens, ","), nil
+}
+
+// cutUDP strips the "u:" prefix and reports whether it was present.
+func cutUDP(token string) (string, bool) {
+	if rest, ok := strings.CutPrefix(strings.ToLower(token), UDPPrefix); ok {
+		return strings.TrimSpace(rest), true
+	}
+	return token, false
 }
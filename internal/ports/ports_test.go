@@ -52,3 +52,49 @@ func TestCustomOverlappingRanges(t *testing.T) {
 		t.Fatalf("mismatch: got %q want %q", got, want)
 	}
 }
+
+func TestUDPPortsKeptSeparate(t *testing.T) {
+	tcp, err := Resolve(ModeCustom, "80,u:53,u:161-162", "")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	udp, err := ResolveUDP(ModeCustom, "80,u:53,u:161-162", "")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(tcp, []int{80}) {
+		t.Fatalf("tcp mismatch: got %v want [80]", tcp)
+	}
+	if !reflect.DeepEqual(udp, []int{53, 161, 162}) {
+		t.Fatalf("udp mismatch: got %v want [53 161 162]", udp)
+	}
+}
+
+func TestUDPOnlyWhenListed(t *testing.T) {
+	udp, err := ResolveUDP(ModeDefault, "", "")
+	if err != nil || len(udp) != 0 {
+		t.Fatalf("default pack probes udp: %v, %v", udp, err)
+	}
+	udp, err = ResolveUDP(ModeDefault, "u:161", "")
+	if err != nil || !reflect.DeepEqual(udp, []int{161}) {
+		t.Fatalf("added udp: got %v, %v want [161]", udp, err)
+	}
+}
+
+func TestCustomNormalizesUDP(t *testing.T) {
+	got, err := NormalizeCustom("u:161,443,U:53, u:160-162,80")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+
+	want := "80,443,u:53,u:160-162"
+	if got != want {
+		t.Fatalf("mismatch: got %q want %q", got, want)
+	}
+
+	_, err = NormalizeCustom("u:0,u:53")
+	if err == nil || err.Error() != "invalid ports: u:0" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"net"
 	"net/netip"
 	"runtime"
@@ -10,10 +11,17 @@ import (
 	"github.com/backendsystems/nibble/internal/scan/linux"
 	"github.com/backendsystems/nibble/internal/scan/macos"
 	"github.com/backendsystems/nibble/internal/scan/windows"
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/target"
 
 	"github.com/mdlayher/arp"
 )
 
+const (
+	arpRequestGap  = 200 * time.Microsecond
+	arpReplyWindow = 750 * time.Millisecond
+)
+
 // resolveMac does an ARP resolve for a single IP and returns the MAC string
 func resolveMac(ifaceName string, targetIP net.IP) string {
 	netIface, err := net.InterfaceByName(ifaceName)
@@ -42,6 +50,104 @@ func resolveMac(ifaceName string, targetIP net.IP) string {
 	return mac.String()
 }
 
+// arpSweep broadcasts an ARP request for every on-link IPv4 target from a
+// single socket and collects replies as they arrive. Opening the socket needs
+// CAP_NET_RAW (or root); without it the sweep returns nothing and discovery
+// falls back to the OS neighbor table.
+func arpSweep(ctx context.Context, ifaceName string, targets target.Set) []NeighborEntry {
+	netIface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil
+	}
+
+	link := linkNets(netIface)
+	if len(link) == 0 {
+		return nil
+	}
+
+	client, err := arp.Dial(netIface)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+
+	replies := make(chan NeighborEntry, 64)
+	go func() {
+		defer close(replies)
+		for {
+			packet, _, err := client.Read()
+			if err != nil {
+				return
+			}
+			if packet.Operation != arp.OperationReply || !targets.Contains(packet.SenderIP) {
+				continue
+			}
+			replies <- NeighborEntry{IP: packet.SenderIP.String(), MAC: packet.SenderHardwareAddr.String()}
+		}
+	}()
+
+	// Collect while sending so a slow sweep never fills the reply channel.
+	found := make(map[string]NeighborEntry)
+	collectDone := make(chan struct{})
+	go func() {
+		defer close(collectDone)
+		for reply := range replies {
+			if _, ok := found[reply.IP]; !ok {
+				found[reply.IP] = reply
+			}
+		}
+	}()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	targets.Each(func(addr netip.Addr) bool {
+		if !addr.Is4() || !link.contains(addr) {
+			return true
+		}
+		_ = client.Request(addr)
+		time.Sleep(arpRequestGap)
+		return ctx.Err() == nil
+	})
+
+	return awaitReplies(client, arpReplyWindow, collectDone, found)
+}
+
+// mergeNeighbors appends extra entries whose IP is not already listed and
+// fills fields that earlier sources left empty for IPs that are.
+func mergeNeighbors(neighbors, extra []NeighborEntry) []NeighborEntry {
+	index := make(map[string]int, len(neighbors))
+	for i, neighbor := range neighbors {
+		index[neighbor.IP] = i
+	}
+	for _, entry := range extra {
+		i, ok := index[entry.IP]
+		if !ok {
+			index[entry.IP] = len(neighbors)
+			neighbors = append(neighbors, entry)
+			continue
+		}
+		if neighbors[i].MAC == "" {
+			neighbors[i].MAC = entry.MAC
+		}
+		if neighbors[i].RTT == 0 {
+			neighbors[i].RTT = entry.RTT
+		}
+		if neighbors[i].TTL == 0 {
+			neighbors[i].TTL = entry.TTL
+		}
+		if len(neighbors[i].MDNS) == 0 {
+			neighbors[i].MDNS = entry.MDNS
+		}
+		if neighbors[i].UPnP == nil {
+			neighbors[i].UPnP = entry.UPnP
+		}
+	}
+	return neighbors
+}
+
 // lookupMacFromCache reads the OS ARP cache to find a MAC without needing root
 // Linux reads /proc/net/arp, macOS reads routing table entries, Windows reads IP helper table entries
 func lookupMacFromCache(ip string) string {
@@ -55,14 +161,20 @@ func lookupMacFromCache(ip string) string {
 }
 
 // NeighborEntry is a visible L2/L3 neighbor from the host ARP/neighbor table
+// or from an active discovery phase
 type NeighborEntry struct {
 	IP  string
 	MAC string
+	RTT time.Duration // Echo round trip time, zero when the host was not pinged.
+	TTL int           // TTL of the echo reply, zero when unknown.
+
+	MDNS []scanner.MDNSService // Services the host advertised while browsing mDNS.
+	UPnP *scanner.UPnPDevice   // Description the host gave in answer to SSDP.
 }
 
 // visibleNeighbors returns neighbors currently visible in the OS ARP
-// table for the selected interface and subnet
-func visibleNeighbors(ifaceName string, subnet *net.IPNet) []NeighborEntry {
+// table for the selected interface that are part of the target set
+func visibleNeighbors(ifaceName string, targets target.Set) []NeighborEntry {
 	var rows []NeighborEntry
 	switch runtime.GOOS {
 	case "windows":
@@ -82,8 +194,8 @@ func visibleNeighbors(ifaceName string, subnet *net.IPNet) []NeighborEntry {
 	seen := make(map[string]struct{})
 	var out []NeighborEntry
 	for _, row := range rows {
-		ip := net.ParseIP(row.IP)
-		if ip == nil || ip.To4() == nil || !subnet.Contains(ip) {
+		addr, err := netip.ParseAddr(row.IP)
+		if err != nil || !addr.Is4() || !targets.Contains(addr) {
 			continue
 		}
 		if row.MAC == "" || row.MAC == "00:00:00:00:00:00" {
@@ -92,7 +204,42 @@ func visibleNeighbors(ifaceName string, subnet *net.IPNet) []NeighborEntry {
 		if strings.EqualFold(row.MAC, "ff:ff:ff:ff:ff:ff") {
 			continue
 		}
-		if isSubnetBroadcastIpv4(ip, subnet) {
+		if _, ok := seen[row.IP]; ok {
+			continue
+		}
+		seen[row.IP] = struct{}{}
+		out = append(out, row)
+	}
+	return out
+}
+
+// visibleNeighbors6 returns neighbors currently visible in the OS NDP
+// cache that are link-local or inside the selected prefix
+func visibleNeighbors6(ifaceName string, subnet *net.IPNet) []NeighborEntry {
+	var rows []NeighborEntry
+	switch runtime.GOOS {
+	case "windows":
+		for _, row := range windows.Neighbors6() {
+			rows = append(rows, NeighborEntry{IP: row.IP, MAC: row.MAC})
+		}
+	case "darwin":
+		for _, row := range macos.Neighbors6(ifaceName) {
+			rows = append(rows, NeighborEntry{IP: row.IP, MAC: row.MAC})
+		}
+	default:
+		for _, row := range linux.Neighbors6(ifaceName) {
+			rows = append(rows, NeighborEntry{IP: row.IP, MAC: row.MAC})
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var out []NeighborEntry
+	for _, row := range rows {
+		addr, err := netip.ParseAddr(row.IP)
+		if err != nil || !onLink6(addr, subnet) {
+			continue
+		}
+		if row.MAC == "" || row.MAC == "00:00:00:00:00:00" {
 			continue
 		}
 		if _, ok := seen[row.IP]; ok {
@@ -104,18 +251,13 @@ func visibleNeighbors(ifaceName string, subnet *net.IPNet) []NeighborEntry {
 	return out
 }
 
-func isSubnetBroadcastIpv4(ip net.IP, subnet *net.IPNet) bool {
-	ip4 := ip.To4()
-	base := subnet.IP.To4()
-	mask := subnet.Mask
-	if ip4 == nil || base == nil || len(mask) != net.IPv4len {
+// onLink6 reports whether an IPv6 unicast address is reachable on the selected link.
+func onLink6(addr netip.Addr, subnet *net.IPNet) bool {
+	if !addr.Is6() || addr.Is4In6() || addr.IsMulticast() {
 		return false
 	}
-	broadcast := net.IPv4(
-		base[0]|^mask[0],
-		base[1]|^mask[1],
-		base[2]|^mask[2],
-		base[3]|^mask[3],
-	)
-	return ip4.Equal(broadcast)
+	if addr.IsLinkLocalUnicast() {
+		return true
+	}
+	return subnet.Contains(net.IP(addr.AsSlice()))
 }
@@ -1,11 +1,13 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -14,37 +16,144 @@ const (
 	maxBannerLength   = 80
 )
 
+// grabService reads what an open port tells about itself. Ports with a
+// registered probe get a protocol hello first, then a fresh connection for
+// the generic path if it fails. Known TLS ports start with a handshake.
+// Other ports retry with a ClientHello on a fresh connection when the
+// plaintext probes get nothing or a hint of TLS back, or with the SSH probe
+// when they turn out to run SSH. Web servers get a full HTTP fingerprint.
+func grabService(ctx context.Context, dialer *net.Dialer, conn net.Conn, port int) portResult {
+	result := portResult{port: port}
+	if probe, ok := tcpProbeFor(port); ok {
+		if id, ok := runProbe(conn, probe); ok {
+			return id.result(port)
+		}
+		retry, done, err := redial(ctx, dialer, conn)
+		if err != nil {
+			return result
+		}
+		defer done()
+		conn = retry
+	}
+
+	if _, ok := tlsPorts[port]; ok {
+		banner, info, isHTTP := grabTLS(conn, port)
+		result.banner, result.tls = banner, info
+		if isHTTP {
+			result.addHTTP(ctx, dialer, "https", conn.RemoteAddr().String())
+		}
+		return result
+	}
+
+	banner, hint := getServiceBanner(conn)
+	result.banner = banner
+	if hint == hintHTTP {
+		result.addHTTP(ctx, dialer, "http", conn.RemoteAddr().String())
+		return result
+	}
+	if banner != "" && hint != hintTLS && !strings.HasPrefix(banner, "SSH-") {
+		return result
+	}
+
+	retry, done, err := redial(ctx, dialer, conn)
+	if err != nil {
+		return result
+	}
+	defer done()
+
+	if strings.HasPrefix(banner, "SSH-") {
+		if id, ok := runProbe(retry, probeSSH); ok {
+			return id.result(port)
+		}
+		return result
+	}
+	if tlsBanner, info, isHTTP := grabTLS(retry, port); info != nil {
+		result.banner, result.tls = tlsBanner, info
+		if isHTTP {
+			result.addHTTP(ctx, dialer, "https", conn.RemoteAddr().String())
+		}
+	}
+	return result
+}
+
+// redial opens a fresh connection to the same address for a second probe.
+// done closes it and stops the cancellation hook.
+func redial(ctx context.Context, dialer *net.Dialer, conn net.Conn) (net.Conn, func(), error) {
+	retry, err := dialer.DialContext(ctx, "tcp", conn.RemoteAddr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = retry.SetDeadline(time.Now())
+	})
+	return retry, func() {
+		stop()
+		retry.Close()
+	}, nil
+}
+
+// replyHint is what a banner reply says about the protocol behind a port.
+type replyHint int
+
+const (
+	hintNone replyHint = iota
+	hintTLS            // The port wants a TLS handshake first.
+	hintHTTP           // The port answered HTTP.
+)
+
 // getServiceBanner reads a service banner
 // Prefer passive reads first, then fall back to HTTP probe.
-func getServiceBanner(conn net.Conn) string {
+func getServiceBanner(conn net.Conn) (string, replyHint) {
 	_ = conn.SetDeadline(time.Now().Add(bannerReadTimeout))
 
 	passive := readPushBanner(conn)
 	if passive != "" {
 		if checkIfHttp(passive) {
-			return parseHTTPServer(passive)
+			return parseHTTPServer(passive), hintHTTP
 		}
-		return passive
+		return passive, hintNone
 	}
 
+	// The passive read used up the deadline.
+	_ = conn.SetDeadline(time.Now().Add(bannerReadTimeout))
 	return requestHttpBanner(conn)
 }
 
 // requestHttpBanner sends a simple HEAD request and returns a parsed banner string
-func requestHttpBanner(conn net.Conn) string {
+func requestHttpBanner(conn net.Conn) (string, replyHint) {
 	_, _ = fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", conn.RemoteAddr())
 
 	buf := make([]byte, 2048)
 	n, err := conn.Read(buf)
 	if err != nil || n == 0 {
-		return ""
+		return "", hintNone
 	}
 
+	if isTLSReply(buf[:n]) {
+		return "", hintTLS
+	}
 	response := string(buf[:n])
 	if checkIfHttp(response) {
-		return parseHTTPServer(response)
+		if plainHTTPOnTLS(response) {
+			return parseHTTPServer(response), hintTLS
+		}
+		return parseHTTPServer(response), hintHTTP
 	}
-	return cleanBanner(buf[:n])
+	return cleanBanner(buf[:n]), hintNone
+}
+
+// plainHTTPOnTLS spots the 400 pages servers send for plaintext on TLS ports.
+func plainHTTPOnTLS(response string) bool {
+	for _, marker := range []string{
+		"HTTPS port",         // nginx
+		"to an HTTPS server", // Go net/http
+		"SSL-enabled server", // Apache
+	} {
+		if strings.Contains(response, marker) {
+			return true
+		}
+	}
+	return false
 }
 
 func readPushBanner(conn net.Conn) string {
@@ -86,6 +195,17 @@ func cleanBanner(raw []byte) string {
 	return clean[:maxBannerLength]
 }
 
+// cleanName strips control and other non-printable runes from a name a
+// device chose, such as an mDNS instance, keeping any UTF-8 it is written in.
+func cleanName(name string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, replaceInvalid([]byte(name))))
+}
+
 // replaceInvalid replaces invalid UTF-8 bytes with '.'
 func replaceInvalid(raw []byte) string {
 	s := string(raw)
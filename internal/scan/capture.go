@@ -0,0 +1,88 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/backendsystems/nibble/internal/classify"
+	"github.com/backendsystems/nibble/internal/pcap"
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+)
+
+// CaptureScanner builds the host list from a pcap or pcapng file instead of
+// the network, decoding the same ARP, DHCP, mDNS, SSDP, LLMNR and NetBIOS
+// traffic as passive listening, plus TCP handshakes for open ports.
+type CaptureScanner struct {
+	Exclude    *scope.Rules    // Hosts left out of the results, by IP, CIDR or MAC prefix.
+	Classifier *classify.Rules // Labels each host with a device type and OS, nil skips it.
+	Err        error           // Why the capture could not be read to the end, set before progressChan closes.
+
+	file   *os.File
+	reader *pcap.Reader
+}
+
+// OpenCapture opens a capture file and checks its format. ScanNetwork reads
+// and closes it.
+func OpenCapture(path string) (*CaptureScanner, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := pcap.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &CaptureScanner{file: file, reader: reader}, nil
+}
+
+// ScanNetwork reads the whole capture, then sends every host found in it.
+// The interface is ignored. Hosts are limited to spec, or to private and
+// link-local addresses when it is empty, so the servers a site talked to
+// across the internet are left out.
+func (s *CaptureScanner) ScanNetwork(ctx context.Context, _ string, spec string, progressChan chan<- scanner.ProgressUpdate) {
+	defer close(progressChan)
+	defer s.file.Close()
+
+	accept := isLocalAddr
+	if spec != "" {
+		if accept = acceptTargets(spec); accept == nil {
+			s.Err = fmt.Errorf("invalid target: %s", spec)
+			return
+		}
+	}
+
+	observer := newHostObserver(accept)
+	for ctx.Err() == nil {
+		packet, err := s.reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			s.Err = err // Keep the hosts from the packets before it.
+			break
+		}
+		if frame, ok := packet.Ethernet(); ok {
+			observer.frame(frame)
+		}
+	}
+
+	hosts := observer.results()
+	for i := range hosts {
+		host := hosts[i]
+		if s.Exclude.Excludes(host.IP, host.MAC) {
+			continue
+		}
+		s.Classifier.Classify(&host)
+		sendProgress(ctx, progressChan, scanner.NeighborProgress{Host: &host, Seen: i + 1, Total: len(hosts)})
+	}
+}
+
+func isLocalAddr(ip netip.Addr) bool {
+	return ip.IsPrivate() || ip.IsLinkLocalUnicast()
+}
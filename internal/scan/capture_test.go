@@ -0,0 +1,104 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+func tcpFrame(mac net.HardwareAddr, src, dst string, srcPort, dstPort int, flags byte) []byte {
+	segment := binary.BigEndian.AppendUint16(nil, uint16(srcPort))
+	segment = binary.BigEndian.AppendUint16(segment, uint16(dstPort))
+	segment = append(segment, make([]byte, 16)...)
+	segment[12] = 5 << 4
+	segment[13] = flags
+	return ipv4Frame(mac, src, dst, ipProtoTCP, segment)
+}
+
+func writeCapture(t *testing.T, frames ...[]byte) string {
+	t.Helper()
+	capture := binary.LittleEndian.AppendUint32(nil, 0xa1b2c3d4)
+	capture = binary.LittleEndian.AppendUint16(capture, 2)
+	capture = binary.LittleEndian.AppendUint16(capture, 4)
+	capture = append(capture, make([]byte, 8)...)
+	capture = binary.LittleEndian.AppendUint32(capture, 65535)
+	capture = binary.LittleEndian.AppendUint32(capture, 1) // Ethernet
+	for _, frame := range frames {
+		capture = binary.LittleEndian.AppendUint32(capture, 1700000000)
+		capture = binary.LittleEndian.AppendUint32(capture, 0)
+		capture = binary.LittleEndian.AppendUint32(capture, uint32(len(frame)))
+		capture = binary.LittleEndian.AppendUint32(capture, uint32(len(frame)))
+		capture = append(capture, frame...)
+	}
+	path := filepath.Join(t.TempDir(), "site.pcap")
+	if err := os.WriteFile(path, capture, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func captureHosts(t *testing.T, path, spec string) []scanner.HostResult {
+	t.Helper()
+	capture, err := OpenCapture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progressChan := make(chan scanner.ProgressUpdate, 16)
+	go capture.ScanNetwork(context.Background(), "", spec, progressChan)
+	var hosts []scanner.HostResult
+	for update := range progressChan {
+		if p, ok := update.(scanner.NeighborProgress); ok && p.Host != nil {
+			hosts = append(hosts, *p.Host)
+		}
+	}
+	if capture.Err != nil {
+		t.Fatal(capture.Err)
+	}
+	return hosts
+}
+
+func TestCaptureScanner(t *testing.T) {
+	pi := net.HardwareAddr{0xd8, 0x3a, 0xdd, 0x11, 0x22, 0x33}
+	router := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	path := writeCapture(t,
+		arpFrame(pi, "192.168.1.10"),
+		tcpFrame(pi, "192.168.1.10", "192.168.1.50", 22, 50000, tcpSYN|tcpACK),
+		tcpFrame(pi, "192.168.1.10", "192.168.1.50", 22, 50000, tcpSYN|tcpACK),
+		tcpFrame(pi, "192.168.1.10", "192.168.1.50", 80, 50001, tcpRST|tcpACK),
+		tcpFrame(pi, "192.168.1.10", "192.168.1.50", 8080, 50002, tcpSYN|tcpACK),
+		// A web server across the internet, seen through the router.
+		tcpFrame(router, "93.184.216.34", "192.168.1.50", 443, 50003, tcpSYN|tcpACK),
+	)
+
+	hosts := captureHosts(t, path, "")
+	if len(hosts) != 1 {
+		t.Fatalf("hosts: %+v", hosts)
+	}
+	pihost := hosts[0]
+	if pihost.IP != "192.168.1.10" || pihost.MAC != pi.String() || pihost.Hardware == "" {
+		t.Fatalf("host: %+v", pihost)
+	}
+	if len(pihost.Ports) != 2 || pihost.Ports[0].Port != 22 || pihost.Ports[1].Port != 8080 {
+		t.Fatalf("ports: %+v", pihost.Ports)
+	}
+
+	hosts = captureHosts(t, path, "93.184.216.0/24")
+	if len(hosts) != 1 || hosts[0].IP != "93.184.216.34" || hosts[0].MAC != "" {
+		t.Fatalf("targeted hosts: %+v", hosts)
+	}
+}
+
+func TestOpenCaptureRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.csv")
+	if err := os.WriteFile(path, []byte("ip,mac\n192.168.1.1,aa:bb:cc:dd:ee:ff\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCapture(path); err == nil {
+		t.Fatal("csv opened as a capture")
+	}
+}
@@ -0,0 +1,59 @@
+package scan
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/target"
+)
+
+// activeDiscovery runs the enabled discovery phases concurrently and merges
+// what they find. Each phase degrades to no results when it lacks privileges.
+// ARP, mDNS and SSDP only reach on-link targets, ping covers routed ones as well.
+// arp is false when the targets were already swept to resolve MAC excludes.
+func (s *NetScanner) activeDiscovery(ctx context.Context, ifaceName string, targets target.Set, arp bool) []NeighborEntry {
+	var phases []func() []NeighborEntry
+	if arp {
+		phases = append(phases, func() []NeighborEntry { return arpSweep(ctx, ifaceName, targets) })
+	}
+	if s.PingSweep {
+		phases = append(phases, func() []NeighborEntry { return pingSweep(ctx, targets) })
+	}
+	if s.MDNSBrowse {
+		phases = append(phases, func() []NeighborEntry { return mdnsBrowse(ctx, ifaceName, targets) })
+	}
+	if s.SSDPDiscover {
+		phases = append(phases, func() []NeighborEntry { return ssdpDiscover(ctx, ifaceName, targets, s.Exclude) })
+	}
+
+	results := make([][]NeighborEntry, len(phases))
+	var wg sync.WaitGroup
+	for i, phase := range phases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = phase()
+		}()
+	}
+	wg.Wait()
+
+	var out []NeighborEntry
+	for _, found := range results {
+		out = mergeNeighbors(out, found)
+	}
+	return out
+}
+
+// awaitReplies gives late replies window to arrive after a sweep, then
+// unblocks the reader and returns what it found once it is done.
+func awaitReplies(conn interface{ SetReadDeadline(time.Time) error }, window time.Duration, done <-chan struct{}, found map[string]NeighborEntry) []NeighborEntry {
+	_ = conn.SetReadDeadline(time.Now().Add(window))
+	<-done
+
+	out := make([]NeighborEntry, 0, len(found))
+	for _, entry := range found {
+		out = append(out, entry)
+	}
+	return out
+}
@@ -0,0 +1,215 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"html"
+	"io"
+	"math/bits"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+const (
+	httpTimeout      = 2 * time.Second
+	httpMaxRedirects = 5
+	httpMaxBody      = 256 << 10
+)
+
+var (
+	htmlTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlLink  = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	htmlAttr  = regexp.MustCompile(`(?is)(rel|href)\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+	authRealm = regexp.MustCompile(`(?i)realm\s*=\s*("[^"]*"|[^\s,]+)`)
+)
+
+// addHTTP fingerprints the web server on addr. When the server sends no
+// Server header the page title becomes the banner instead.
+func (r *portResult) addHTTP(ctx context.Context, dialer *net.Dialer, scheme, addr string) {
+	info := grabHTTP(ctx, dialer, scheme, addr)
+	if info == nil {
+		return
+	}
+	r.http = info
+	if info.Server == "" && info.Title != "" {
+		r.banner = info.Title
+	}
+}
+
+// grabHTTP GETs the root page, follows redirects that stay on the same
+// host, and hashes the favicon. It returns nil when no response came back.
+func grabHTTP(ctx context.Context, dialer *net.Dialer, scheme, addr string) *scanner.HTTPInfo {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+
+	info := &scanner.HTTPInfo{}
+	client := &http.Client{
+		Timeout: httpTimeout,
+		Transport: &http.Transport{
+			DialContext:       dialer.DialContext,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: sameHostRedirects(host, &info.Redirects),
+	}
+	defer client.CloseIdleConnections()
+
+	root := &url.URL{Scheme: scheme, Host: addr, Path: "/"}
+	resp, body, err := httpGet(ctx, client, root.String())
+	if err != nil {
+		return nil
+	}
+
+	info.Status = resp.StatusCode
+	info.Server = cleanBanner([]byte(resp.Header.Get("Server")))
+	info.PoweredBy = cleanBanner([]byte(resp.Header.Get("X-Powered-By")))
+	info.Realm = parseRealm(resp.Header.Get("WWW-Authenticate"))
+	info.Title = parseTitle(body)
+
+	client.CheckRedirect = sameHostRedirects(host, nil)
+	icon := faviconURL(resp.Request.URL, body)
+	if icon.Hostname() == host {
+		if iconResp, data, _ := httpGet(ctx, client, icon.String()); iconResp != nil &&
+			iconResp.StatusCode == http.StatusOK && len(data) > 0 {
+			info.FaviconHash = faviconHash(data)
+		}
+	}
+	return info
+}
+
+// sameHostRedirects follows redirects only on the same host, so a captive
+// portal or cloud login page is named, not fetched. Targets are appended to
+// visited when it is not nil.
+func sameHostRedirects(host string, visited *[]string) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if visited != nil {
+			*visited = append(*visited, req.URL.String())
+		}
+		if req.URL.Hostname() != host || len(via) > httpMaxRedirects {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+}
+
+// httpGet fetches a URL and reads up to httpMaxBody of the body.
+func httpGet(ctx context.Context, client *http.Client, target string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "nibble")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, httpMaxBody))
+	return resp, body, nil
+}
+
+func parseTitle(body []byte) string {
+	m := htmlTitle.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	return cleanBanner([]byte(title))
+}
+
+// parseRealm pulls the realm out of a WWW-Authenticate header.
+func parseRealm(header string) string {
+	m := authRealm.FindStringSubmatch(header)
+	if m == nil {
+		return ""
+	}
+	return cleanBanner([]byte(strings.Trim(m[1], `"`)))
+}
+
+// faviconURL uses the page's icon link when it has one, else /favicon.ico.
+func faviconURL(page *url.URL, body []byte) *url.URL {
+	for _, link := range htmlLink.FindAll(body, -1) {
+		var rel, href string
+		for _, attr := range htmlAttr.FindAllSubmatch(link, -1) {
+			value := strings.Trim(string(attr[2]), `"'`)
+			if strings.EqualFold(string(attr[1]), "rel") {
+				rel = strings.ToLower(value)
+			} else {
+				href = html.UnescapeString(value)
+			}
+		}
+		if href == "" || !strings.Contains(rel, "icon") || strings.HasPrefix(href, "data:") {
+			continue
+		}
+		if ref, err := page.Parse(href); err == nil {
+			return ref
+		}
+	}
+	return page.ResolveReference(&url.URL{Path: "/favicon.ico"})
+}
+
+// faviconHash is the Shodan-style favicon hash: MurmurHash3 of the icon
+// encoded as base64 with a newline after every 76 characters.
+func faviconHash(icon []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(icon)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return int32(murmur3([]byte(b.String()), 0))
+}
+
+// murmur3 is the 32-bit x86 variant of MurmurHash3.
+func murmur3(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[n*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
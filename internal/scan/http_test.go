@@ -0,0 +1,127 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var fakeFavicon = func() []byte {
+	icon := make([]byte, 0, 768)
+	for range 3 {
+		for b := range 256 {
+			icon = append(icon, byte(b))
+		}
+	}
+	return icon
+}()
+
+func fakeWebServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Powered-By", "PHP/8.1.2")
+		w.Write([]byte(`<html><head>
+<TITLE>  Router &amp;
+  Admin </TITLE>
+<link href="/static/fav.ico" rel="shortcut icon">
+</head></html>`))
+	})
+	mux.HandleFunc("/static/fav.ico", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(fakeFavicon)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMurmur3(t *testing.T) {
+	tests := map[string]uint32{
+		"":      0,
+		"hello": 613153351,
+		"The quick brown fox jumps over the lazy dog": 776992547,
+	}
+	for in, want := range tests {
+		if got := murmur3([]byte(in), 0); got != want {
+			t.Errorf("murmur3(%q) = %d, want %d", in, got, want)
+		}
+	}
+	// Matches mmh3.hash(base64.encodebytes(icon)) as Shodan computes it.
+	if got := faviconHash(fakeFavicon); got != 1836528006 {
+		t.Errorf("faviconHash = %d", got)
+	}
+}
+
+func TestGrabHTTP(t *testing.T) {
+	srv := fakeWebServer(t)
+	addr := srv.Listener.Addr().String()
+
+	info := grabHTTP(context.Background(), &net.Dialer{}, "http", addr)
+	if info == nil {
+		t.Fatalf("no HTTP info")
+	}
+	if info.Status != http.StatusOK || info.Title != "Router & Admin" || info.PoweredBy != "PHP/8.1.2" {
+		t.Fatalf("page details: %+v", info)
+	}
+	if !slices.Equal(info.Redirects, []string{"http://" + addr + "/login"}) {
+		t.Fatalf("redirects: %v", info.Redirects)
+	}
+	if info.FaviconHash != 1836528006 {
+		t.Fatalf("favicon hash: %d", info.FaviconHash)
+	}
+}
+
+func TestParseRealm(t *testing.T) {
+	tests := map[string]string{
+		`Basic realm="NETGEAR R7000"`:                      "NETGEAR R7000",
+		`Digest qop="auth", realm=DSL-Router, nonce="abc"`: "DSL-Router",
+		`Bearer`: "",
+	}
+	for in, want := range tests {
+		if got := parseRealm(in); got != want {
+			t.Errorf("parseRealm(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGrabHTTPStopsOffHost(t *testing.T) {
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Captive Portal"`)
+		http.Redirect(w, r, "http://192.0.2.77/portal", http.StatusFound)
+	}))
+	defer portal.Close()
+
+	info := grabHTTP(context.Background(), &net.Dialer{}, "http", portal.Listener.Addr().String())
+	if info == nil {
+		t.Fatalf("no HTTP info")
+	}
+	if info.Status != http.StatusFound || info.Realm != "Captive Portal" ||
+		!slices.Equal(info.Redirects, []string{"http://192.0.2.77/portal"}) {
+		t.Fatalf("off-host redirect: %+v", info)
+	}
+}
+
+func TestGrabServiceFingerprintsHTTP(t *testing.T) {
+	srv := fakeWebServer(t)
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+	dialer := net.Dialer{Timeout: httpTimeout}
+	conn, err := dialer.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	got := grabService(context.Background(), &dialer, conn, addr.Port)
+	if got.http == nil || got.http.Title != "Router & Admin" {
+		t.Fatalf("HTTP not fingerprinted: %+v", got)
+	}
+	if !strings.Contains(got.banner, "Router") {
+		t.Fatalf("title not used as banner without a Server header: %q", got.banner)
+	}
+}
@@ -0,0 +1,139 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/target"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	pingRequestGap  = 200 * time.Microsecond
+	pingReplyWindow = time.Second
+	icmpv4Protocol  = 1
+)
+
+// pingSweep sends one ICMP echo request to every IPv4 target and
+// records round trip time and TTL for each reply. It prefers unprivileged
+// ping sockets (Linux net.ipv4.ping_group_range, macOS) and falls back to a
+// raw socket; when neither can be opened the sweep returns nothing.
+func pingSweep(ctx context.Context, targets target.Set) []NeighborEntry {
+	conn, privileged, err := listenICMP4()
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	p := conn.IPv4PacketConn()
+	_ = p.SetControlMessage(ipv4.FlagTTL, true)
+
+	var mu sync.Mutex
+	sent := make(map[string]time.Time)
+	found := make(map[string]NeighborEntry)
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		buf := make([]byte, 1500)
+		for {
+			n, cm, peer, err := p.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			received := time.Now()
+			ip, ok := peerAddr(peer)
+			if !ok {
+				continue
+			}
+			payload, ttl := stripIPv4Header(buf[:n])
+			if cm != nil && cm.TTL > 0 {
+				ttl = cm.TTL
+			}
+			msg, err := icmp.ParseMessage(icmpv4Protocol, payload)
+			if err != nil || msg.Type != ipv4.ICMPTypeEchoReply {
+				continue
+			}
+
+			key := ip.String()
+			mu.Lock()
+			start, ok := sent[key]
+			if _, seen := found[key]; ok && !seen {
+				found[key] = NeighborEntry{IP: key, RTT: received.Sub(start), TTL: ttl}
+			}
+			mu.Unlock()
+		}
+	}()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	id := os.Getpid() & 0xffff
+	seq := 0
+	targets.Each(func(addr netip.Addr) bool {
+		if !addr.Is4() {
+			return true
+		}
+		seq++
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Body: &icmp.Echo{ID: id, Seq: seq & 0xffff, Data: []byte("nibble")},
+		}
+		payload, err := msg.Marshal(nil)
+		if err != nil {
+			return true
+		}
+
+		key := addr.String()
+		mu.Lock()
+		sent[key] = time.Now()
+		mu.Unlock()
+		_, _ = conn.WriteTo(payload, icmpDest4(addr.AsSlice(), privileged))
+		time.Sleep(pingRequestGap)
+		return ctx.Err() == nil
+	})
+
+	return awaitReplies(conn, pingReplyWindow, readDone, found)
+}
+
+// listenICMP4 prefers an unprivileged ping socket and falls back to a raw socket.
+func listenICMP4() (*icmp.PacketConn, bool, error) {
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err == nil {
+		return conn, false, nil
+	}
+	conn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, true, nil
+}
+
+func icmpDest4(ip net.IP, privileged bool) net.Addr {
+	dst := append(net.IP(nil), ip...)
+	if privileged {
+		return &net.IPAddr{IP: dst}
+	}
+	return &net.UDPAddr{IP: dst}
+}
+
+// stripIPv4Header drops the IP header some raw sockets deliver with the ICMP
+// payload and returns the header TTL when it was present.
+func stripIPv4Header(b []byte) ([]byte, int) {
+	if len(b) < 20 || b[0]>>4 != 4 {
+		return b, 0
+	}
+	headerLen := int(b[0]&0x0f) * 4
+	if headerLen < 20 || headerLen > len(b) {
+		return b, 0
+	}
+	return b[headerLen:], int(b[8])
+}
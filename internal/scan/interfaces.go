@@ -5,7 +5,8 @@ import (
 	"net/netip"
 )
 
-// DiscoverInterfaces returns active non-loopback interfaces with at least one IPv4 address.
+// DiscoverInterfaces returns active non-loopback interfaces with at least one IPv4
+// or global IPv6 address. Link-local IPv6 alone does not make an interface scannable.
 func DiscoverInterfaces() ([]net.Interface, map[string][]net.Addr, error) {
 	sysIfaces, err := net.Interfaces()
 	if err != nil {
@@ -29,7 +30,7 @@ func DiscoverInterfaces() ([]net.Interface, map[string][]net.Addr, error) {
 			continue
 		}
 
-		if hasIp4(addrs) {
+		if hasIp4(addrs) || hasGlobalIp6(addrs) {
 			ifaces = append(ifaces, iface)
 			addrsByIface[iface.Name] = addrs
 		}
@@ -48,6 +49,16 @@ func hasIp4(addrs []net.Addr) bool {
 	return false
 }
 
+func hasGlobalIp6(addrs []net.Addr) bool {
+	for _, addr := range addrs {
+		ip, ok := parseAddr(addr.String())
+		if ok && ip.Is6() && !ip.Is4In6() && ip.IsGlobalUnicast() {
+			return true
+		}
+	}
+	return false
+}
+
 func parseAddr(s string) (netip.Addr, bool) {
 	// addresses may be CIDR "192.168.1.10/24"
 	prefix, err := netip.ParsePrefix(s)
@@ -63,3 +74,35 @@ func parseAddr(s string) (netip.Addr, bool) {
 
 	return netip.Addr{}, false
 }
+
+// onLink lists the prefixes attached to an interface. Targets outside them
+// are routed, so ARP cannot reach them and the cache only knows the gateway.
+type onLink []netip.Prefix
+
+func linkNets(iface *net.Interface) onLink {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	var out onLink
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err == nil {
+			out = append(out, prefix.Masked())
+		}
+	}
+	return out
+}
+
+func (l onLink) contains(addr netip.Addr) bool {
+	addr = addr.WithZone("").Unmap()
+	if addr.IsLinkLocalUnicast() {
+		return true
+	}
+	for _, prefix := range l {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,9 @@
 package linux
 
 import (
+	"net/netip"
 	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/backendsystems/nibble/internal/scan/shared"
@@ -53,3 +55,43 @@ func Neighbors(ifaceName string) []Neighbor {
 
 	return rows
 }
+
+// Neighbors6 reads the IPv6 neighbor (NDP) cache for an interface via `ip -6 neigh`.
+// Link-local entries keep the interface as zone so they stay dialable.
+func Neighbors6(ifaceName string) []Neighbor {
+	out, err := exec.Command("ip", "-6", "neigh", "show", "dev", ifaceName).Output()
+	if err != nil {
+		return nil
+	}
+
+	rows := make([]Neighbor, 0)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		ip, err := netip.ParseAddr(fields[0])
+		if err != nil || !ip.Is6() {
+			continue
+		}
+
+		mac := ""
+		for i := 1; i < len(fields)-1; i++ {
+			if fields[i] == "lladdr" {
+				mac = shared.NormalizeMAC(fields[i+1])
+				break
+			}
+		}
+		if mac == "" {
+			continue
+		}
+
+		if ip.IsLinkLocalUnicast() {
+			ip = ip.WithZone(ifaceName)
+		}
+		rows = append(rows, Neighbor{IP: ip.String(), MAC: mac})
+	}
+
+	return rows
+}
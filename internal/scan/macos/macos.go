@@ -27,7 +27,16 @@ func LookupMAC(ip string) string {
 }
 
 func Neighbors(ifaceName string) []Neighbor {
-	rib, err := route.FetchRIB(syscall.AF_INET, route.RIBTypeRoute, 0)
+	return fetchNeighbors(syscall.AF_INET, ifaceName)
+}
+
+// Neighbors6 reads the IPv6 neighbor (NDP) entries from the routing table.
+func Neighbors6(ifaceName string) []Neighbor {
+	return fetchNeighbors(syscall.AF_INET6, ifaceName)
+}
+
+func fetchNeighbors(family int, ifaceName string) []Neighbor {
+	rib, err := route.FetchRIB(family, route.RIBTypeRoute, 0)
 	if err != nil || len(rib) == 0 {
 		return nil
 	}
@@ -69,8 +78,13 @@ func parseNeighbor(routeMsg *route.RouteMessage) (Neighbor, bool) {
 		return Neighbor{}, false
 	}
 
-	dst, ok := routeMsg.Addrs[syscall.RTAX_DST].(*route.Inet4Addr)
-	if !ok {
+	var addr netip.Addr
+	switch dst := routeMsg.Addrs[syscall.RTAX_DST].(type) {
+	case *route.Inet4Addr:
+		addr = netip.AddrFrom4(dst.IP)
+	case *route.Inet6Addr:
+		addr = netip.AddrFrom16(dst.IP)
+	default:
 		return Neighbor{}, false
 	}
 
@@ -79,7 +93,6 @@ func parseNeighbor(routeMsg *route.RouteMessage) (Neighbor, bool) {
 		return Neighbor{}, false
 	}
 
-	addr := netip.AddrFrom4(dst.IP)
 	mac := shared.NormalizeMAC(net.HardwareAddr(gateway.Addr).String())
 	if mac == "" {
 		return Neighbor{}, false
@@ -93,6 +106,10 @@ func parseNeighbor(routeMsg *route.RouteMessage) (Neighbor, bool) {
 		}
 	}
 
+	if addr.Is6() && addr.IsLinkLocalUnicast() && iface != "" {
+		addr = addr.WithZone(iface)
+	}
+
 	return Neighbor{
 		IP:    addr.String(),
 		MAC:   mac,
@@ -15,3 +15,7 @@ func LookupMAC(ip string) string {
 func Neighbors(ifaceName string) []Neighbor {
 	return nil
 }
+
+func Neighbors6(ifaceName string) []Neighbor {
+	return nil
+}
@@ -0,0 +1,321 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/target"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	mdnsBrowseWindow = 1500 * time.Millisecond
+	mdnsRequery      = 500 * time.Millisecond
+
+	// mdnsUnicastResponse is the QU bit, asking responders to reply to the
+	// querying port instead of the group.
+	mdnsUnicastResponse dnsmessage.Class = 1 << 15
+)
+
+var mdnsServicesName = dnsmessage.MustNewName("_services._dns-sd._udp.local.")
+
+// TXT keys devices use for their model and display name, most specific first.
+var (
+	mdnsModelKeys = []string{"md", "model", "am", "usb_MDL", "ty", "product"}
+	mdnsNameKeys  = []string{"fn", "n", "name"}
+)
+
+// mdnsBrowse enumerates DNS-SD service types on the link, resolves every
+// advertised instance and returns the advertising hosts among targets with
+// their services. Queries ask for unicast replies, the group is listened
+// to as well for responders that answer by multicast and for unsolicited
+// announcements.
+func mdnsBrowse(ctx context.Context, ifaceName string, targets target.Set) []NeighborEntry {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	_ = ipv4.NewPacketConn(conn).SetMulticastInterface(iface)
+
+	conns := []net.PacketConn{conn}
+	if group, err := net.ListenMulticastUDP("udp4", iface, mdnsGroup); err == nil {
+		defer group.Close()
+		conns = append(conns, group)
+	}
+	return browseMDNS(ctx, conns, mdnsGroup, targets)
+}
+
+type mdnsPacket struct {
+	data []byte
+	src  netip.Addr
+}
+
+// browseMDNS sends queries from the first conn to dst and follows up on
+// every reply read from conns until the browse window closes.
+func browseMDNS(ctx context.Context, conns []net.PacketConn, dst net.Addr, targets target.Set) []NeighborEntry {
+	ctx, cancel := context.WithTimeout(ctx, mdnsBrowseWindow)
+	defer cancel()
+
+	packets := make(chan mdnsPacket)
+	for _, conn := range conns {
+		stop := context.AfterFunc(ctx, func() {
+			_ = conn.SetReadDeadline(time.Now())
+		})
+		defer stop()
+		go readMDNS(ctx, conn, packets)
+	}
+
+	send := func(questions []dnsmessage.Question) {
+		if query := mdnsQuery(questions); query != nil {
+			_, _ = conns[0].WriteTo(query, dst)
+		}
+	}
+	enumerate := []dnsmessage.Question{{Name: mdnsServicesName, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET | mdnsUnicastResponse}}
+	send(enumerate)
+
+	requery := time.NewTicker(mdnsRequery)
+	defer requery.Stop()
+
+	browser := newMDNSBrowser()
+	for {
+		select {
+		case <-ctx.Done():
+			return browser.neighbors(targets)
+		case <-requery.C:
+			// Multicast is lossy, ask again for types that were missed.
+			send(enumerate)
+		case pkt := <-packets:
+			if questions := browser.handle(pkt.data, pkt.src); len(questions) > 0 {
+				send(questions)
+			}
+		}
+	}
+}
+
+func readMDNS(ctx context.Context, conn net.PacketConn, packets chan<- mdnsPacket) {
+	buf := make([]byte, 9000)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		src, ok := peerAddr(peer)
+		if !ok {
+			continue
+		}
+		select {
+		case packets <- mdnsPacket{data: slices.Clone(buf[:n]), src: src}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func mdnsQuery(questions []dnsmessage.Question) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	_ = b.StartQuestions()
+	for _, q := range questions {
+		if err := b.Question(q); err != nil {
+			return nil
+		}
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// mdnsInstance is an advertised service instance being resolved.
+type mdnsInstance struct {
+	name    dnsmessage.Name
+	service scanner.MDNSService
+	target  string     // Lowercased SRV target, looked up in the A records.
+	src     netip.Addr // Responder that advertised it.
+	asked   bool       // SRV and TXT were queried.
+}
+
+// mdnsBrowser keeps what replies have told so far and works out which
+// names still need asking about.
+type mdnsBrowser struct {
+	types     map[string]bool
+	instances map[string]*mdnsInstance // By lowercased full instance name.
+	order     []string
+	addrs     map[string]netip.Addr // A records by lowercased host name.
+}
+
+func newMDNSBrowser() *mdnsBrowser {
+	return &mdnsBrowser{
+		types:     make(map[string]bool),
+		instances: make(map[string]*mdnsInstance),
+		addrs:     make(map[string]netip.Addr),
+	}
+}
+
+// handle records a reply's PTR, SRV, TXT and A records and returns the
+// questions it raises: instances of new service types, then SRV and TXT of
+// new instances the reply did not already resolve.
+func (b *mdnsBrowser) handle(reply []byte, src netip.Addr) []dnsmessage.Question {
+	records := mdnsRecords(reply)
+	var fresh []*mdnsInstance
+	var questions []dnsmessage.Question
+
+	// PTRs first so SRV and TXT in the same reply find their instance.
+	for _, rr := range records {
+		ptr, ok := rr.Body.(*dnsmessage.PTRResource)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(rr.Header.Name.String())
+		if name == mdnsServicesName.String() {
+			serviceType := strings.ToLower(ptr.PTR.String())
+			if !b.types[serviceType] {
+				b.types[serviceType] = true
+				questions = append(questions, dnsmessage.Question{Name: ptr.PTR, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET | mdnsUnicastResponse})
+			}
+			continue
+		}
+		if inst := b.instance(ptr.PTR, name, src); inst != nil && !inst.asked {
+			fresh = append(fresh, inst)
+		}
+	}
+
+	for _, rr := range records {
+		name := strings.ToLower(rr.Header.Name.String())
+		switch body := rr.Body.(type) {
+		case *dnsmessage.SRVResource:
+			if inst, ok := b.instances[name]; ok {
+				inst.service.Port = int(body.Port)
+				inst.service.Host = cleanName(strings.TrimSuffix(body.Target.String(), "."))
+				inst.target = strings.ToLower(body.Target.String())
+				inst.asked = true
+			}
+		case *dnsmessage.TXTResource:
+			if inst, ok := b.instances[name]; ok {
+				inst.service.TXT = parseTXT(body.TXT)
+			}
+		case *dnsmessage.AResource:
+			b.addrs[name] = netip.AddrFrom4(body.A)
+		}
+	}
+
+	for _, inst := range fresh {
+		if inst.asked {
+			continue
+		}
+		inst.asked = true
+		questions = append(questions,
+			dnsmessage.Question{Name: inst.name, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET | mdnsUnicastResponse},
+			dnsmessage.Question{Name: inst.name, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET | mdnsUnicastResponse},
+		)
+	}
+	return questions
+}
+
+// instance returns the instance named name, creating it on first sight.
+// It returns nil when name is not an instance of serviceType.
+func (b *mdnsBrowser) instance(name dnsmessage.Name, serviceType string, src netip.Addr) *mdnsInstance {
+	full := name.String()
+	key := strings.ToLower(full)
+	if inst, ok := b.instances[key]; ok {
+		return inst
+	}
+	if !strings.HasSuffix(key, "."+serviceType) {
+		return nil
+	}
+	inst := &mdnsInstance{
+		name: name,
+		service: scanner.MDNSService{
+			Instance: cleanName(full[:len(full)-len(serviceType)-1]),
+			Type:     cleanName(strings.TrimSuffix(full[len(full)-len(serviceType):], ".local.")),
+		},
+		src: src,
+	}
+	b.instances[key] = inst
+	b.order = append(b.order, key)
+	return inst
+}
+
+// neighbors groups the instances by the address their SRV target resolves
+// to, or the responder's address when no A record was seen.
+func (b *mdnsBrowser) neighbors(targets target.Set) []NeighborEntry {
+	index := make(map[netip.Addr]int)
+	var out []NeighborEntry
+	for _, key := range b.order {
+		inst := b.instances[key]
+		addr, ok := b.addrs[inst.target]
+		if !ok {
+			addr = inst.src
+		}
+		if !addr.IsValid() || !targets.Contains(addr) {
+			continue
+		}
+		service := inst.service
+		service.Model = firstTXT(service.TXT, mdnsModelKeys)
+		service.FriendlyName = firstTXT(service.TXT, mdnsNameKeys)
+
+		i, ok := index[addr]
+		if !ok {
+			i = len(out)
+			index[addr] = i
+			out = append(out, NeighborEntry{IP: addr.String()})
+		}
+		out[i].MDNS = append(out[i].MDNS, service)
+	}
+	return out
+}
+
+// mdnsRecords returns the answer and additional records of a response.
+func mdnsRecords(reply []byte) []dnsmessage.Resource {
+	var p dnsmessage.Parser
+	header, err := p.Start(reply)
+	if err != nil || !header.Response {
+		return nil
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil
+	}
+	records, err := p.AllAnswers()
+	if err != nil {
+		return nil
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		return records
+	}
+	additionals, _ := p.AllAdditionals()
+	return append(records, additionals...)
+}
+
+func parseTXT(entries []string) map[string]string {
+	txt := make(map[string]string)
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "=")
+		if key = cleanName(key); key != "" {
+			txt[key] = cleanName(value)
+		}
+	}
+	if len(txt) == 0 {
+		return nil
+	}
+	return txt
+}
+
+func firstTXT(txt map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(txt[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
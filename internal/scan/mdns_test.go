@@ -0,0 +1,145 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/target"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeChromecast answers DNS-SD queries one step at a time, so the browser
+// has to follow up with PTR, SRV and TXT questions itself.
+func fakeChromecast(query []byte) []byte {
+	var p dnsmessage.Parser
+	if _, err := p.Start(query); err != nil {
+		return nil
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil
+	}
+
+	instance := dnsmessage.MustNewName("Chromecast-1a2b._googlecast._tcp.local.")
+	serviceType := dnsmessage.MustNewName("_googlecast._tcp.local.")
+	host := dnsmessage.MustNewName("1a2b.local.")
+	header := func(name dnsmessage.Name, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	_ = b.StartAnswers()
+	for _, q := range questions {
+		switch {
+		case q.Type == dnsmessage.TypePTR && q.Name == mdnsServicesName:
+			_ = b.PTRResource(header(mdnsServicesName, dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: serviceType})
+		case q.Type == dnsmessage.TypePTR && q.Name == serviceType:
+			_ = b.PTRResource(header(serviceType, dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: instance})
+		case q.Type == dnsmessage.TypeSRV && q.Name == instance:
+			_ = b.SRVResource(header(instance, dnsmessage.TypeSRV), dnsmessage.SRVResource{Port: 8009, Target: host})
+			_ = b.AResource(header(host, dnsmessage.TypeA), dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+		case q.Type == dnsmessage.TypeTXT && q.Name == instance:
+			_ = b.TXTResource(header(instance, dnsmessage.TypeTXT), dnsmessage.TXTResource{
+				TXT: []string{"id=1a2b", "md=Chromecast Ultra", "fn=Living Room TV"},
+			})
+		}
+	}
+	msg, _ := b.Finish()
+	return msg
+}
+
+func TestBrowseMDNS(t *testing.T) {
+	port := fakeUDPService(t, fakeChromecast)
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	targets, _ := target.Parse("127.0.0.0/8")
+	dst := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+	got := browseMDNS(context.Background(), []net.PacketConn{conn}, dst, targets)
+
+	want := []NeighborEntry{{
+		IP: "127.0.0.1",
+		MDNS: []scanner.MDNSService{{
+			Instance:     "Chromecast-1a2b",
+			Type:         "_googlecast._tcp",
+			Host:         "1a2b.local",
+			Port:         8009,
+			Model:        "Chromecast Ultra",
+			FriendlyName: "Living Room TV",
+			TXT:          map[string]string{"id": "1a2b", "md": "Chromecast Ultra", "fn": "Living Room TV"},
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+	if summary := got[0].MDNS[0].Summary(); summary != "_googlecast._tcp:8009: Living Room TV, Chromecast Ultra" {
+		t.Fatalf("summary %q", summary)
+	}
+}
+
+func TestMDNSBrowserAnnouncement(t *testing.T) {
+	// An unsolicited announcement carries every record at once and the
+	// host's address can differ from the sender's, e.g. a sleep proxy.
+	printer := dnsmessage.MustNewName("Office._ipp._tcp.local.")
+	host := dnsmessage.MustNewName("office-printer.local.")
+	header := func(name dnsmessage.Name, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	_ = b.StartAnswers()
+	_ = b.PTRResource(header(dnsmessage.MustNewName("_ipp._tcp.local."), dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: printer})
+	_ = b.SRVResource(header(printer, dnsmessage.TypeSRV), dnsmessage.SRVResource{Port: 631, Target: host})
+	_ = b.TXTResource(header(printer, dnsmessage.TypeTXT), dnsmessage.TXTResource{TXT: []string{"ty=HP LaserJet M404"}})
+	_ = b.AResource(header(host, dnsmessage.TypeA), dnsmessage.AResource{A: [4]byte{192, 168, 1, 77}})
+	msg, _ := b.Finish()
+
+	browser := newMDNSBrowser()
+	if questions := browser.handle(msg, netip.MustParseAddr("192.168.1.2")); len(questions) != 0 {
+		t.Fatalf("resolved instance raised questions: %+v", questions)
+	}
+
+	inside, _ := target.Parse("192.168.1.0/24")
+	got := browser.neighbors(inside)
+	if len(got) != 1 || got[0].IP != "192.168.1.77" || got[0].MDNS[0].Model != "HP LaserJet M404" {
+		t.Fatalf("got %+v", got)
+	}
+
+	outside, _ := target.Parse("10.0.0.0/24")
+	if got := browser.neighbors(outside); len(got) != 0 {
+		t.Fatalf("host outside targets kept: %+v", got)
+	}
+}
+
+func TestMDNSBrowserCleansNames(t *testing.T) {
+	speaker := dnsmessage.MustNewName("Küche\x1b[2J\x07._airplay._tcp.local.")
+	host := dnsmessage.MustNewName("kueche.local.")
+	header := func(name dnsmessage.Name, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	_ = b.StartAnswers()
+	_ = b.PTRResource(header(dnsmessage.MustNewName("_airplay._tcp.local."), dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: speaker})
+	_ = b.SRVResource(header(speaker, dnsmessage.TypeSRV), dnsmessage.SRVResource{Port: 7000, Target: host})
+	_ = b.TXTResource(header(speaker, dnsmessage.TypeTXT), dnsmessage.TXTResource{TXT: []string{"model=Audio\r\nPro", "fn=Küche\x00"}})
+	_ = b.AResource(header(host, dnsmessage.TypeA), dnsmessage.AResource{A: [4]byte{192, 168, 1, 78}})
+	msg, _ := b.Finish()
+
+	browser := newMDNSBrowser()
+	browser.handle(msg, netip.MustParseAddr("192.168.1.78"))
+	inside, _ := target.Parse("192.168.1.0/24")
+	got := browser.neighbors(inside)
+	if len(got) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+	service := got[0].MDNS[0]
+	if service.Instance != "Küche[2J" || service.TXT["model"] != "AudioPro" || service.TXT["fn"] != "Küche" {
+		t.Fatalf("service %+v", service)
+	}
+}
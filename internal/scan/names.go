@@ -0,0 +1,274 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+const nameTimeout = 800 * time.Millisecond
+
+var (
+	mdnsGroup   = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+	mdnsPort    = 5353
+	netbiosPort = 137
+)
+
+// lookupAddr is the system resolver's PTR lookup, replaced in tests.
+var lookupAddr = net.DefaultResolver.LookupAddr
+
+// nameLookup asks one naming service for a host's name.
+type nameLookup struct {
+	source string
+	lookup func(ctx context.Context, ifaceName string, ip netip.Addr) string
+}
+
+// nameLookups run in parallel, the first source listed that finds a name
+// becomes the host's display name.
+var nameLookups = []nameLookup{
+	{scanner.NameDNS, reverseDNSName},
+	{scanner.NameMDNS, mdnsName},
+	{scanner.NameNetBIOS, netbiosName},
+}
+
+// resolveNames asks reverse DNS, mDNS and NetBIOS for the host's name at
+// the same time and records every distinct answer.
+func resolveNames(ctx context.Context, ifaceName string, host *scanner.HostResult) {
+	ip, err := netip.ParseAddr(host.IP)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, nameTimeout)
+	defer cancel()
+
+	found := make([]string, len(nameLookups))
+	var wg sync.WaitGroup
+	for i, lookup := range nameLookups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found[i] = lookup.lookup(ctx, ifaceName, ip)
+		}()
+	}
+	wg.Wait()
+
+	for i, name := range found {
+		if name == "" {
+			continue
+		}
+		host.Names = append(host.Names, scanner.HostName{Name: name, Source: nameLookups[i].source})
+		if host.Name == "" {
+			host.Name = name
+		}
+	}
+}
+
+func reverseDNSName(ctx context.Context, _ string, ip netip.Addr) string {
+	names, err := lookupAddr(ctx, ip.String())
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// mdnsName asks for the host's reverse PTR record, both to the host itself
+// and to the mDNS group, with the unicast-response bit set.
+func mdnsName(ctx context.Context, ifaceName string, ip netip.Addr) string {
+	name, err := dnsmessage.NewName(reverseName(ip))
+	if err != nil {
+		return ""
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{
+		Name:  name,
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET | mdnsUnicastResponse,
+	})
+	query, err := b.Finish()
+	if err != nil {
+		return ""
+	}
+
+	dsts := []*net.UDPAddr{{IP: ip.AsSlice(), Port: mdnsPort, Zone: ip.Zone()}}
+	if ip.Is4() {
+		dsts = append(dsts, mdnsGroup)
+	}
+	return queryName(ctx, ifaceName, ip, dsts, query, func(reply []byte) string {
+		return parseMDNSName(reply, name)
+	})
+}
+
+// parseMDNSName returns the PTR target answering question, without the dot.
+func parseMDNSName(reply []byte, question dnsmessage.Name) string {
+	var p dnsmessage.Parser
+	header, err := p.Start(reply)
+	if err != nil || !header.Response {
+		return ""
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return ""
+	}
+	for {
+		answer, err := p.Answer()
+		if err != nil {
+			return ""
+		}
+		ptr, ok := answer.Body.(*dnsmessage.PTRResource)
+		if ok && strings.EqualFold(answer.Header.Name.String(), question.String()) {
+			return cleanName(strings.TrimSuffix(ptr.PTR.String(), "."))
+		}
+	}
+}
+
+// reverseName builds the in-addr.arpa or ip6.arpa name for ip.
+func reverseName(ip netip.Addr) string {
+	var b strings.Builder
+	raw := ip.AsSlice()
+	if ip.Is4() {
+		for i := len(raw) - 1; i >= 0; i-- {
+			b.WriteString(strconv.Itoa(int(raw[i])))
+			b.WriteByte('.')
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String()
+	}
+	const hex = "0123456789abcdef"
+	for i := len(raw) - 1; i >= 0; i-- {
+		b.WriteByte(hex[raw[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hex[raw[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String()
+}
+
+// netbiosName sends a NetBIOS node status request, which Windows, Samba
+// and many NAS boxes answer with their machine name.
+func netbiosName(ctx context.Context, ifaceName string, ip netip.Addr) string {
+	if !ip.Is4() {
+		return ""
+	}
+	dst := &net.UDPAddr{IP: ip.AsSlice(), Port: netbiosPort}
+	return queryName(ctx, ifaceName, ip, []*net.UDPAddr{dst}, netbiosStatusRequest(), parseNetBIOSStatus)
+}
+
+func netbiosStatusRequest() []byte {
+	msg := make([]byte, 12, 50)
+	binary.BigEndian.PutUint16(msg, uint16(rand.Uint32()))
+	binary.BigEndian.PutUint16(msg[4:], 1) // one question
+
+	// The wildcard name "*" padded with zeros, in first-level encoding.
+	msg = append(msg, 32)
+	for i := range 16 {
+		c := byte(0)
+		if i == 0 {
+			c = '*'
+		}
+		msg = append(msg, 'A'+c>>4, 'A'+c&0x0f)
+	}
+	msg = append(msg, 0)
+	return append(msg, 0x00, 0x21, 0x00, 0x01) // NBSTAT, IN
+}
+
+// parseNetBIOSStatus picks the unique workstation name from a node status
+// reply, falling back to the file server name.
+func parseNetBIOSStatus(reply []byte) string {
+	if len(reply) < 12 || reply[2]&0x80 == 0 {
+		return ""
+	}
+	rest := reply[12:]
+	if len(rest) >= 2 && rest[0]&0xc0 == 0xc0 {
+		rest = rest[2:]
+	} else if end := bytes.IndexByte(rest, 0); end >= 0 {
+		rest = rest[end+1:]
+	} else {
+		return ""
+	}
+	// Type, class, TTL and data length precede the name table.
+	if len(rest) < 11 {
+		return ""
+	}
+	count := int(rest[10])
+	table := rest[11:]
+
+	fallback := ""
+	for i := 0; i < count && len(table) >= 18; i++ {
+		entry := table[:18]
+		table = table[18:]
+		name := cleanName(strings.TrimRight(string(entry[:15]), " \x00"))
+		suffix := entry[15]
+		group := entry[16]&0x80 != 0
+		if group || name == "" {
+			continue
+		}
+		switch suffix {
+		case 0x00:
+			return name
+		case 0x20:
+			if fallback == "" {
+				fallback = name
+			}
+		}
+	}
+	return fallback
+}
+
+// queryName sends one UDP request to each destination and returns the
+// first name parse finds in a reply from ip.
+func queryName(ctx context.Context, ifaceName string, ip netip.Addr, dsts []*net.UDPAddr, request []byte, parse func([]byte) string) string {
+	network := "udp4"
+	if !ip.Is4() {
+		network = "udp6"
+	}
+	conn, err := net.ListenPacket(network, ":0")
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	if iface, err := net.InterfaceByName(ifaceName); err == nil && ip.Is4() {
+		_ = ipv4.NewPacketConn(conn).SetMulticastInterface(iface)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+
+	for _, dst := range dsts {
+		_, _ = conn.WriteTo(request, dst)
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return ""
+		}
+		from, ok := peer.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		if addr, _ := netip.AddrFromSlice(from.IP); addr.Unmap() != ip.WithZone("") {
+			continue
+		}
+		if name := parse(buf[:n]); name != "" {
+			return name
+		}
+	}
+}
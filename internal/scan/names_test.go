@@ -0,0 +1,181 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeUDPService answers each datagram on loopback with reply(request),
+// staying silent when reply returns nil. It returns the listening port.
+func fakeUDPService(t *testing.T, reply func([]byte) []byte) int {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if out := reply(buf[:n]); out != nil {
+				_, _ = conn.WriteTo(out, peer)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func mdnsPTRReply(query []byte, target string) []byte {
+	var p dnsmessage.Parser
+	if _, err := p.Start(query); err != nil {
+		return nil
+	}
+	q, err := p.Question()
+	if err != nil || q.Type != dnsmessage.TypePTR {
+		return nil
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	_ = b.StartAnswers()
+	_ = b.PTRResource(
+		dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: 120},
+		dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(target)},
+	)
+	msg, _ := b.Finish()
+	return msg
+}
+
+type netbiosEntry struct {
+	name   string
+	suffix byte
+	group  bool
+}
+
+func netbiosStatusReply(request []byte, entries ...netbiosEntry) []byte {
+	msg := append([]byte{}, request[:2]...)
+	msg = append(msg, 0x84, 0x00, 0, 0, 0, 1, 0, 0, 0, 0)
+	msg = append(msg, request[12:46]...) // The question name, uncompressed.
+	msg = append(msg, 0x00, 0x21, 0x00, 0x01, 0, 0, 0, 0)
+	length := 1 + 18*len(entries) + 6
+	msg = append(msg, byte(length>>8), byte(length), byte(len(entries)))
+	for _, e := range entries {
+		name := make([]byte, 15)
+		copy(name, bytes.Repeat([]byte{' '}, 15))
+		copy(name, e.name)
+		flags := byte(0x04)
+		if e.group {
+			flags |= 0x80
+		}
+		msg = append(msg, name...)
+		msg = append(msg, e.suffix, flags, 0x00)
+	}
+	return append(msg, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55) // Unit ID.
+}
+
+func TestResolveNames(t *testing.T) {
+	oldLookup, oldMDNS, oldNetBIOS := lookupAddr, mdnsPort, netbiosPort
+	t.Cleanup(func() { lookupAddr, mdnsPort, netbiosPort = oldLookup, oldMDNS, oldNetBIOS })
+
+	lookupAddr = func(_ context.Context, addr string) ([]string, error) {
+		if addr != "127.0.0.1" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"nas.lan."}, nil
+	}
+	mdnsPort = fakeUDPService(t, func(query []byte) []byte {
+		return mdnsPTRReply(query, "nas.local.")
+	})
+	netbiosPort = fakeUDPService(t, func(request []byte) []byte {
+		if len(request) != 50 || string(request[13:15]) != "CK" {
+			return nil
+		}
+		return netbiosStatusReply(request,
+			netbiosEntry{name: "WORKGROUP", suffix: 0x00, group: true},
+			netbiosEntry{name: "NAS", suffix: 0x20},
+			netbiosEntry{name: "NAS", suffix: 0x00},
+		)
+	})
+
+	host := scanner.HostResult{IP: "127.0.0.1"}
+	resolveNames(context.Background(), "lo", &host)
+
+	want := []scanner.HostName{
+		{Name: "nas.lan", Source: scanner.NameDNS},
+		{Name: "nas.local", Source: scanner.NameMDNS},
+		{Name: "NAS", Source: scanner.NameNetBIOS},
+	}
+	if host.Name != "nas.lan" || !slices.Equal(host.Names, want) {
+		t.Fatalf("got name %q, names %+v", host.Name, host.Names)
+	}
+}
+
+func TestResolveNamesFallsBackToLaterSources(t *testing.T) {
+	oldLookup, oldMDNS, oldNetBIOS := lookupAddr, mdnsPort, netbiosPort
+	t.Cleanup(func() { lookupAddr, mdnsPort, netbiosPort = oldLookup, oldMDNS, oldNetBIOS })
+
+	lookupAddr = func(context.Context, string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	mdnsPort = fakeUDPService(t, func([]byte) []byte { return nil })
+	netbiosPort = fakeUDPService(t, func(request []byte) []byte {
+		return netbiosStatusReply(request, netbiosEntry{name: "DESKTOP-1A2B3C", suffix: 0x20})
+	})
+
+	host := scanner.HostResult{IP: "127.0.0.1"}
+	resolveNames(context.Background(), "lo", &host)
+	if host.Name != "DESKTOP-1A2B3C" || len(host.Names) != 1 {
+		t.Fatalf("got name %q, names %+v", host.Name, host.Names)
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.20": "20.1.168.192.in-addr.arpa.",
+		"fe80::1":      "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.e.f.ip6.arpa.",
+	}
+	for ip, want := range tests {
+		if got := reverseName(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("reverseName(%s) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestParseNetBIOSStatusRejectsQueries(t *testing.T) {
+	if got := parseNetBIOSStatus(netbiosStatusRequest()); got != "" {
+		t.Fatalf("parsed a name from a request: %q", got)
+	}
+	// A reply cut off inside a compressed name pointer.
+	short := make([]byte, 13)
+	short[2], short[12] = 0x84, 0xc0
+	if got := parseNetBIOSStatus(short); got != "" {
+		t.Fatalf("parsed a name from a short reply: %q", got)
+	}
+}
+
+func TestNamesDropControlCharacters(t *testing.T) {
+	reply := netbiosStatusReply(netbiosStatusRequest(), netbiosEntry{name: "DESK\x1b[2JTOP", suffix: 0x00})
+	if got := parseNetBIOSStatus(reply); got != "DESK[2JTOP" {
+		t.Fatalf("netbios name %q", got)
+	}
+
+	question, _ := dnsmessage.NewName(reverseName(netip.MustParseAddr("192.168.1.20")))
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{Name: question, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+	query, _ := b.Finish()
+	if got := parseMDNSName(mdnsPTRReply(query, "Küche\a\x7f.local."), question); got != "Küche.local" {
+		t.Fatalf("mdns name %q", got)
+	}
+}
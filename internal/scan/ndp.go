@@ -0,0 +1,338 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scope"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	echoListenWindow    = time.Second
+	solicitListenWindow = 500 * time.Millisecond
+	icmpv6Protocol      = 58
+)
+
+var allNodesMulticast = netip.MustParseAddr("ff02::1")
+
+// discoverNeighbors6 finds on-link IPv6 hosts without sweeping the prefix.
+// It pings the all-nodes group from every local source address, solicits
+// responders and their likely global addresses, then merges the NDP cache.
+// Excluded addresses are never solicited. With MAC excludes, hosts whose MAC
+// stays unknown are left out, they cannot be told apart from excluded ones.
+func discoverNeighbors6(ctx context.Context, ifaceName string, subnet *net.IPNet, exclude *scope.Rules) []NeighborEntry {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return visibleNeighbors6(ifaceName, subnet)
+	}
+
+	local := localAddrs6(iface)
+	responders := pingAllNodes6(ctx, iface, local)
+	targets := slices.DeleteFunc(solicitTargets(responders, subnet), func(addr netip.Addr) bool {
+		return exclude.ExcludesIP(addr.String())
+	})
+	solicited := solicitNeighbors6(ctx, iface, targets)
+
+	var neighbors []NeighborEntry
+	seen := make(map[string]struct{})
+	add := func(addr netip.Addr, mac string) {
+		// Multicast echoes loop back, so our own addresses can show up too.
+		if _, ok := local[addr.WithZone("")]; ok || !onLink6(addr, subnet) {
+			return
+		}
+		if exclude.ExcludesIP(addr.String()) || (exclude.HasMACs() && mac == "") {
+			return
+		}
+		key := addr.String()
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		neighbors = append(neighbors, NeighborEntry{IP: key, MAC: mac})
+	}
+
+	// Replies and solicitations refresh the kernel NDP cache, so read it last.
+	for _, neighbor := range visibleNeighbors6(ifaceName, subnet) {
+		if addr, err := netip.ParseAddr(neighbor.IP); err == nil {
+			add(addr, neighbor.MAC)
+		}
+	}
+	for addr, mac := range solicited {
+		add(addr, mac)
+	}
+	for _, addr := range responders {
+		add(addr, solicited[addr])
+	}
+	return neighbors
+}
+
+// localAddrs6 returns the interface's own IPv6 addresses without zones.
+func localAddrs6(iface *net.Interface) map[netip.Addr]struct{} {
+	out := make(map[netip.Addr]struct{})
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return out
+	}
+	for _, addr := range addrs {
+		ip, ok := parseAddr(addr.String())
+		if ok && ip.Is6() && !ip.Is4In6() {
+			out[ip] = struct{}{}
+		}
+	}
+	return out
+}
+
+// pingAllNodes6 sends an echo request to ff02::1 from each local address.
+// Hosts answer from the same scope as the source, so pinging from a global
+// address finds global addresses and pinging from fe80:: finds link-local ones.
+func pingAllNodes6(ctx context.Context, iface *net.Interface, local map[netip.Addr]struct{}) []netip.Addr {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	found := make(map[netip.Addr]struct{})
+
+	for src := range local {
+		wg.Add(1)
+		go func(src netip.Addr) {
+			defer wg.Done()
+			if src.IsLinkLocalUnicast() {
+				src = src.WithZone(iface.Name)
+			}
+			for _, addr := range pingMulticast6(ctx, iface, src) {
+				mu.Lock()
+				found[addr] = struct{}{}
+				mu.Unlock()
+			}
+		}(src)
+	}
+	wg.Wait()
+
+	out := make([]netip.Addr, 0, len(found))
+	for addr := range found {
+		out = append(out, addr)
+	}
+	return out
+}
+
+func pingMulticast6(ctx context.Context, iface *net.Interface, src netip.Addr) []netip.Addr {
+	conn, privileged, err := listenICMP6(src.String())
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	if p := conn.IPv6PacketConn(); p != nil {
+		_ = p.SetMulticastInterface(iface)
+		_ = p.SetMulticastHopLimit(1)
+	}
+
+	msg := icmp.Message{
+		Type: ipv6.ICMPTypeEchoRequest,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: 1, Data: []byte("nibble")},
+	}
+	payload, err := msg.Marshal(nil)
+	if err != nil {
+		return nil
+	}
+	if _, err := conn.WriteTo(payload, icmpDest(allNodesMulticast.WithZone(iface.Name), privileged)); err != nil {
+		return nil
+	}
+
+	var out []netip.Addr
+	readICMP6(ctx, conn, echoListenWindow, func(reply *icmp.Message, peer netip.Addr) {
+		if reply.Type == ipv6.ICMPTypeEchoReply {
+			out = append(out, withLinkZone(peer, iface.Name))
+		}
+	})
+	return out
+}
+
+// solicitTargets returns addresses worth a Neighbor Solicitation: every
+// responder plus the subnet prefix joined with each link-local interface ID,
+// which finds SLAAC global addresses that did not answer the multicast ping.
+func solicitTargets(responders []netip.Addr, subnet *net.IPNet) []netip.Addr {
+	targets := append([]netip.Addr(nil), responders...)
+	prefix, ok := netip.AddrFromSlice(subnet.IP.To16())
+	ones, _ := subnet.Mask.Size()
+	if !ok || ones > 64 {
+		return targets
+	}
+
+	base := prefix.As16()
+	for _, addr := range responders {
+		if !addr.IsLinkLocalUnicast() {
+			continue
+		}
+		guess := base
+		iid := addr.As16()
+		copy(guess[8:], iid[8:])
+		targets = append(targets, netip.AddrFrom16(guess))
+	}
+	return targets
+}
+
+// solicitNeighbors6 sends Neighbor Solicitations and returns the link-layer
+// address from every advertisement. It needs a raw ICMPv6 socket and returns
+// nothing when the process lacks CAP_NET_RAW.
+func solicitNeighbors6(ctx context.Context, iface *net.Interface, targets []netip.Addr) map[netip.Addr]string {
+	found := make(map[netip.Addr]string)
+	if len(targets) == 0 || len(iface.HardwareAddr) == 0 {
+		return found
+	}
+
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return found
+	}
+	defer conn.Close()
+
+	p := conn.IPv6PacketConn()
+	_ = p.SetMulticastInterface(iface)
+	_ = p.SetMulticastHopLimit(255)
+	_ = p.SetHopLimit(255)
+
+	for _, target := range targets {
+		msg := icmp.Message{
+			Type: ipv6.ICMPTypeNeighborSolicitation,
+			Body: &icmp.RawBody{Data: neighborSolicitation(target, iface.HardwareAddr)},
+		}
+		payload, err := msg.Marshal(nil)
+		if err != nil {
+			continue
+		}
+		_, _ = conn.WriteTo(payload, icmpDest(solicitedNode(target).WithZone(iface.Name), true))
+	}
+
+	readICMP6(ctx, conn, solicitListenWindow, func(reply *icmp.Message, _ netip.Addr) {
+		if reply.Type != ipv6.ICMPTypeNeighborAdvertisement {
+			return
+		}
+		body, ok := reply.Body.(*icmp.RawBody)
+		if !ok {
+			return
+		}
+		target, mac, ok := parseNeighborAdvertisement(body.Data)
+		if ok {
+			found[withLinkZone(target, iface.Name)] = mac
+		}
+	})
+	return found
+}
+
+// neighborSolicitation builds the NS body: reserved, target and source
+// link-layer option. The option is left out for addresses other than
+// Ethernet's, which do not fit its 8 bytes.
+func neighborSolicitation(target netip.Addr, srcMAC net.HardwareAddr) []byte {
+	body := make([]byte, 20, 28)
+	t := target.As16()
+	copy(body[4:20], t[:])
+	if len(srcMAC) != 6 {
+		return body
+	}
+	body = append(body, 1, 1) // source link-layer address, 8 bytes
+	return append(body, srcMAC...)
+}
+
+// parseNeighborAdvertisement returns the target address and its link-layer address.
+func parseNeighborAdvertisement(body []byte) (netip.Addr, string, bool) {
+	if len(body) < 20 {
+		return netip.Addr{}, "", false
+	}
+	target := netip.AddrFrom16([16]byte(body[4:20]))
+	opts := body[20:]
+	for len(opts) >= 8 {
+		size := int(opts[1]) * 8
+		if size == 0 || size > len(opts) {
+			break
+		}
+		// Option 2 is the target link-layer address.
+		if opts[0] == 2 && size >= 8 {
+			return target, net.HardwareAddr(opts[2:8]).String(), true
+		}
+		opts = opts[size:]
+	}
+	return netip.Addr{}, "", false
+}
+
+// solicitedNode returns the ff02::1:ffXX:XXXX group a target listens on.
+func solicitedNode(target netip.Addr) netip.Addr {
+	t := target.As16()
+	group := [16]byte{0xff, 0x02, 11: 0x01, 12: 0xff}
+	copy(group[13:], t[13:])
+	return netip.AddrFrom16(group)
+}
+
+// listenICMP6 prefers an unprivileged ping socket and falls back to a raw socket.
+func listenICMP6(address string) (*icmp.PacketConn, bool, error) {
+	conn, err := icmp.ListenPacket("udp6", address)
+	if err == nil {
+		return conn, false, nil
+	}
+	conn, err = icmp.ListenPacket("ip6:ipv6-icmp", address)
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, true, nil
+}
+
+func icmpDest(addr netip.Addr, privileged bool) net.Addr {
+	if privileged {
+		return &net.IPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}
+	}
+	return &net.UDPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}
+}
+
+// readICMP6 reads messages until the window closes or ctx is cancelled.
+func readICMP6(ctx context.Context, conn *icmp.PacketConn, window time.Duration, handle func(*icmp.Message, netip.Addr)) {
+	_ = conn.SetReadDeadline(time.Now().Add(window))
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		msg, err := icmp.ParseMessage(icmpv6Protocol, buf[:n])
+		if err != nil {
+			continue
+		}
+		addr, ok := peerAddr(peer)
+		if !ok {
+			continue
+		}
+		handle(msg, addr)
+	}
+}
+
+func peerAddr(peer net.Addr) (netip.Addr, bool) {
+	var ip net.IP
+	switch typed := peer.(type) {
+	case *net.UDPAddr:
+		ip = typed.IP
+	case *net.IPAddr:
+		ip = typed.IP
+	default:
+		return netip.Addr{}, false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	return addr.Unmap(), ok
+}
+
+// withLinkZone scopes link-local addresses to the interface so they can be dialed.
+func withLinkZone(addr netip.Addr, ifaceName string) netip.Addr {
+	if addr.IsLinkLocalUnicast() {
+		return addr.WithZone(ifaceName)
+	}
+	return addr.WithZone("")
+}
@@ -0,0 +1,72 @@
+package scan
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestNeighborSolicitation(t *testing.T) {
+	target := netip.MustParseAddr("fe80::1")
+	body := neighborSolicitation(target, net.HardwareAddr{0xaa, 0xbb, 0xcc, 0, 0, 1})
+	if len(body) != 28 || netip.AddrFrom16([16]byte(body[4:20])) != target || body[20] != 1 || body[21] != 1 {
+		t.Fatalf("solicitation % x", body)
+	}
+	// An InfiniBand address does not fit the option, which is left out.
+	if body := neighborSolicitation(target, make(net.HardwareAddr, 20)); len(body) != 20 {
+		t.Fatalf("solicitation with a long address % x", body)
+	}
+}
+
+func TestParseNeighborAdvertisement(t *testing.T) {
+	target := netip.MustParseAddr("2001:db8::10")
+	t16 := target.As16()
+	body := append([]byte{0x60, 0, 0, 0}, t16[:]...)
+	nonce := []byte{14, 1, 0, 0, 0, 0, 0, 0}
+	tll := []byte{2, 1, 0xaa, 0xbb, 0xcc, 0, 0, 0x10}
+
+	addr, mac, ok := parseNeighborAdvertisement(slices.Concat(body, nonce, tll))
+	if !ok || addr != target || mac != "aa:bb:cc:00:00:10" {
+		t.Fatalf("got %v %q %v", addr, mac, ok)
+	}
+
+	bad := map[string][]byte{
+		"short":          body[:19],
+		"no options":     body,
+		"zero length":    slices.Concat(body, []byte{14, 0, 0, 0, 0, 0, 0, 0}, tll),
+		"past the end":   slices.Concat(body, []byte{2, 2, 0xaa, 0xbb, 0xcc, 0, 0, 0x10}),
+		"source address": slices.Concat(body, []byte{1, 1, 0xaa, 0xbb, 0xcc, 0, 0, 0x10}),
+	}
+	for name, advert := range bad {
+		if _, _, ok := parseNeighborAdvertisement(advert); ok {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
+
+func TestSolicitedNode(t *testing.T) {
+	got := solicitedNode(netip.MustParseAddr("2001:db8::aabb:ccdd:ee01:2345"))
+	if want := netip.MustParseAddr("ff02::1:ff01:2345"); got != want {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+func TestSolicitTargets(t *testing.T) {
+	responders := []netip.Addr{
+		netip.MustParseAddr("fe80::211:22ff:fe33:4455%eth0"),
+		netip.MustParseAddr("2001:db8::1"),
+	}
+	_, subnet, _ := net.ParseCIDR("2001:db8::/64")
+	got := solicitTargets(responders, subnet)
+	want := append(slices.Clone(responders), netip.MustParseAddr("2001:db8::211:22ff:fe33:4455"))
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+
+	// Longer prefixes are not SLAAC, only the responders are solicited.
+	_, small, _ := net.ParseCIDR("2001:db8::/120")
+	if got := solicitTargets(responders, small); !slices.Equal(got, responders) {
+		t.Fatalf("got %v for a /120", got)
+	}
+}
@@ -1,10 +1,12 @@
 package scan
 
 import (
-	"fmt"
+	"context"
 	"net"
+	"net/netip"
 	"runtime"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -19,51 +21,77 @@ const unixGlobalDialConcurrencyCap = 12 * 1024
 var dialLimiter = newDialLimiter()
 
 type portResult struct {
-	port   int
-	banner string
+	port    int
+	proto   string
+	banner  string
+	product string
+	version string
+	tls     *scanner.TLSInfo
+	ssh     *scanner.SSHInfo
+	http    *scanner.HTTPInfo
 }
 
-func scanHost(ifaceName, ip string, ports []int) string {
-	return scanHostMac(ifaceName, ip, "", ports)
+func scanHost(ctx context.Context, ifaceName, ip string, ports probePorts) *scanner.HostResult {
+	return scanHostMac(ctx, ifaceName, ip, "", ports)
 }
 
-func scanHostMac(ifaceName, ip, knownMAC string, ports []int) string {
-	if len(ports) == 0 {
+func scanHostMac(ctx context.Context, ifaceName, ip, knownMAC string, ports probePorts) *scanner.HostResult {
+	if ports.empty() {
 		// Host-only mode: ARP to check liveness (requires CAP_NET_RAW).
 		// For neighbors knownMAC is already set so no ARP request is made.
-		hardware := resolveHardware(ifaceName, net.ParseIP(ip), knownMAC)
-		if knownMAC == "" && hardware == "" {
-			return ""
+		mac := resolveHostMac(ifaceName, net.ParseIP(ip), knownMAC)
+		if knownMAC == "" && mac == "" {
+			return nil
 		}
-		return scanner.FormatHost(scanner.HostResult{IP: ip, Hardware: hardware})
+		return &scanner.HostResult{IP: ip, MAC: mac, Hardware: VendorFromMac(mac)}
 	}
 
-	results := scanOpenPorts(ip, ports)
+	// UDP probes wait for replies, so run them alongside the TCP dials.
+	udpDone := make(chan []portResult, 1)
+	go func() { udpDone <- scanUDPPorts(ctx, ip, ports.udp) }()
+	results := scanOpenPorts(ctx, ip, ports.tcp)
+	results = append(results, <-udpDone...)
 	if len(results) == 0 {
-		return ""
+		return nil
 	}
 
+	// TCP ports first, then UDP.
 	sort.Slice(results, func(i, j int) bool {
+		if results[i].proto != results[j].proto {
+			return results[i].proto < results[j].proto
+		}
 		return results[i].port < results[j].port
 	})
 
-	host := scanner.HostResult{
+	mac := resolveHostMac(ifaceName, net.ParseIP(ip), knownMAC)
+	host := &scanner.HostResult{
 		IP:       ip,
-		Hardware: resolveHardware(ifaceName, net.ParseIP(ip), knownMAC),
+		MAC:      mac,
+		Hardware: VendorFromMac(mac),
 		Ports:    make([]scanner.PortInfo, 0, len(results)),
 	}
 
 	for _, result := range results {
-		host.Ports = append(host.Ports, scanner.PortInfo{Port: result.port, Banner: result.banner})
+		host.Ports = append(host.Ports, scanner.PortInfo{
+			Port:    result.port,
+			Proto:   result.proto,
+			Banner:  result.banner,
+			Product: result.product,
+			Version: result.version,
+			TLS:     result.tls,
+			SSH:     result.ssh,
+			HTTP:    result.http,
+		})
 	}
 
-	return scanner.FormatHost(host)
+	return host
 }
 
-func scanOpenPorts(ip string, ports []int) []portResult {
+func scanOpenPorts(ctx context.Context, ip string, ports []int) []portResult {
 	var wg sync.WaitGroup
 	var resultMu sync.Mutex
 	results := make([]portResult, 0, len(ports))
+	dialer := net.Dialer{Timeout: portDialTimeout}
 
 	for _, port := range ports {
 		wg.Add(1)
@@ -71,20 +99,30 @@ func scanOpenPorts(ip string, ports []int) []portResult {
 			defer wg.Done()
 			if dialLimiter != nil {
 				// Acquire one slot in the global dial work pool
-				dialLimiter <- struct{}{}
+				select {
+				case dialLimiter <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
 				defer func() {
 					<-dialLimiter // release
 				}()
 			}
 
-			conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip, port), portDialTimeout)
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
 			if err != nil {
 				return
 			}
 			defer conn.Close()
+			// Unblock banner reads as soon as the scan is cancelled.
+			stop := context.AfterFunc(ctx, func() {
+				_ = conn.SetDeadline(time.Now())
+			})
+			defer stop()
 
+			result := grabService(ctx, &dialer, conn, port)
 			resultMu.Lock()
-			results = append(results, portResult{port: port, banner: getServiceBanner(conn)})
+			results = append(results, result)
 			resultMu.Unlock()
 		}(port)
 	}
@@ -108,17 +146,18 @@ func newDialLimiter() chan struct{} {
 	}
 }
 
-func resolveHardware(_ string, targetIP net.IP, knownMAC string) string {
+// resolveHostMac returns the known MAC or looks it up in the OS ARP cache.
+// Routed targets have no MAC of their own, so they are not looked up.
+func resolveHostMac(ifaceName string, targetIP net.IP, knownMAC string) string {
 	if knownMAC != "" {
-		return VendorFromMac(knownMAC)
+		return knownMAC
 	}
-	if targetIP == nil {
+	addr, ok := netip.AddrFromSlice(targetIP)
+	if !ok {
 		return ""
 	}
-
-	mac := lookupMacFromCache(targetIP.String())
-	if mac == "" {
+	if iface, err := net.InterfaceByName(ifaceName); err == nil && !linkNets(iface).contains(addr) {
 		return ""
 	}
-	return VendorFromMac(mac)
+	return lookupMacFromCache(targetIP.String())
 }
@@ -0,0 +1,390 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeARP  = 0x0806
+	etherTypeVLAN = 0x8100
+
+	ipProtoTCP = 6
+	ipProtoUDP = 17
+
+	tcpSYN = 0x02
+	tcpRST = 0x04
+	tcpACK = 0x10
+)
+
+// hostObserver builds hosts from traffic seen on the wire, without sending
+// anything: ARP senders, DHCP clients and the senders of mDNS, SSDP, LLMNR
+// and NetBIOS broadcasts, with the MACs and names those packets carry, and
+// the open ports TCP handshakes reveal.
+type hostObserver struct {
+	accept func(netip.Addr) bool
+
+	hosts    map[netip.Addr]*scanner.HostResult
+	order    []netip.Addr
+	macNames map[string]string // DHCP host names by MAC, for clients with no address yet.
+	changed  []netip.Addr
+}
+
+func newHostObserver(accept func(netip.Addr) bool) *hostObserver {
+	return &hostObserver{
+		accept:   accept,
+		hosts:    make(map[netip.Addr]*scanner.HostResult),
+		macNames: make(map[string]string),
+	}
+}
+
+// frame reads an Ethernet frame and returns the hosts it added or taught
+// something new about.
+func (o *hostObserver) frame(data []byte) []scanner.HostResult {
+	o.changed = o.changed[:0]
+	if len(data) < 14 {
+		return nil
+	}
+	srcMAC := ""
+	if !bytes.Equal(data[6:12], make([]byte, 6)) { // Zero in captures without link headers.
+		srcMAC = net.HardwareAddr(data[6:12]).String()
+	}
+	etherType := binary.BigEndian.Uint16(data[12:14])
+	payload := data[14:]
+	if etherType == etherTypeVLAN && len(payload) >= 4 {
+		etherType = binary.BigEndian.Uint16(payload[2:4])
+		payload = payload[4:]
+	}
+
+	switch etherType {
+	case etherTypeARP:
+		o.arp(payload)
+	case etherTypeIPv4:
+		o.ipv4(payload, srcMAC)
+	}
+	return o.snapshots()
+}
+
+// datagram reads a UDP payload received on a socket, where the sender's
+// MAC is unknown.
+func (o *hostObserver) datagram(src netip.Addr, srcPort, dstPort int, payload []byte) []scanner.HostResult {
+	o.changed = o.changed[:0]
+	o.udp(src, "", srcPort, dstPort, payload)
+	return o.snapshots()
+}
+
+// results returns every host seen, in the order they first appeared.
+func (o *hostObserver) results() []scanner.HostResult {
+	o.changed = append(o.changed[:0], o.order...)
+	return o.snapshots()
+}
+
+func (o *hostObserver) snapshots() []scanner.HostResult {
+	if len(o.changed) == 0 {
+		return nil
+	}
+	out := make([]scanner.HostResult, 0, len(o.changed))
+	for _, ip := range o.changed {
+		host := *o.hosts[ip]
+		host.Names = slices.Clone(host.Names)
+		host.Ports = slices.Clone(host.Ports)
+		out = append(out, host)
+	}
+	return out
+}
+
+func (o *hostObserver) markChanged(ip netip.Addr) {
+	if !slices.Contains(o.changed, ip) {
+		o.changed = append(o.changed, ip)
+	}
+}
+
+// see records that ip is in use, by mac when known. Broadcast, multicast
+// and unspecified addresses and addresses outside the scan are ignored.
+func (o *hostObserver) see(ip netip.Addr, mac string) *scanner.HostResult {
+	if !ip.Is4() || ip.IsUnspecified() || ip.IsMulticast() || ip == netip.AddrFrom4([4]byte{255, 255, 255, 255}) || !o.accept(ip) {
+		return nil
+	}
+	host, ok := o.hosts[ip]
+	if !ok {
+		host = &scanner.HostResult{IP: ip.String()}
+		o.hosts[ip] = host
+		o.order = append(o.order, ip)
+		o.markChanged(ip)
+	}
+	if mac != "" && host.MAC == "" {
+		host.MAC = mac
+		host.Hardware = VendorFromMac(mac)
+		o.markChanged(ip)
+		if name := o.macNames[mac]; name != "" {
+			o.name(ip, name, scanner.NameDHCP)
+		}
+	}
+	return host
+}
+
+// name adds a name for ip. The first name a host gets is its display name.
+// Names are whatever a device on the link sent, so control characters are
+// stripped before they reach a terminal.
+func (o *hostObserver) name(ip netip.Addr, name, source string) {
+	host := o.see(ip, "")
+	name = strings.TrimSuffix(cleanName(name), ".")
+	if host == nil || name == "" {
+		return
+	}
+	for _, known := range host.Names {
+		if known.Source == source && strings.EqualFold(known.Name, name) {
+			return
+		}
+	}
+	host.Names = append(host.Names, scanner.HostName{Name: name, Source: source})
+	if host.Name == "" {
+		host.Name = name
+	}
+	o.markChanged(ip)
+}
+
+// arp records the sender of any request or reply. ARP probes, sent before
+// a host has an address, have an unspecified sender and are skipped.
+func (o *hostObserver) arp(payload []byte) {
+	if len(payload) < 28 || binary.BigEndian.Uint16(payload[2:4]) != etherTypeIPv4 || payload[4] != 6 || payload[5] != 4 {
+		return
+	}
+	senderMAC := net.HardwareAddr(payload[8:14]).String()
+	senderIP := netip.AddrFrom4([4]byte(payload[14:18]))
+	o.see(senderIP, senderMAC)
+}
+
+func (o *hostObserver) ipv4(packet []byte, srcMAC string) {
+	if len(packet) < 20 || packet[0]>>4 != 4 {
+		return
+	}
+	headerLen := int(packet[0]&0x0f) * 4
+	totalLen := int(binary.BigEndian.Uint16(packet[2:4]))
+	if headerLen < 20 || totalLen < headerLen || len(packet) < headerLen {
+		return
+	}
+	if totalLen < len(packet) {
+		packet = packet[:totalLen] // Drop Ethernet padding.
+	}
+	// Later fragments have no transport header.
+	if binary.BigEndian.Uint16(packet[6:8])&0x1fff != 0 {
+		return
+	}
+	src := netip.AddrFrom4([4]byte(packet[12:16]))
+	segment := packet[headerLen:]
+
+	switch packet[9] {
+	case ipProtoUDP:
+		if len(segment) < 8 {
+			return
+		}
+		srcPort := int(binary.BigEndian.Uint16(segment[0:2]))
+		dstPort := int(binary.BigEndian.Uint16(segment[2:4]))
+		o.udp(src, srcMAC, srcPort, dstPort, segment[8:])
+	case ipProtoTCP:
+		o.tcp(src, srcMAC, segment)
+	}
+}
+
+// tcp records the hosts behind connection attempts, and the port of every
+// SYN/ACK as open. The frame's MAC is not used: for a routed host it is the
+// router's.
+func (o *hostObserver) tcp(src netip.Addr, _ string, segment []byte) {
+	if len(segment) < 20 {
+		return
+	}
+	flags := segment[13]
+	if flags&tcpSYN == 0 || flags&tcpRST != 0 {
+		return
+	}
+	host := o.see(src, "")
+	if host == nil || flags&tcpACK == 0 {
+		return
+	}
+	port := int(binary.BigEndian.Uint16(segment[0:2]))
+	i, found := slices.BinarySearchFunc(host.Ports, port, func(p scanner.PortInfo, port int) int { return p.Port - port })
+	if found {
+		return
+	}
+	host.Ports = slices.Insert(host.Ports, i, scanner.PortInfo{Port: port})
+	o.markChanged(src)
+}
+
+func (o *hostObserver) udp(src netip.Addr, srcMAC string, srcPort, dstPort int, payload []byte) {
+	hasPort := func(port int) bool { return srcPort == port || dstPort == port }
+	switch {
+	case hasPort(67) || hasPort(68):
+		o.see(src, srcMAC)
+		o.dhcp(payload)
+	case hasPort(5353):
+		o.see(src, srcMAC)
+		o.mdns(payload)
+	case hasPort(1900):
+		o.ssdp(o.see(src, srcMAC), payload)
+	case hasPort(5355):
+		o.see(src, srcMAC)
+		o.llmnr(src, payload)
+	case hasPort(137):
+		o.see(src, srcMAC)
+		o.netbios(src, payload)
+	}
+}
+
+// dhcp learns addresses and host names from DHCP traffic. Clients ask with
+// their MAC and host name before they have an address, so the name is kept
+// until an ACK, a renewal or ARP ties the MAC to an address.
+func (o *hostObserver) dhcp(payload []byte) {
+	if len(payload) < 240 || !bytes.Equal(payload[236:240], []byte{99, 130, 83, 99}) || payload[2] != 6 {
+		return
+	}
+	op := payload[0]
+	mac := net.HardwareAddr(payload[28:34]).String()
+	clientIP := netip.AddrFrom4([4]byte(payload[12:16]))
+	yourIP := netip.AddrFrom4([4]byte(payload[16:20]))
+
+	var msgType byte
+	var hostName string
+	var requested netip.Addr
+	for opts := payload[240:]; len(opts) > 0; {
+		code := opts[0]
+		if code == 0 {
+			opts = opts[1:]
+			continue
+		}
+		if code == 255 || len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			break
+		}
+		value := opts[2 : 2+int(opts[1])]
+		opts = opts[2+len(value):]
+		switch code {
+		case 12:
+			hostName = string(value)
+		case 50:
+			if len(value) == 4 {
+				requested = netip.AddrFrom4([4]byte(value))
+			}
+		case 53:
+			if len(value) == 1 {
+				msgType = value[0]
+			}
+		}
+	}
+
+	if hostName = cleanBanner([]byte(hostName)); hostName != "" {
+		o.macNames[mac] = hostName
+	}
+	ip := clientIP
+	switch {
+	case op == 2 && msgType == 5: // ACK
+		ip = yourIP
+	case op == 1 && ip.IsUnspecified() && requested.IsValid():
+		ip = requested
+	}
+	if op == 2 && msgType != 5 {
+		return
+	}
+	if host := o.see(ip, mac); host != nil && o.macNames[mac] != "" {
+		o.name(ip, o.macNames[mac], scanner.NameDHCP)
+	}
+}
+
+// mdns takes names from the A records of responses and announcements.
+func (o *hostObserver) mdns(payload []byte) {
+	for _, rr := range mdnsRecords(payload) {
+		if a, ok := rr.Body.(*dnsmessage.AResource); ok {
+			o.name(netip.AddrFrom4(a.A), rr.Header.Name.String(), scanner.NameMDNS)
+		}
+	}
+}
+
+// ssdp records the SERVER and LOCATION headers a device announces. Only
+// NOTIFY and search responses describe the sender, M-SEARCH comes from
+// control points looking for devices.
+func (o *hostObserver) ssdp(host *scanner.HostResult, payload []byte) {
+	if host == nil || bytes.HasPrefix(payload, []byte("M-SEARCH")) {
+		return
+	}
+	var server, location string
+	for _, line := range strings.Split(string(payload), "\r\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "server":
+			server = cleanBanner([]byte(value))
+		case "location":
+			location = strings.TrimSpace(value)
+		}
+	}
+	if server == "" && location == "" || host.UPnP != nil {
+		return
+	}
+	ip, _ := netip.ParseAddr(host.IP)
+	host.UPnP = &scanner.UPnPDevice{Server: server, Location: ssdpLocation(location, ip)}
+	o.markChanged(ip)
+}
+
+// llmnr takes the name from responses where a host answers for itself.
+func (o *hostObserver) llmnr(src netip.Addr, payload []byte) {
+	var p dnsmessage.Parser
+	header, err := p.Start(payload)
+	if err != nil || !header.Response {
+		return
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return
+	}
+	for _, rr := range answers {
+		if a, ok := rr.Body.(*dnsmessage.AResource); ok && netip.AddrFrom4(a.A) == src {
+			o.name(src, rr.Header.Name.String(), scanner.NameLLMNR)
+		}
+	}
+}
+
+// netbios takes the name from name registrations and refreshes, which
+// Windows and Samba broadcast for their own names.
+func (o *hostObserver) netbios(src netip.Addr, payload []byte) {
+	if len(payload) < 12+34 || payload[2]&0x80 != 0 {
+		return
+	}
+	switch opcode := payload[2] >> 3 & 0x0f; opcode {
+	case 5, 8, 9: // Registration and the two refresh opcodes.
+	default:
+		return
+	}
+	name, suffix, ok := decodeNetBIOSName(payload[12:])
+	if ok && (suffix == 0x00 || suffix == 0x20) {
+		o.name(src, name, scanner.NameNetBIOS)
+	}
+}
+
+// decodeNetBIOSName undoes the first-level encoding of a 16 byte name.
+func decodeNetBIOSName(b []byte) (string, byte, bool) {
+	if len(b) < 33 || b[0] != 32 {
+		return "", 0, false
+	}
+	var raw [16]byte
+	for i := range raw {
+		hi, lo := b[1+2*i]-'A', b[2+2*i]-'A'
+		if hi > 15 || lo > 15 {
+			return "", 0, false
+		}
+		raw[i] = hi<<4 | lo
+	}
+	name := strings.TrimRight(string(raw[:15]), " \x00")
+	return name, raw[15], name != "" && name != "*"
+}
@@ -0,0 +1,179 @@
+package scan
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+func ethernetFrame(src net.HardwareAddr, etherType uint16, payload []byte) []byte {
+	frame := append([]byte{}, broadcastMAC...)
+	frame = append(frame, src...)
+	frame = binary.BigEndian.AppendUint16(frame, etherType)
+	return append(frame, payload...)
+}
+
+func arpFrame(mac net.HardwareAddr, ip string) []byte {
+	payload := []byte{0, 1, 8, 0, 6, 4, 0, 1}
+	payload = append(payload, mac...)
+	payload = append(payload, netip.MustParseAddr(ip).AsSlice()...)
+	payload = append(payload, make([]byte, 6)...)
+	payload = append(payload, 192, 168, 1, 1)
+	return ethernetFrame(mac, etherTypeARP, payload)
+}
+
+func ipv4Frame(mac net.HardwareAddr, src, dst string, proto byte, segment []byte) []byte {
+	header := make([]byte, 20)
+	header[0] = 0x45
+	binary.BigEndian.PutUint16(header[2:], uint16(20+len(segment)))
+	header[8] = 64
+	header[9] = proto
+	copy(header[12:], netip.MustParseAddr(src).AsSlice())
+	copy(header[16:], netip.MustParseAddr(dst).AsSlice())
+	return ethernetFrame(mac, etherTypeIPv4, append(header, segment...))
+}
+
+func udpFrame(mac net.HardwareAddr, src, dst string, srcPort, dstPort int, payload []byte) []byte {
+	segment := binary.BigEndian.AppendUint16(nil, uint16(srcPort))
+	segment = binary.BigEndian.AppendUint16(segment, uint16(dstPort))
+	segment = binary.BigEndian.AppendUint16(segment, uint16(8+len(payload)))
+	segment = append(segment, 0, 0)
+	return ipv4Frame(mac, src, dst, ipProtoUDP, append(segment, payload...))
+}
+
+func dhcpMessage(op, msgType byte, mac net.HardwareAddr, yourIP, hostName string) []byte {
+	msg := make([]byte, 240)
+	msg[0], msg[1], msg[2] = op, 1, 6
+	if yourIP != "" {
+		copy(msg[16:20], netip.MustParseAddr(yourIP).AsSlice())
+	}
+	copy(msg[28:], mac)
+	copy(msg[236:], []byte{99, 130, 83, 99})
+	msg = append(msg, 53, 1, msgType)
+	if hostName != "" {
+		msg = append(msg, 12, byte(len(hostName)))
+		msg = append(msg, hostName...)
+	}
+	return append(msg, 255)
+}
+
+func netbiosRegistration(name string) []byte {
+	msg := []byte{0x12, 0x34, 0x29, 0x10, 0, 1, 0, 0, 0, 0, 0, 1} // Registration, broadcast.
+	msg = append(msg, 32)
+	padded := []byte(name + "                ")[:15]
+	for _, c := range append(padded, 0x00) {
+		msg = append(msg, 'A'+c>>4, 'A'+c&0x0f)
+	}
+	return append(msg, 0, 0x00, 0x20, 0x00, 0x01)
+}
+
+func mdnsAnnouncement(host string, ip [4]byte) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	_ = b.StartAnswers()
+	_ = b.AResource(
+		dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(host), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 120},
+		dnsmessage.AResource{A: ip},
+	)
+	msg, _ := b.Finish()
+	return msg
+}
+
+func TestHostObserver(t *testing.T) {
+	subnet := netip.MustParsePrefix("192.168.1.0/24")
+	observer := newHostObserver(subnet.Contains)
+
+	pi := net.HardwareAddr{0xd8, 0x3a, 0xdd, 0x11, 0x22, 0x33}
+	phone := net.HardwareAddr{0x02, 0x11, 0x22, 0x33, 0x44, 0x55}
+	tv := net.HardwareAddr{0x02, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+	windows := net.HardwareAddr{0x02, 0xaa, 0xbb, 0xcc, 0xdd, 0x02}
+
+	got := observer.frame(arpFrame(pi, "192.168.1.10"))
+	if len(got) != 1 || got[0].IP != "192.168.1.10" || got[0].MAC != pi.String() || got[0].Hardware == "" {
+		t.Fatalf("arp: %+v", got)
+	}
+	if got := observer.frame(arpFrame(pi, "192.168.1.10")); got != nil {
+		t.Fatalf("repeated arp reported again: %+v", got)
+	}
+
+	// The client names itself before it has an address, the server's ACK
+	// ties the name to one.
+	discover := dhcpMessage(1, 1, phone, "", "Janes-iPhone")
+	if got := observer.frame(udpFrame(phone, "0.0.0.0", "255.255.255.255", 68, 67, discover)); got != nil {
+		t.Fatalf("discover without address: %+v", got)
+	}
+	ack := dhcpMessage(2, 5, phone, "192.168.1.40", "")
+	got = observer.frame(udpFrame(broadcastMAC, "192.168.1.1", "255.255.255.255", 67, 68, ack))
+	var client *scanner.HostResult
+	for i := range got {
+		if got[i].IP == "192.168.1.40" {
+			client = &got[i]
+		}
+	}
+	if client == nil || client.MAC != phone.String() || client.Name != "Janes-iPhone" || client.Names[0].Source != scanner.NameDHCP {
+		t.Fatalf("dhcp ack: %+v", got)
+	}
+
+	got = observer.frame(udpFrame(tv, "192.168.1.41", "224.0.0.251", 5353, 5353, mdnsAnnouncement("living-room.local.", [4]byte{192, 168, 1, 41})))
+	if len(got) != 1 || got[0].Name != "living-room.local" || got[0].MAC != tv.String() {
+		t.Fatalf("mdns: %+v", got)
+	}
+
+	notify := []byte("NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\n" +
+		"LOCATION: http://192.168.1.41:9197/dmr\r\nNT: upnp:rootdevice\r\nNTS: ssdp:alive\r\n" +
+		"SERVER: SHP, UPnP/1.0, Samsung UPnP SDK/1.0\r\n\r\n")
+	got = observer.frame(udpFrame(tv, "192.168.1.41", "239.255.255.250", 1900, 1900, notify))
+	if len(got) != 1 || got[0].UPnP == nil || got[0].UPnP.Server != "SHP, UPnP/1.0, Samsung UPnP SDK/1.0" || got[0].UPnP.Location != "http://192.168.1.41:9197/dmr" {
+		t.Fatalf("ssdp: %+v", got)
+	}
+
+	got = observer.frame(udpFrame(windows, "192.168.1.42", "192.168.1.255", 137, 137, netbiosRegistration("DESKTOP-7QK2")))
+	if len(got) != 1 || got[0].Name != "DESKTOP-7QK2" || got[0].Names[0].Source != scanner.NameNetBIOS {
+		t.Fatalf("netbios: %+v", got)
+	}
+
+	if got := observer.frame(arpFrame(pi, "10.0.0.5")); got != nil {
+		t.Fatalf("host outside targets: %+v", got)
+	}
+
+	all := observer.results()
+	want := []string{"192.168.1.10", "192.168.1.1", "192.168.1.40", "192.168.1.41", "192.168.1.42"}
+	if len(all) != len(want) {
+		t.Fatalf("results: %+v", all)
+	}
+	for i, ip := range want {
+		if all[i].IP != ip {
+			t.Fatalf("result %d is %s, want %s", i, all[i].IP, ip)
+		}
+	}
+}
+
+func TestHostObserverCleansNames(t *testing.T) {
+	observer := newHostObserver(netip.MustParsePrefix("192.168.1.0/24").Contains)
+	tv := net.HardwareAddr{0x02, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+	windows := net.HardwareAddr{0x02, 0xaa, 0xbb, 0xcc, 0xdd, 0x02}
+
+	got := observer.frame(udpFrame(tv, "192.168.1.41", "224.0.0.251", 5353, 5353, mdnsAnnouncement("tv\x1b[2J\x07.local.", [4]byte{192, 168, 1, 41})))
+	if len(got) != 1 || got[0].Name != "tv[2J.local" || got[0].Names[0].Name != got[0].Name {
+		t.Fatalf("mdns: %+v", got)
+	}
+	got = observer.frame(udpFrame(windows, "192.168.1.42", "192.168.1.255", 137, 137, netbiosRegistration("DESK\x1bTOP")))
+	if len(got) != 1 || got[0].Name != "DESKTOP" {
+		t.Fatalf("netbios: %+v", got)
+	}
+}
+
+func TestDecodeNetBIOSName(t *testing.T) {
+	name, suffix, ok := decodeNetBIOSName(netbiosRegistration("NAS")[12:])
+	if !ok || name != "NAS" || suffix != 0x00 {
+		t.Fatalf("got %q %#x %v", name, suffix, ok)
+	}
+	if _, _, ok := decodeNetBIOSName(netbiosStatusRequest()[12:]); ok {
+		t.Fatal("wildcard name decoded as a host name")
+	}
+}
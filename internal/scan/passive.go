@@ -0,0 +1,161 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/mdlayher/packet"
+)
+
+const ethPAll = 0x0003 // ETH_P_ALL, every protocol.
+
+// Groups joined while listening, so the NIC passes their traffic up.
+var passiveGroups = []*net.UDPAddr{
+	mdnsGroup,
+	ssdpGroup,
+	{IP: net.IPv4(224, 0, 0, 252), Port: 5355}, // LLMNR
+}
+
+// Broadcast ports listened on when raw capture is not available.
+var passivePorts = []int{67, 137}
+
+// passivePacket is either a raw Ethernet frame or a datagram from a socket.
+type passivePacket struct {
+	frame   []byte
+	src     netip.Addr
+	srcPort int
+	dstPort int
+	payload []byte
+}
+
+// passiveListen builds the host list from traffic on the interface for
+// s.Passive without sending a single probe. It captures every frame with
+// a raw socket (Linux, CAP_NET_RAW), otherwise it falls back to listening
+// on the mDNS, SSDP and LLMNR groups and, when it may bind them, the DHCP
+// and NetBIOS ports. Hosts are sent as soon as they are seen and again
+// whenever a later packet adds a MAC or name.
+func (s *NetScanner) passiveListen(ctx context.Context, ifaceName string, accept func(netip.Addr) bool, progressChan chan<- scanner.ProgressUpdate) {
+	ctx, cancel := context.WithTimeout(ctx, s.Passive)
+	defer cancel()
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return
+	}
+	packets := make(chan passivePacket, 64)
+
+	// Joining the groups is needed for raw capture to see them as well.
+	raw, rawErr := packet.Listen(iface, packet.Raw, ethPAll, nil)
+	for _, group := range passiveGroups {
+		conn, err := net.ListenMulticastUDP("udp4", iface, group)
+		if err != nil {
+			continue
+		}
+		defer conn.Close()
+		if rawErr != nil {
+			go readPassiveSocket(ctx, conn, group.Port, packets)
+		}
+	}
+	if rawErr == nil {
+		defer raw.Close()
+		go readPassiveFrames(ctx, raw, iface.HardwareAddr, packets)
+	} else {
+		for _, port := range passivePorts {
+			conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+			if err != nil {
+				continue
+			}
+			defer conn.Close()
+			go readPassiveSocket(ctx, conn, port, packets)
+		}
+	}
+
+	start := time.Now()
+	total := int(s.Passive / time.Second)
+	elapsed := func() int { return int(time.Since(start) / time.Second) }
+	sendProgress(ctx, progressChan, scanner.NeighborProgress{Total: total})
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	observer := newHostObserver(accept)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			sendProgress(ctx, progressChan, scanner.NeighborProgress{Seen: elapsed(), Total: total})
+		case p := <-packets:
+			var changed []scanner.HostResult
+			if p.frame != nil {
+				changed = observer.frame(p.frame)
+			} else {
+				changed = observer.datagram(p.src, p.srcPort, p.dstPort, p.payload)
+			}
+			for _, host := range changed {
+				if s.Exclude.Excludes(host.IP, host.MAC) {
+					continue
+				}
+				s.Classifier.Classify(&host)
+				sendProgress(ctx, progressChan, scanner.NeighborProgress{Host: &host, Seen: elapsed(), Total: total})
+			}
+		}
+	}
+}
+
+// readPassiveFrames reads frames until ctx ends, skipping the ones this
+// machine sent.
+func readPassiveFrames(ctx context.Context, conn *packet.Conn, own net.HardwareAddr, packets chan<- passivePacket) {
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if n < 14 || slices.Equal(buf[6:12], own) {
+			continue
+		}
+		select {
+		case packets <- passivePacket{frame: slices.Clone(buf[:n])}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func readPassiveSocket(ctx context.Context, conn net.PacketConn, port int, packets chan<- passivePacket) {
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	buf := make([]byte, 9000)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		src, ok := peerAddr(peer)
+		if !ok {
+			continue
+		}
+		srcPort := 0
+		if udp, ok := peer.(*net.UDPAddr); ok {
+			srcPort = udp.Port
+		}
+		select {
+		case packets <- passivePacket{src: src, srcPort: srcPort, dstPort: port, payload: slices.Clone(buf[:n])}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
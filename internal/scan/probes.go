@@ -0,0 +1,520 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+const probeTimeout = 500 * time.Millisecond
+
+// serviceID is what a protocol probe learned about a service.
+type serviceID struct {
+	product string
+	version string
+	detail  string // Extra facts such as the auth mode, shown after the version.
+	raw     string // Banner the service sent itself, shown instead when set.
+	ssh     *scanner.SSHInfo
+}
+
+func (id serviceID) banner() string {
+	if id.raw != "" {
+		return cleanBanner([]byte(id.raw))
+	}
+	parts := []string{id.product}
+	if id.version != "" {
+		parts[0] += " " + id.version
+	}
+	if id.detail != "" {
+		parts = append(parts, id.detail)
+	}
+	return cleanBanner([]byte(strings.Join(parts, ", ")))
+}
+
+func (id serviceID) result(port int) portResult {
+	return portResult{
+		port:    port,
+		banner:  id.banner(),
+		product: id.product,
+		version: id.version,
+		ssh:     id.ssh,
+	}
+}
+
+// tcpProbe speaks just enough of a protocol to identify the service.
+// It reports false when the peer does not answer like that protocol.
+type tcpProbe func(conn net.Conn) (serviceID, bool)
+
+// probeKey names a service the way PortInfo does, by port and Proto, which
+// is empty for TCP.
+type probeKey struct {
+	port  int
+	proto string
+}
+
+// probe is the protocol hello of one service. TCP services are probed over
+// the open connection, UDP services need a request before anything comes
+// back.
+type probe struct {
+	tcp tcpProbe
+	udp udpProbe
+}
+
+// probes is the registry of protocol hellos for well-known services.
+var probes = map[probeKey]probe{
+	{22, ""}:   {tcp: probeSSH},
+	{445, ""}:  {tcp: probeSMB2},
+	{1883, ""}: {tcp: probeMQTT},
+	{3306, ""}: {tcp: probeMySQL},
+	{3389, ""}: {tcp: probeRDP},
+	{5432, ""}: {tcp: probePostgres},
+	{6379, ""}: {tcp: probeRedis},
+
+	{53, scanner.ProtoUDP}:   {udp: udpProbe{requests: dnsVersionRequest, parse: parseDNSVersion}},
+	{69, scanner.ProtoUDP}:   {udp: udpProbe{requests: tftpRequest, parse: parseTFTP}},
+	{123, scanner.ProtoUDP}:  {udp: udpProbe{requests: ntpRequests, parse: parseNTP}},
+	{161, scanner.ProtoUDP}:  {udp: udpProbe{requests: snmpSysDescrRequest, parse: parseSNMPSysDescr}},
+	{1900, scanner.ProtoUDP}: {udp: udpProbe{requests: ssdpSearchRequest, parse: parseSSDP}},
+	{5353, scanner.ProtoUDP}: {udp: udpProbe{requests: mdnsServicesRequest, parse: parseMDNSServices}},
+}
+
+// tcpProbeFor returns the protocol hello of a well-known TCP port.
+func tcpProbeFor(port int) (tcpProbe, bool) {
+	p := probes[probeKey{port: port}]
+	return p.tcp, p.tcp != nil
+}
+
+func runProbe(conn net.Conn, probe tcpProbe) (serviceID, bool) {
+	_ = conn.SetDeadline(time.Now().Add(probeTimeout))
+	return probe(conn)
+}
+
+// probeMySQL parses the greeting the server sends on connect.
+func probeMySQL(conn net.Conn) (serviceID, bool) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return serviceID{}, false
+	}
+	size := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if size == 0 || size > 1<<16 {
+		return serviceID{}, false
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return serviceID{}, false
+	}
+
+	switch payload[0] {
+	case 0x0a:
+		end := bytes.IndexByte(payload[1:], 0)
+		if end < 0 {
+			return serviceID{}, false
+		}
+		return mysqlVersion(string(payload[1 : 1+end])), true
+	case 0xff:
+		// Error packet, e.g. the host is not allowed to connect.
+		msg := ""
+		if len(payload) > 3 {
+			msg = string(payload[3:])
+		}
+		return serviceID{product: "MySQL", detail: msg}, true
+	}
+	return serviceID{}, false
+}
+
+// mysqlVersion splits "5.5.5-10.6.12-MariaDB-0ubuntu0.22.04.1" style versions.
+func mysqlVersion(raw string) serviceID {
+	if strings.Contains(raw, "MariaDB") {
+		version := strings.TrimPrefix(raw, "5.5.5-")
+		if i := strings.Index(version, "-MariaDB"); i >= 0 {
+			version = version[:i]
+		}
+		return serviceID{product: "MariaDB", version: version}
+	}
+	version := raw
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		version = version[:i]
+	}
+	return serviceID{product: "MySQL", version: version}
+}
+
+// probePostgres asks for TLS, then sends a startup message and reads how the
+// server wants to authenticate. Servers do not reveal their version pre-auth.
+func probePostgres(conn net.Conn) (serviceID, bool) {
+	sslRequest := []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}
+	if _, err := conn.Write(sslRequest); err != nil {
+		return serviceID{}, false
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return serviceID{}, false
+	}
+
+	id := serviceID{product: "PostgreSQL"}
+	var stream io.ReadWriter = conn
+	switch reply[0] {
+	case 'S':
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+		if err := tlsConn.Handshake(); err != nil {
+			return serviceID{}, false // Any banner starting with S lands here.
+		}
+		stream = tlsConn
+		id.detail = "ssl"
+	case 'N':
+	default:
+		return serviceID{}, false
+	}
+
+	if _, err := stream.Write(postgresStartup("nibble", "postgres")); err != nil {
+		return id, true
+	}
+	if auth := postgresAuth(stream); auth != "" {
+		id.detail = strings.TrimPrefix(id.detail+", "+auth, ", ")
+	}
+	return id, true
+}
+
+func postgresStartup(user, database string) []byte {
+	var body bytes.Buffer
+	_ = binary.Write(&body, binary.BigEndian, uint32(196608)) // protocol 3.0
+	for _, kv := range []string{"user", user, "database", database} {
+		body.WriteString(kv)
+		body.WriteByte(0)
+	}
+	body.WriteByte(0)
+
+	msg := make([]byte, 4, 4+body.Len())
+	binary.BigEndian.PutUint32(msg, uint32(4+body.Len()))
+	return append(msg, body.Bytes()...)
+}
+
+// postgresAuth describes the first reply to a startup message.
+func postgresAuth(r io.Reader) string {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return ""
+	}
+	size := int(binary.BigEndian.Uint32(header[1:])) - 4
+	if size < 0 || size > 1<<14 {
+		return ""
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return ""
+	}
+
+	switch header[0] {
+	case 'R':
+		if len(body) < 4 {
+			return ""
+		}
+		switch binary.BigEndian.Uint32(body) {
+		case 0:
+			return "trust auth"
+		case 3:
+			return "password auth"
+		case 5:
+			return "md5 auth"
+		case 10:
+			return "scram auth"
+		}
+		return "auth required"
+	case 'E':
+		// Error fields are a type byte followed by a C string, M is the message.
+		for _, field := range bytes.Split(body, []byte{0}) {
+			if len(field) > 1 && field[0] == 'M' {
+				return string(field[1:])
+			}
+		}
+	}
+	return ""
+}
+
+// probeMQTT sends a CONNECT without credentials. Brokers that accept it are
+// asked for $SYS/broker/version, which Mosquitto and others publish.
+func probeMQTT(conn net.Conn) (serviceID, bool) {
+	clientID := "nibble"
+	variable := []byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0x02, 0, 30}
+	payload := append([]byte{0, byte(len(clientID))}, clientID...)
+	connect := append([]byte{0x10, byte(len(variable) + len(payload))}, variable...)
+	connect = append(connect, payload...)
+	if _, err := conn.Write(connect); err != nil {
+		return serviceID{}, false
+	}
+
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil || ack[0] != 0x20 || ack[1] != 0x02 {
+		return serviceID{}, false
+	}
+	defer conn.Write([]byte{0xe0, 0}) // DISCONNECT
+
+	id := serviceID{product: "MQTT"}
+	switch ack[3] {
+	case 0:
+		id.detail = "anonymous access"
+	case 4, 5:
+		id.detail = "auth required"
+		return id, true
+	default:
+		return id, true
+	}
+
+	if version := mqttBrokerVersion(conn); version != "" {
+		id.product, id.version = splitProductVersion(version)
+	}
+	return id, true
+}
+
+func mqttBrokerVersion(conn net.Conn) string {
+	topic := "$SYS/broker/version"
+	body := []byte{0, 1, 0, byte(len(topic))} // packet id 1
+	body = append(body, topic...)
+	body = append(body, 0) // QoS 0
+	subscribe := append([]byte{0x82, byte(len(body))}, body...)
+	if _, err := conn.Write(subscribe); err != nil {
+		return ""
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return ""
+		}
+		size, err := mqttRemainingLength(r)
+		if err != nil || size > 1<<12 {
+			return ""
+		}
+		packet := make([]byte, size)
+		if _, err := io.ReadFull(r, packet); err != nil {
+			return ""
+		}
+		if kind&0xf0 != 0x30 || len(packet) < 2 {
+			continue // SUBACK or anything else before the retained message
+		}
+		topicLen := int(binary.BigEndian.Uint16(packet))
+		if len(packet) < 2+topicLen {
+			return ""
+		}
+		return string(packet[2+topicLen:])
+	}
+}
+
+func mqttRemainingLength(r io.ByteReader) (int, error) {
+	size, shift := 0, 0
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		size |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return size, nil
+		}
+		shift += 7
+	}
+	return 0, fmt.Errorf("malformed length")
+}
+
+// splitProductVersion turns "mosquitto version 2.0.18" into its parts.
+func splitProductVersion(s string) (string, string) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return s, ""
+	}
+	version := fields[len(fields)-1]
+	product := strings.Join(fields[:len(fields)-1], " ")
+	return strings.TrimSuffix(product, " version"), version
+}
+
+// probeRDP sends an X.224 Connection Request with an RDP Negotiation Request
+// and reports the security protocol the server selects.
+func probeRDP(conn net.Conn) (serviceID, bool) {
+	request := []byte{
+		0x03, 0x00, 0x00, 0x13, // TPKT, length 19
+		0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00, // X.224 Connection Request
+		0x01, 0x00, 0x08, 0x00, 0x0b, 0x00, 0x00, 0x00, // RDP_NEG_REQ: TLS, CredSSP, RDSTLS
+	}
+	if _, err := conn.Write(request); err != nil {
+		return serviceID{}, false
+	}
+
+	reply := make([]byte, 19)
+	n, err := io.ReadAtLeast(conn, reply, 11)
+	if err != nil || reply[0] != 0x03 || reply[5] != 0xd0 {
+		return serviceID{}, false
+	}
+
+	id := serviceID{product: "RDP"}
+	if n < 19 {
+		id.detail = "standard security"
+		return id, true
+	}
+	switch reply[11] {
+	case 0x02: // RDP_NEG_RSP
+		switch binary.LittleEndian.Uint32(reply[15:]) {
+		case 0:
+			id.detail = "standard security"
+		case 1:
+			id.detail = "TLS"
+		case 2, 8:
+			id.detail = "NLA"
+		}
+	case 0x03: // RDP_NEG_FAILURE
+		id.detail = "negotiation failed"
+	}
+	return id, true
+}
+
+// smbDialects are offered in SMB2 NEGOTIATE, newest last.
+var smbDialects = []uint16{0x0202, 0x0210, 0x0300, 0x0302, 0x0311}
+
+// probeSMB2 sends an SMB2 NEGOTIATE and reports the chosen dialect and
+// whether signing is required.
+func probeSMB2(conn net.Conn) (serviceID, bool) {
+	if _, err := conn.Write(smb2Negotiate()); err != nil {
+		return serviceID{}, false
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil || header[0] != 0 {
+		return serviceID{}, false
+	}
+	size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+	if size < 64+8 || size > 1<<16 {
+		return serviceID{}, false
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return serviceID{}, false
+	}
+
+	if bytes.HasPrefix(msg, []byte("\xffSMB")) {
+		return serviceID{product: "SMB", version: "1"}, true
+	}
+	if !bytes.HasPrefix(msg, []byte("\xfeSMB")) {
+		return serviceID{}, false
+	}
+	body := msg[64:]
+	securityMode := binary.LittleEndian.Uint16(body[2:])
+	dialect := binary.LittleEndian.Uint16(body[4:])
+
+	id := serviceID{product: "SMB", version: smbDialectName(dialect)}
+	if securityMode&0x02 != 0 {
+		id.detail = "signing required"
+	}
+	return id, true
+}
+
+func smb2Negotiate() []byte {
+	var msg bytes.Buffer
+
+	// SMB2 header, command 0 (NEGOTIATE)
+	msg.WriteString("\xfeSMB")
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(64)) // structure size
+	msg.Write(make([]byte, 6))                              // credit charge, status
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(0))  // command
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(1))  // credits requested
+	msg.Write(make([]byte, 64-16))                          // flags through signature
+
+	// NEGOTIATE request
+	contextOffset := 64 + 36 + 2*len(smbDialects)
+	padding := (8 - contextOffset%8) % 8
+	contextOffset += padding
+
+	clientGUID := make([]byte, 16)
+	_, _ = rand.Read(clientGUID)
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(36))
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(len(smbDialects)))
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(1)) // signing enabled
+	msg.Write(make([]byte, 2+4))                           // reserved, capabilities
+	msg.Write(clientGUID)
+	_ = binary.Write(&msg, binary.LittleEndian, uint32(contextOffset))
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(1)) // one negotiate context
+	msg.Write(make([]byte, 2))
+	for _, dialect := range smbDialects {
+		_ = binary.Write(&msg, binary.LittleEndian, dialect)
+	}
+	msg.Write(make([]byte, padding))
+
+	// SMB 3.1.1 requires a preauth integrity context: SHA-512 with a salt.
+	salt := make([]byte, 32)
+	_, _ = rand.Read(salt)
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(1))           // context type
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(4+len(salt))) // data length
+	msg.Write(make([]byte, 4))
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(1)) // hash algorithm count
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(len(salt)))
+	_ = binary.Write(&msg, binary.LittleEndian, uint16(1)) // SHA-512
+	msg.Write(salt)
+
+	// NetBIOS session header
+	out := []byte{0, byte(msg.Len() >> 16), byte(msg.Len() >> 8), byte(msg.Len())}
+	return append(out, msg.Bytes()...)
+}
+
+func smbDialectName(dialect uint16) string {
+	switch dialect {
+	case 0x0202:
+		return "2.0.2"
+	case 0x0210:
+		return "2.1"
+	case 0x0300:
+		return "3.0"
+	case 0x0302:
+		return "3.0.2"
+	case 0x0311:
+		return "3.1.1"
+	}
+	return fmt.Sprintf("0x%04x", dialect)
+}
+
+// probeRedis asks for INFO server. Protected instances answer with NOAUTH.
+func probeRedis(conn net.Conn) (serviceID, bool) {
+	if _, err := conn.Write([]byte("INFO server\r\n")); err != nil {
+		return serviceID{}, false
+	}
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return serviceID{}, false
+	}
+	line = strings.TrimSpace(line)
+
+	switch {
+	case strings.HasPrefix(line, "-NOAUTH"), strings.HasPrefix(line, "-WRONGPASS"):
+		return serviceID{product: "Redis", detail: "auth required"}, true
+	case strings.HasPrefix(line, "-DENIED"):
+		return serviceID{product: "Redis", detail: "protected mode"}, true
+	case !strings.HasPrefix(line, "$"):
+		return serviceID{}, false
+	}
+
+	id := serviceID{product: "Redis", detail: "no auth"}
+	for {
+		field, err := r.ReadString('\n')
+		if err != nil {
+			return id, true
+		}
+		field = strings.TrimSpace(field)
+		if version, ok := strings.CutPrefix(field, "redis_version:"); ok {
+			id.version = version
+		}
+		if version, ok := strings.CutPrefix(field, "valkey_version:"); ok {
+			id.product, id.version = "Valkey", version
+		}
+		if strings.HasPrefix(field, "redis_mode:") {
+			return id, true
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// fakeService accepts one connection on loopback, hands it to serve and
+// returns the client side.
+func fakeService(t *testing.T, serve func(net.Conn)) net.Conn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func mysqlGreeting(version string) []byte {
+	payload := append([]byte{0x0a}, version...)
+	payload = append(payload, 0)
+	payload = append(payload, make([]byte, 20)...)
+	return append([]byte{byte(len(payload)), 0, 0, 0}, payload...)
+}
+
+func readPostgresMessage(r io.Reader) []byte {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil
+	}
+	body := make([]byte, binary.BigEndian.Uint32(header)-4)
+	_, _ = io.ReadFull(r, body)
+	return body
+}
+
+func postgresAuthRequest(code uint32) []byte {
+	msg := []byte{'R', 0, 0, 0, 8, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(msg[5:], code)
+	return msg
+}
+
+// servePostgres answers the SSLRequest with sslReply and wraps the rest of
+// the session in TLS when it is 'S'.
+func servePostgres(t *testing.T, sslReply byte, cfg *tls.Config) func(net.Conn) {
+	return func(conn net.Conn) {
+		if code := readPostgresMessage(conn); binary.BigEndian.Uint32(code) != 80877103 {
+			t.Errorf("expected SSLRequest, got %x", code)
+			return
+		}
+		_, _ = conn.Write([]byte{sslReply})
+		var stream io.ReadWriter = conn
+		if sslReply == 'S' {
+			tlsConn := tls.Server(conn, cfg)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			stream = tlsConn
+		}
+		startup := readPostgresMessage(stream)
+		if !bytes.Contains(startup, []byte("user\x00nibble\x00")) {
+			t.Errorf("startup message missing user: %q", startup)
+		}
+		_, _ = stream.Write(postgresAuthRequest(10))
+	}
+}
+
+func readMQTTPacket(r *bufio.Reader) (byte, []byte) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return 0, nil
+	}
+	size, err := mqttRemainingLength(r)
+	if err != nil {
+		return 0, nil
+	}
+	packet := make([]byte, size)
+	_, _ = io.ReadFull(r, packet)
+	return kind, packet
+}
+
+func serveMQTT(t *testing.T, returnCode byte) func(net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		if kind, packet := readMQTTPacket(r); kind != 0x10 || !bytes.HasPrefix(packet, []byte("\x00\x04MQTT\x04")) {
+			t.Errorf("expected CONNECT, got %x %x", kind, packet)
+			return
+		}
+		_, _ = conn.Write([]byte{0x20, 0x02, 0, returnCode})
+		if returnCode != 0 {
+			return
+		}
+		if kind, _ := readMQTTPacket(r); kind != 0x82 {
+			t.Errorf("expected SUBSCRIBE, got %x", kind)
+			return
+		}
+		_, _ = conn.Write([]byte{0x90, 0x03, 0, 1, 0}) // SUBACK
+		topic, version := "$SYS/broker/version", "mosquitto version 2.0.18"
+		publish := []byte{0x31, byte(2 + len(topic) + len(version)), 0, byte(len(topic))}
+		publish = append(publish, topic...)
+		_, _ = conn.Write(append(publish, version...))
+		readMQTTPacket(r) // DISCONNECT
+	}
+}
+
+func serveRDP(selected uint32) func(net.Conn) {
+	return func(conn net.Conn) {
+		request := make([]byte, 19)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		reply := []byte{
+			0x03, 0x00, 0x00, 0x13,
+			0x0e, 0xd0, 0x00, 0x00, 0x12, 0x34, 0x00,
+			0x02, 0x00, 0x08, 0x00, 0, 0, 0, 0,
+		}
+		binary.LittleEndian.PutUint32(reply[15:], selected)
+		_, _ = conn.Write(reply)
+	}
+}
+
+func serveSMB2(t *testing.T, dialect uint16, securityMode uint16) func(net.Conn) {
+	return func(conn net.Conn) {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		msg := make([]byte, int(header[1])<<16|int(header[2])<<8|int(header[3]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		if !bytes.HasPrefix(msg, []byte("\xfeSMB")) {
+			t.Errorf("expected SMB2 header, got %x", msg[:4])
+			return
+		}
+		body := msg[64:]
+		count := int(binary.LittleEndian.Uint16(body[2:]))
+		contextOffset := int(binary.LittleEndian.Uint32(body[28:]))
+		if count != len(smbDialects) || contextOffset%8 != 0 || contextOffset >= len(msg) {
+			t.Errorf("bad NEGOTIATE: %d dialects, context at %d of %d", count, contextOffset, len(msg))
+		}
+
+		reply := make([]byte, 64+65)
+		copy(reply, "\xfeSMB")
+		binary.LittleEndian.PutUint16(reply[4:], 64)
+		binary.LittleEndian.PutUint16(reply[64:], 65)
+		binary.LittleEndian.PutUint16(reply[66:], securityMode)
+		binary.LittleEndian.PutUint16(reply[68:], dialect)
+		out := []byte{0, 0, byte(len(reply) >> 8), byte(len(reply))}
+		_, _ = conn.Write(append(out, reply...))
+	}
+}
+
+func serveRedis(t *testing.T, reply string) func(net.Conn) {
+	return func(conn net.Conn) {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		if line != "INFO server\r\n" {
+			t.Errorf("unexpected command %q", line)
+		}
+		_, _ = io.WriteString(conn, reply)
+	}
+}
+
+func testTLSConfig() *tls.Config {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	return srv.TLS.Clone()
+}
+
+func TestTCPProbes(t *testing.T) {
+	redisInfo := "# Server\r\nredis_version:7.2.4\r\nredis_git_sha1:00000000\r\nredis_mode:standalone\r\n"
+
+	tests := []struct {
+		name  string
+		probe tcpProbe
+		serve func(net.Conn)
+		want  serviceID
+	}{
+		{
+			name:  "mysql",
+			probe: probeMySQL,
+			serve: func(conn net.Conn) { conn.Write(mysqlGreeting("8.0.36-0ubuntu0.22.04.1")) },
+			want:  serviceID{product: "MySQL", version: "8.0.36"},
+		},
+		{
+			name:  "mariadb",
+			probe: probeMySQL,
+			serve: func(conn net.Conn) { conn.Write(mysqlGreeting("5.5.5-10.6.12-MariaDB-0ubuntu0.22.04.1")) },
+			want:  serviceID{product: "MariaDB", version: "10.6.12"},
+		},
+		{
+			name:  "postgres plaintext",
+			probe: probePostgres,
+			serve: servePostgres(t, 'N', nil),
+			want:  serviceID{product: "PostgreSQL", detail: "scram auth"},
+		},
+		{
+			name:  "postgres ssl",
+			probe: probePostgres,
+			serve: servePostgres(t, 'S', testTLSConfig()),
+			want:  serviceID{product: "PostgreSQL", detail: "ssl, scram auth"},
+		},
+		{
+			name:  "mqtt anonymous",
+			probe: probeMQTT,
+			serve: serveMQTT(t, 0),
+			want:  serviceID{product: "mosquitto", version: "2.0.18", detail: "anonymous access"},
+		},
+		{
+			name:  "mqtt auth",
+			probe: probeMQTT,
+			serve: serveMQTT(t, 5),
+			want:  serviceID{product: "MQTT", detail: "auth required"},
+		},
+		{
+			name:  "rdp nla",
+			probe: probeRDP,
+			serve: serveRDP(2),
+			want:  serviceID{product: "RDP", detail: "NLA"},
+		},
+		{
+			name:  "smb 3.1.1",
+			probe: probeSMB2,
+			serve: serveSMB2(t, 0x0311, 0x03),
+			want:  serviceID{product: "SMB", version: "3.1.1", detail: "signing required"},
+		},
+		{
+			name:  "smb 2.1",
+			probe: probeSMB2,
+			serve: serveSMB2(t, 0x0210, 0x01),
+			want:  serviceID{product: "SMB", version: "2.1"},
+		},
+		{
+			name:  "redis",
+			probe: probeRedis,
+			serve: serveRedis(t, "$"+strconv.Itoa(len(redisInfo))+"\r\n"+redisInfo+"\r\n"),
+			want:  serviceID{product: "Redis", version: "7.2.4", detail: "no auth"},
+		},
+		{
+			name:  "redis noauth",
+			probe: probeRedis,
+			serve: serveRedis(t, "-NOAUTH Authentication required.\r\n"),
+			want:  serviceID{product: "Redis", detail: "auth required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := fakeService(t, tt.serve)
+			got, ok := runProbe(conn, tt.probe)
+			if !ok {
+				t.Fatalf("probe did not recognise the service")
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTCPProbesRejectOtherServices(t *testing.T) {
+	for key, probe := range probes {
+		if probe.tcp == nil {
+			continue
+		}
+		port := key.port
+		conn := fakeService(t, func(conn net.Conn) {
+			conn.Write([]byte("220 FTP server ready\r\n"))
+			io.Copy(io.Discard, conn)
+		})
+		if id, ok := runProbe(conn, probe.tcp); ok {
+			t.Errorf("port %d probe accepted an FTP banner as %+v", port, id)
+		}
+	}
+}
+
+func TestServiceIDBanner(t *testing.T) {
+	id := serviceID{product: "SMB", version: "3.1.1", detail: "signing required"}
+	if got := id.banner(); got != "SMB 3.1.1, signing required" {
+		t.Fatalf("banner mismatch: got %q", got)
+	}
+}
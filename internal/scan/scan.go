@@ -1,35 +1,107 @@
 package scan
 
 import (
+	"context"
 	"net"
+	"net/netip"
+	"time"
 
+	"github.com/backendsystems/nibble/internal/classify"
 	"github.com/backendsystems/nibble/internal/ports"
 	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
 )
 
 // NetScanner performs real network scanning (TCP connect, ARP, banner grab)
 type NetScanner struct {
-	Ports []int
+	Ports        []int
+	UDPPorts     []int           // Probed with protocol hellos, nil probes none as the default UDP set is empty.
+	ARPSweep     bool            // Broadcast ARP across the subnet to find hosts with no open ports.
+	PingSweep    bool            // Send ICMP echo to every address and record round trip times.
+	MDNSBrowse   bool            // Browse DNS-SD services over mDNS to find advertising devices.
+	SSDPDiscover bool            // Send an SSDP M-SEARCH and read each responder's UPnP description.
+	Passive      time.Duration   // Only listen to traffic for this long instead of probing, zero scans actively.
+	Exclude      *scope.Rules    // Hosts that are never probed, by IP, CIDR or MAC prefix.
+	Classifier   *classify.Rules // Labels each host with a device type and OS, nil skips it.
 }
 
-// ScanNetwork scans a real subnet with controlled concurrency for smooth progress
-func (s *NetScanner) ScanNetwork(ifaceName, subnet string, progressChan chan<- scanner.ProgressUpdate) {
-	_, ipnet, err := net.ParseCIDR(subnet)
+// ScanNetwork scans a target spec (CIDR, range, list, hostname or @file) with
+// controlled concurrency for smooth progress. Cancelling ctx stops feeding new
+// hosts, aborts in-flight dials and closes progressChan.
+func (s *NetScanner) ScanNetwork(ctx context.Context, ifaceName, spec string, progressChan chan<- scanner.ProgressUpdate) {
+	defer close(progressChan)
+
+	if s.Passive > 0 {
+		if accept := acceptTargets(spec); accept != nil {
+			s.passiveListen(ctx, ifaceName, accept, progressChan)
+		}
+		return
+	}
+
+	// An IPv6 prefix is too large to sweep, so hosts come from NDP and multicast only.
+	if prefix, ok := target.Prefix6(spec); ok {
+		ipnet := &net.IPNet{IP: prefix.Addr().AsSlice(), Mask: net.CIDRMask(prefix.Bits(), 128)}
+		neighbors := discoverNeighbors6(ctx, ifaceName, ipnet, s.Exclude)
+		s.neighborDiscovery(ctx, ifaceName, neighbors, 0, progressChan)
+		return
+	}
+
+	targets, err := target.Parse(spec)
 	if err != nil {
 		return
 	}
+	if s.Exclude != nil {
+		targets = targets.Exclude(s.Exclude.Prefixes)
+	}
 
-	totalHosts := scanner.TotalScanHosts(ipnet)
-	skipIPs := s.neighborDiscovery(ifaceName, ipnet, totalHosts, progressChan)
-	s.subnetSweep(ifaceName, ipnet, totalHosts, skipIPs, progressChan)
+	neighbors := visibleNeighbors(ifaceName, targets)
+	arp := s.ARPSweep
+	if s.Exclude.HasMACs() {
+		targets, neighbors = s.resolveExcludedMACs(ctx, ifaceName, targets, neighbors)
+		arp = false // Every on-link target was just asked.
+	}
 
-	close(progressChan)
+	totalHosts := targets.Count()
+	neighbors = mergeNeighbors(neighbors, s.activeDiscovery(ctx, ifaceName, targets, arp))
+	skipIPs := s.neighborDiscovery(ctx, ifaceName, neighbors, totalHosts, progressChan)
+	if ctx.Err() != nil {
+		return
+	}
+	s.subnetSweep(ctx, ifaceName, targets, totalHosts, skipIPs, progressChan)
 }
 
-func (s *NetScanner) ports() (out []int) {
-	out = ports.DefaultPorts()
+// probePorts are the TCP and UDP ports probed on every host.
+type probePorts struct {
+	tcp []int
+	udp []int
+}
+
+// empty reports host-only mode, where liveness comes from ARP alone.
+func (p probePorts) empty() bool {
+	return len(p.tcp) == 0 && len(p.udp) == 0
+}
+
+func (s *NetScanner) ports() (out probePorts) {
+	out.tcp = ports.DefaultPorts()
 	if s.Ports != nil {
-		out = s.Ports
+		out.tcp = s.Ports
+	}
+	out.udp = ports.DefaultUDPPorts()
+	if s.UDPPorts != nil {
+		out.udp = s.UDPPorts
 	}
 	return out
 }
+
+// acceptTargets reports membership in a target spec, nil when it is invalid.
+func acceptTargets(spec string) func(netip.Addr) bool {
+	if prefix, ok := target.Prefix6(spec); ok {
+		return prefix.Contains
+	}
+	targets, err := target.Parse(spec)
+	if err != nil {
+		return nil
+	}
+	return targets.Contains
+}
@@ -0,0 +1,195 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	ssdpWindow             = 2 * time.Second // The M-SEARCH asks for replies within MX 1s.
+	ssdpDescriptionTimeout = 2 * time.Second
+	ssdpMaxDescription     = 256 << 10
+)
+
+var ssdpGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// ssdpDiscover multicasts an SSDP M-SEARCH on the interface, then fetches
+// the UPnP description of every responder among targets that is not excluded.
+func ssdpDiscover(ctx context.Context, ifaceName string, targets target.Set, exclude *scope.Rules) []NeighborEntry {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	_ = ipv4.NewPacketConn(conn).SetMulticastInterface(iface)
+	return discoverSSDP(ctx, conn, ssdpGroup, targets, exclude)
+}
+
+// ssdpResponder is the first reply seen from one host.
+type ssdpResponder struct {
+	addr     netip.Addr
+	server   string
+	location string
+}
+
+func discoverSSDP(ctx context.Context, conn net.PacketConn, dst net.Addr, targets target.Set, exclude *scope.Rules) []NeighborEntry {
+	responders := slices.DeleteFunc(collectSSDP(ctx, conn, dst, targets), func(r ssdpResponder) bool {
+		return excludedHost(exclude, r.addr.String(), "")
+	})
+
+	out := make([]NeighborEntry, len(responders))
+	var wg sync.WaitGroup
+	for i, r := range responders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			device := fetchUPnPDescription(ctx, r.location)
+			device.Server = r.server
+			device.Location = r.location
+			out[i] = NeighborEntry{IP: r.addr.String(), UPnP: &device}
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// collectSSDP sends the search twice, as UDP may drop either, and keeps the
+// first reply from each host until the window closes. Devices answer once
+// per service type, all pointing at the same description.
+func collectSSDP(ctx context.Context, conn net.PacketConn, dst net.Addr, targets target.Set) []ssdpResponder {
+	ctx, cancel := context.WithTimeout(ctx, ssdpWindow)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	search := ssdpSearchRequest()[0]
+	for range 2 {
+		_, _ = conn.WriteTo(search, dst)
+	}
+
+	seen := make(map[netip.Addr]bool)
+	var responders []ssdpResponder
+	buf := make([]byte, 2048)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return responders
+		}
+		addr, ok := peerAddr(peer)
+		if !ok || seen[addr] || !targets.Contains(addr) {
+			continue
+		}
+		header, ok := parseSSDPReply(buf[:n])
+		if !ok {
+			continue
+		}
+		seen[addr] = true
+		responders = append(responders, ssdpResponder{
+			addr:     addr,
+			server:   cleanBanner([]byte(header.Get("Server"))),
+			location: ssdpLocation(header.Get("Location"), addr),
+		})
+	}
+}
+
+func parseSSDPReply(reply []byte) (http.Header, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(reply)), nil)
+	if err != nil {
+		return nil, false
+	}
+	resp.Body.Close()
+	return resp.Header, resp.StatusCode == http.StatusOK
+}
+
+// ssdpLocation returns location when it is an HTTP URL on the responding
+// host itself, so a reply cannot send nibble to fetch from elsewhere.
+func ssdpLocation(location string, addr netip.Addr) string {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "http" {
+		return ""
+	}
+	host, err := netip.ParseAddr(u.Hostname())
+	if err != nil || host.Unmap() != addr {
+		return ""
+	}
+	return location
+}
+
+// upnpDescription is the part of a UPnP device description nibble shows.
+type upnpDescription struct {
+	Device struct {
+		DeviceType   string `xml:"deviceType"`
+		FriendlyName string `xml:"friendlyName"`
+		Manufacturer string `xml:"manufacturer"`
+		ModelName    string `xml:"modelName"`
+		ModelNumber  string `xml:"modelNumber"`
+	} `xml:"device"`
+}
+
+func fetchUPnPDescription(ctx context.Context, location string) scanner.UPnPDevice {
+	if location == "" {
+		return scanner.UPnPDevice{}
+	}
+	ctx, cancel := context.WithTimeout(ctx, ssdpDescriptionTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return scanner.UPnPDevice{}
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return scanner.UPnPDevice{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return scanner.UPnPDevice{}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, ssdpMaxDescription))
+	if err != nil {
+		return scanner.UPnPDevice{}
+	}
+	return parseUPnPDescription(body)
+}
+
+func parseUPnPDescription(body []byte) scanner.UPnPDevice {
+	var desc upnpDescription
+	if err := xml.Unmarshal(body, &desc); err != nil {
+		return scanner.UPnPDevice{}
+	}
+	// Names may be non-ASCII, so whitespace is tidied and control characters
+	// dropped like in every other name a device chose.
+	clean := func(s string) string { return cleanName(strings.Join(strings.Fields(s), " ")) }
+	return scanner.UPnPDevice{
+		FriendlyName: clean(desc.Device.FriendlyName),
+		Manufacturer: clean(desc.Device.Manufacturer),
+		ModelName:    clean(desc.Device.ModelName),
+		ModelNumber:  clean(desc.Device.ModelNumber),
+		DeviceType:   clean(desc.Device.DeviceType),
+	}
+}
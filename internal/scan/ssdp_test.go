@@ -0,0 +1,132 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
+)
+
+const fakeTVDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName>Wohnzimmer TV</friendlyName>
+    <manufacturer>Samsung Electronics</manufacturer>
+    <modelName>QE55Q80T</modelName>
+    <modelNumber>AllShare1.0</modelNumber>
+    <deviceList><device><friendlyName>Nested</friendlyName></device></deviceList>
+  </device>
+</root>`
+
+func TestDiscoverSSDP(t *testing.T) {
+	desc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dmr.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(fakeTVDescription))
+	}))
+	defer desc.Close()
+
+	location := desc.URL + "/dmr.xml"
+	port := fakeUDPService(t, func(search []byte) []byte {
+		if !strings.HasPrefix(string(search), "M-SEARCH * HTTP/1.1\r\n") {
+			return nil
+		}
+		return []byte("HTTP/1.1 200 OK\r\n" +
+			"CACHE-CONTROL: max-age=1800\r\n" +
+			"LOCATION: " + location + "\r\n" +
+			"SERVER: Samsung-Linux/4.1 UPnP/1.0 Samsung UPnP SDK/1.0\r\n" +
+			"ST: urn:schemas-upnp-org:device:MediaRenderer:1\r\n\r\n")
+	})
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	targets, _ := target.Parse("127.0.0.1")
+	dst := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+	got := discoverSSDP(context.Background(), conn, dst, targets, nil)
+
+	if len(got) != 1 || got[0].IP != "127.0.0.1" || got[0].UPnP == nil {
+		t.Fatalf("got %+v", got)
+	}
+	want := scanner.UPnPDevice{
+		FriendlyName: "Wohnzimmer TV",
+		Manufacturer: "Samsung Electronics",
+		ModelName:    "QE55Q80T",
+		ModelNumber:  "AllShare1.0",
+		DeviceType:   "urn:schemas-upnp-org:device:MediaRenderer:1",
+		Server:       "Samsung-Linux/4.1 UPnP/1.0 Samsung UPnP SDK/1.0",
+		Location:     location,
+	}
+	if *got[0].UPnP != want {
+		t.Fatalf("got %+v\nwant %+v", *got[0].UPnP, want)
+	}
+	if summary := want.Summary(); summary != "Wohnzimmer TV, Samsung Electronics QE55Q80T AllShare1.0" {
+		t.Fatalf("summary %q", summary)
+	}
+}
+
+func TestSSDPLocationStaysOnResponder(t *testing.T) {
+	addr := netip.MustParseAddr("192.168.1.20")
+	tests := map[string]string{
+		"http://192.168.1.20:49152/desc.xml": "http://192.168.1.20:49152/desc.xml",
+		"http://192.168.1.21:49152/desc.xml": "",
+		"http://tv.lan/desc.xml":             "",
+		"file:///etc/passwd":                 "",
+	}
+	for location, want := range tests {
+		if got := ssdpLocation(location, addr); got != want {
+			t.Errorf("ssdpLocation(%q) = %q, want %q", location, got, want)
+		}
+	}
+}
+
+func TestDiscoverSSDPSkipsExcludedMAC(t *testing.T) {
+	oldMAC := cachedMAC
+	t.Cleanup(func() { cachedMAC = oldMAC })
+	cachedMAC = func(string) string { return "b8:27:eb:00:00:01" }
+
+	var fetched atomic.Bool
+	desc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched.Store(true)
+		_, _ = w.Write([]byte(fakeTVDescription))
+	}))
+	defer desc.Close()
+
+	port := fakeUDPService(t, func([]byte) []byte {
+		return []byte("HTTP/1.1 200 OK\r\nLOCATION: " + desc.URL + "/dmr.xml\r\nST: upnp:rootdevice\r\n\r\n")
+	})
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	targets, _ := target.Parse("127.0.0.1")
+	exclude, _ := scope.Compile([]string{"B8:27:EB"})
+	dst := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+	if got := discoverSSDP(context.Background(), conn, dst, targets, exclude); len(got) != 0 || fetched.Load() {
+		t.Fatalf("excluded responder found %+v, description fetched %v", got, fetched.Load())
+	}
+}
+
+func TestParseUPnPDescriptionDropsControlCharacters(t *testing.T) {
+	desc := strings.NewReplacer("Wohnzimmer TV", "Wohnzimmer\u009b2J\n  TV", "QE55Q80T", "QE55\u0085Q80T").Replace(fakeTVDescription)
+	got := parseUPnPDescription([]byte(desc))
+	if got.FriendlyName != "Wohnzimmer2J TV" || got.ModelName != "QE55 Q80T" {
+		t.Fatalf("got %+v", got)
+	}
+}
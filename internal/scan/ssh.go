@@ -0,0 +1,334 @@
+package scan
+
+import (
+	"bufio"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+const sshTimeout = time.Second
+
+// SSH message numbers used before any encryption is set up.
+const (
+	sshMsgKexInit  = 20
+	sshMsgKexInit2 = 30 // KEXDH_INIT and KEX_ECDH_INIT
+	sshMsgKexReply = 31 // KEXDH_REPLY and KEX_ECDH_REPLY
+)
+
+const sshClientID = "SSH-2.0-nibble"
+
+// sshKexMethods are the key exchanges the probe can start, in preference order.
+// The probe never derives keys, it only needs the server's reply.
+var sshKexMethods = []string{
+	"curve25519-sha256",
+	"curve25519-sha256@libssh.org",
+	"ecdh-sha2-nistp256",
+	"ecdh-sha2-nistp384",
+	"ecdh-sha2-nistp521",
+	"diffie-hellman-group14-sha256",
+	"diffie-hellman-group14-sha1",
+}
+
+// sshWeak lists algorithms that are broken or deprecated by OpenSSH.
+var sshWeak = map[string]struct{}{
+	"diffie-hellman-group1-sha1":         {},
+	"diffie-hellman-group14-sha1":        {},
+	"diffie-hellman-group-exchange-sha1": {},
+	"ssh-dss":                            {},
+	"ssh-rsa":                            {},
+	"3des-cbc":                           {},
+	"aes128-cbc":                         {},
+	"aes192-cbc":                         {},
+	"aes256-cbc":                         {},
+	"blowfish-cbc":                       {},
+	"cast128-cbc":                        {},
+	"arcfour":                            {},
+	"arcfour128":                         {},
+	"arcfour256":                         {},
+	"rijndael-cbc@lysator.liu.se":        {},
+	"hmac-md5":                           {},
+	"hmac-md5-96":                        {},
+	"hmac-sha1-96":                       {},
+	"hmac-md5-etm@openssh.com":           {},
+	"hmac-md5-96-etm@openssh.com":        {},
+	"hmac-sha1-96-etm@openssh.com":       {},
+	"umac-64@openssh.com":                {},
+	"umac-64-etm@openssh.com":            {},
+	"none":                               {},
+}
+
+// 2048-bit MODP group from RFC 3526, used by diffie-hellman-group14.
+var sshGroup14, _ = new(big.Int).SetString(
+	"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
+		"29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245"+
+		"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D"+
+		"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F"+
+		"83655D23DCA3AD961C62F356208552BB9ED529077096966D"+
+		"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9"+
+		"DE2BCBF6955817183995497CEA956AE515D2261898FA0510"+
+		"15728E5A8AACAA68FFFFFFFFFFFFFFFF", 16)
+
+// probeSSH reads the identification line, then runs the key exchange far
+// enough to see the server's algorithms and host key.
+func probeSSH(conn net.Conn) (serviceID, bool) {
+	_ = conn.SetDeadline(time.Now().Add(sshTimeout))
+	r := bufio.NewReader(conn)
+
+	ident, err := readSSHIdent(r)
+	if err != nil {
+		return serviceID{}, false
+	}
+	id := sshServiceID(ident)
+	if _, err := io.WriteString(conn, sshClientID+"\r\n"); err != nil {
+		return id, true
+	}
+
+	id.ssh = sshKeyExchange(conn, r)
+	return id, true
+}
+
+// readSSHIdent skips any lines a server sends before "SSH-".
+func readSSHIdent(r *bufio.Reader) (string, error) {
+	for range 10 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, "SSH-") {
+			return strings.TrimRight(line, "\r\n"), nil
+		}
+	}
+	return "", errors.New("no SSH identification")
+}
+
+// sshServiceID splits "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3" into its parts.
+func sshServiceID(ident string) serviceID {
+	id := serviceID{raw: ident}
+	software := strings.SplitN(ident, "-", 3)
+	if len(software) < 3 {
+		return id
+	}
+	software[2], _, _ = strings.Cut(software[2], " ")
+	id.product, id.version, _ = strings.Cut(software[2], "_")
+	return id
+}
+
+// sshKeyExchange reads the server's KEXINIT and, when a method in common
+// exists, starts the exchange to get the host key. It returns nil when the
+// server does not send a readable KEXINIT.
+func sshKeyExchange(conn net.Conn, r *bufio.Reader) *scanner.SSHInfo {
+	payload, err := readSSHPacket(r)
+	if err != nil || len(payload) < 17 || payload[0] != sshMsgKexInit {
+		return nil
+	}
+	lists, err := readNameLists(payload[17:], 8)
+	if err != nil {
+		return nil
+	}
+
+	info := &scanner.SSHInfo{
+		Kex:      lists[0],
+		HostKeys: lists[1],
+		Ciphers:  union(lists[2], lists[3]),
+		MACs:     union(lists[4], lists[5]),
+	}
+	for _, list := range [][]string{info.Kex, info.HostKeys, info.Ciphers, info.MACs} {
+		for _, name := range list {
+			if _, weak := sshWeak[name]; weak {
+				info.Weak = append(info.Weak, name)
+			}
+		}
+	}
+
+	kex := ""
+	for _, method := range sshKexMethods {
+		if slices.Contains(info.Kex, method) {
+			kex = method
+			break
+		}
+	}
+	if kex == "" {
+		return info
+	}
+
+	// Echo the server's own lists so it picks its preferred host key and
+	// negotiation cannot fail on ciphers the probe never uses.
+	reply := []byte{sshMsgKexInit}
+	cookie := make([]byte, 16)
+	_, _ = rand.Read(cookie)
+	reply = append(reply, cookie...)
+	reply = appendNameList(reply, []string{kex})
+	for _, list := range lists[1:] {
+		reply = appendNameList(reply, list)
+	}
+	reply = appendNameList(reply, nil)
+	reply = appendNameList(reply, nil)
+	reply = append(reply, 0, 0, 0, 0, 0) // first_kex_packet_follows, reserved
+
+	clientKey, err := sshKexPublic(kex)
+	if err != nil {
+		return info
+	}
+	init := appendSSHString([]byte{sshMsgKexInit2}, clientKey)
+	if err := writeSSHPacket(conn, reply); err != nil {
+		return info
+	}
+	if err := writeSSHPacket(conn, init); err != nil {
+		return info
+	}
+
+	for {
+		payload, err := readSSHPacket(r)
+		if err != nil || len(payload) == 0 {
+			return info
+		}
+		if payload[0] != sshMsgKexReply {
+			continue // e.g. SSH_MSG_IGNORE or EXT_INFO
+		}
+		hostKey, _, ok := readSSHString(payload[1:])
+		if !ok {
+			return info
+		}
+		keyType, _, _ := readSSHString(hostKey)
+		info.HostKeyType = string(keyType)
+		info.Fingerprint = sshFingerprint(hostKey)
+		return info
+	}
+}
+
+// sshKexPublic returns the client's ephemeral public value for a method.
+func sshKexPublic(kex string) ([]byte, error) {
+	var curve ecdh.Curve
+	switch kex {
+	case "curve25519-sha256", "curve25519-sha256@libssh.org":
+		curve = ecdh.X25519()
+	case "ecdh-sha2-nistp256":
+		curve = ecdh.P256()
+	case "ecdh-sha2-nistp384":
+		curve = ecdh.P384()
+	case "ecdh-sha2-nistp521":
+		curve = ecdh.P521()
+	default:
+		x, err := rand.Int(rand.Reader, sshGroup14)
+		if err != nil {
+			return nil, err
+		}
+		e := new(big.Int).Exp(big.NewInt(2), x, sshGroup14)
+		return sshMPInt(e), nil
+	}
+	key, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return key.PublicKey().Bytes(), nil
+}
+
+// sshFingerprint formats a host key blob the way ssh-keygen -l does.
+func sshFingerprint(hostKey []byte) string {
+	sum := sha256.Sum256(hostKey)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// sshMPInt encodes a positive integer as an SSH mpint without the length.
+func sshMPInt(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func readSSHPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header)
+	padding := uint32(header[4])
+	if size < padding+1 || size > 35000 {
+		return nil, fmt.Errorf("bad packet length %d", size)
+	}
+	body := make([]byte, size-1)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body[:len(body)-int(padding)], nil
+}
+
+func writeSSHPacket(w io.Writer, payload []byte) error {
+	padding := 8 - (5+len(payload))%8
+	if padding < 4 {
+		padding += 8
+	}
+	packet := make([]byte, 5, 5+len(payload)+padding)
+	binary.BigEndian.PutUint32(packet, uint32(1+len(payload)+padding))
+	packet[4] = byte(padding)
+	packet = append(packet, payload...)
+	packet = append(packet, make([]byte, padding)...)
+	_, err := w.Write(packet)
+	return err
+}
+
+func readSSHString(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	size := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < size {
+		return nil, nil, false
+	}
+	return b[4 : 4+size], b[4+size:], true
+}
+
+func appendSSHString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func readNameLists(b []byte, count int) ([][]string, error) {
+	lists := make([][]string, 0, count)
+	for range count {
+		field, rest, ok := readSSHString(b)
+		if !ok {
+			return nil, errors.New("truncated name-list")
+		}
+		var names []string
+		if len(field) > 0 {
+			names = strings.Split(string(field), ",")
+		}
+		lists = append(lists, names)
+		b = rest
+	}
+	return lists, nil
+}
+
+func appendNameList(b []byte, names []string) []byte {
+	return appendSSHString(b, []byte(strings.Join(names, ",")))
+}
+
+// union joins two name-lists, keeping the order of first appearance.
+func union(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, name := range b {
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
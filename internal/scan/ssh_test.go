@@ -0,0 +1,177 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math/big"
+	"net"
+	"slices"
+	"testing"
+)
+
+// fakeSSHHostKey is an ssh-ed25519 public key blob.
+var fakeSSHHostKey = appendSSHString(appendSSHString(nil, []byte("ssh-ed25519")), make([]byte, 32))
+
+// serveSSH plays the server side of a key exchange up to the host key.
+func serveSSH(t *testing.T, kex, ciphers []string) func(net.Conn) {
+	return func(conn net.Conn) {
+		_, _ = io.WriteString(conn, "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n")
+		kexInit := append([]byte{sshMsgKexInit}, make([]byte, 16)...)
+		for _, list := range [][]string{
+			kex,
+			{"ssh-ed25519", "ssh-rsa"},
+			ciphers, ciphers,
+			{"hmac-sha2-256", "hmac-md5"}, {"hmac-sha2-256", "hmac-md5"},
+			{"none"}, {"none"},
+			nil, nil,
+		} {
+			kexInit = appendNameList(kexInit, list)
+		}
+		kexInit = append(kexInit, 0, 0, 0, 0, 0)
+		if err := writeSSHPacket(conn, kexInit); err != nil {
+			return
+		}
+
+		r := bufio.NewReader(conn)
+		// The generic banner read hangs up here, only a probe goes on.
+		ident, err := readSSHIdent(r)
+		if err != nil {
+			return
+		}
+		if ident != sshClientID {
+			t.Errorf("client identification %q", ident)
+			return
+		}
+		clientInit, err := readSSHPacket(r)
+		if err != nil || clientInit[0] != sshMsgKexInit {
+			t.Errorf("expected client KEXINIT: %v", err)
+			return
+		}
+		lists, _ := readNameLists(clientInit[17:], 2)
+		if len(lists[0]) != 1 || !slices.Contains(kex, lists[0][0]) {
+			t.Errorf("client offered kex %v, server has %v", lists[0], kex)
+		}
+		if !slices.Equal(lists[1], []string{"ssh-ed25519", "ssh-rsa"}) {
+			t.Errorf("client host key list %v", lists[1])
+		}
+
+		kexDH, err := readSSHPacket(r)
+		if err != nil || kexDH[0] != sshMsgKexInit2 {
+			t.Errorf("expected KEX_ECDH_INIT: %v", err)
+			return
+		}
+		if public, _, ok := readSSHString(kexDH[1:]); !ok || len(public) == 0 {
+			t.Errorf("empty client public key")
+		}
+
+		reply := appendSSHString([]byte{sshMsgKexReply}, fakeSSHHostKey)
+		reply = appendSSHString(reply, make([]byte, 32))
+		reply = appendSSHString(reply, []byte("signature"))
+		_ = writeSSHPacket(conn, reply)
+	}
+}
+
+func TestProbeSSH(t *testing.T) {
+	tests := []struct {
+		name     string
+		kex      []string
+		ciphers  []string
+		wantWeak []string
+	}{
+		{
+			name:     "curve25519",
+			kex:      []string{"sntrup761x25519-sha512@openssh.com", "curve25519-sha256"},
+			ciphers:  []string{"chacha20-poly1305@openssh.com", "aes128-ctr"},
+			wantWeak: []string{"ssh-rsa", "hmac-md5"},
+		},
+		{
+			name:     "group14",
+			kex:      []string{"diffie-hellman-group14-sha1"},
+			ciphers:  []string{"aes128-ctr", "3des-cbc"},
+			wantWeak: []string{"diffie-hellman-group14-sha1", "ssh-rsa", "3des-cbc", "hmac-md5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := fakeService(t, serveSSH(t, tt.kex, tt.ciphers))
+			id, ok := runProbe(conn, probeSSH)
+			if !ok {
+				t.Fatalf("SSH not recognised")
+			}
+			if id.product != "OpenSSH" || id.version != "9.6p1" {
+				t.Fatalf("product %q version %q", id.product, id.version)
+			}
+			if id.banner() != "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13" {
+				t.Fatalf("banner mismatch: got %q", id.banner())
+			}
+			if id.ssh == nil {
+				t.Fatalf("no key exchange details")
+			}
+			if id.ssh.HostKeyType != "ssh-ed25519" || id.ssh.Fingerprint != sshFingerprint(fakeSSHHostKey) {
+				t.Fatalf("host key %q %q", id.ssh.HostKeyType, id.ssh.Fingerprint)
+			}
+			if !slices.Equal(id.ssh.Ciphers, tt.ciphers) {
+				t.Fatalf("ciphers %v", id.ssh.Ciphers)
+			}
+			if !slices.Equal(id.ssh.Weak, tt.wantWeak) {
+				t.Fatalf("weak %v, want %v", id.ssh.Weak, tt.wantWeak)
+			}
+		})
+	}
+}
+
+func TestSSHFingerprintFormat(t *testing.T) {
+	// ssh-keygen prints unpadded base64 of the SHA256 digest.
+	got := sshFingerprint([]byte("key"))
+	if got != "SHA256:LHDhK3oGRvkiefQnx7OOczTY5Tic/xZ6HcMOc/gmtoM" {
+		t.Fatalf("fingerprint mismatch: got %q", got)
+	}
+}
+
+func TestSSHGroup14IsSafePrime(t *testing.T) {
+	if sshGroup14 == nil || sshGroup14.BitLen() != 2048 {
+		t.Fatalf("group14 prime did not parse")
+	}
+	q := new(big.Int).Rsh(sshGroup14, 1)
+	if !sshGroup14.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+		t.Fatalf("group14 modulus is not a safe prime")
+	}
+}
+
+func TestGrabServiceProbesSSHOnOtherPorts(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serveSSH(t, []string{"curve25519-sha256"}, []string{"aes256-ctr"})(conn)
+			}()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	dialer := net.Dialer{Timeout: sshTimeout}
+	conn, err := dialer.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	got := grabService(context.Background(), &dialer, conn, addr.Port)
+	if got.ssh == nil || got.ssh.Fingerprint == "" {
+		t.Fatalf("SSH host key not captured on port %d: %+v", addr.Port, got)
+	}
+	if got.product != "OpenSSH" {
+		t.Fatalf("product mismatch: got %q", got.product)
+	}
+}
@@ -1,11 +1,15 @@
 package scan
 
 import (
+	"context"
 	"net"
+	"net/netip"
 	"sync"
 	"sync/atomic"
 
 	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
 )
 
 const (
@@ -14,12 +18,13 @@ const (
 )
 
 // neighborDiscovery emits hosts already visible in neighbor tables
-// and returns IPs that should be skipped in the full sweep
-func (s *NetScanner) neighborDiscovery(ifaceName string, subnet *net.IPNet, totalHosts int, progressChan chan<- scanner.ProgressUpdate) map[string]struct{} {
-	neighbors := visibleNeighbors(ifaceName, subnet)
+// and returns IPs that should be skipped in the full sweep.
+// Excluded neighbors are skipped too but never probed.
+func (s *NetScanner) neighborDiscovery(ctx context.Context, ifaceName string, neighbors []NeighborEntry, totalHosts int, progressChan chan<- scanner.ProgressUpdate) map[string]struct{} {
 	skipIPs := buildSkipMap(neighbors)
+	neighbors = s.allowedNeighbors(neighbors)
 	if len(neighbors) == 0 {
-		emitNeighborProgress(progressChan, scanner.NeighborProgress{TotalHosts: totalHosts})
+		sendProgress(ctx, progressChan, scanner.NeighborProgress{TotalHosts: totalHosts})
 		return skipIPs
 	}
 
@@ -38,13 +43,18 @@ func (s *NetScanner) neighborDiscovery(ifaceName string, subnet *net.IPNet, tota
 		go func() {
 			defer wg.Done()
 			for neighbor := range jobs {
-				processNeighborJob(ifaceName, neighbor, ports, totalHosts, len(neighbors), &seenCount, progressChan)
+				s.processNeighborJob(ctx, ifaceName, neighbor, ports, totalHosts, len(neighbors), &seenCount, progressChan)
 			}
 		}()
 	}
 
+feed:
 	for _, neighbor := range neighbors {
-		jobs <- neighbor
+		select {
+		case jobs <- neighbor:
+		case <-ctx.Done():
+			break feed
+		}
 	}
 	close(jobs)
 
@@ -52,8 +62,8 @@ func (s *NetScanner) neighborDiscovery(ifaceName string, subnet *net.IPNet, tota
 	return skipIPs
 }
 
-// subnetSweep scans the subnet and skips hosts found in neighbor discovery
-func (s *NetScanner) subnetSweep(ifaceName string, subnet *net.IPNet, totalHosts int, skipIPs map[string]struct{}, progressChan chan<- scanner.ProgressUpdate) {
+// subnetSweep scans every target and skips hosts found in neighbor discovery
+func (s *NetScanner) subnetSweep(ctx context.Context, ifaceName string, targets target.Set, totalHosts int, skipIPs map[string]struct{}, progressChan chan<- scanner.ProgressUpdate) {
 	ports := s.ports()
 	jobs := make(chan string, sweepPhaseMaxWorkers)
 	var wg sync.WaitGroup
@@ -64,106 +74,172 @@ func (s *NetScanner) subnetSweep(ifaceName string, subnet *net.IPNet, totalHosts
 		go func() {
 			defer wg.Done()
 			for currentIP := range jobs {
-				processSweepJob(ifaceName, currentIP, ports, skipIPs, totalHosts, &scanned, progressChan)
+				s.processSweepJob(ctx, ifaceName, currentIP, ports, skipIPs, totalHosts, &scanned, progressChan)
 			}
 		}()
 	}
 
-	for ip := subnet.IP.Mask(subnet.Mask); subnet.Contains(ip); incrementIP(ip) {
-		if skipIp4(ip, subnet) {
-			continue
+	targets.Each(func(addr netip.Addr) bool {
+		select {
+		case jobs <- withLinkZone(addr, ifaceName).String():
+			return true
+		case <-ctx.Done():
+			return false
 		}
-
-		jobs <- ip.String()
-	}
+	})
 	close(jobs)
 
 	wg.Wait()
 }
 
-func processNeighborJob(ifaceName string, neighbor NeighborEntry, ports []int, totalHosts, totalNeighbors int, seenCount *atomic.Int64, progressChan chan<- scanner.ProgressUpdate) {
-	hostInfo := scanHostMac(ifaceName, neighbor.IP, neighbor.MAC, ports)
-	if hostInfo == "" {
-		hostInfo = formatHost(neighbor)
+func (s *NetScanner) processNeighborJob(ctx context.Context, ifaceName string, neighbor NeighborEntry, ports probePorts, totalHosts, totalNeighbors int, seenCount *atomic.Int64, progressChan chan<- scanner.ProgressUpdate) {
+	if ctx.Err() != nil {
+		return
+	}
+	host := scanHostMac(ctx, ifaceName, neighbor.IP, neighbor.MAC, ports)
+	if host == nil {
+		host = neighborHost(ifaceName, neighbor)
 	}
+	host.RTT = neighbor.RTT
+	host.TTL = neighbor.TTL
+	host.MDNS = neighbor.MDNS
+	host.UPnP = neighbor.UPnP
+	resolveNames(ctx, ifaceName, host)
+	s.Classifier.Classify(host)
 
 	currentSeen := int(seenCount.Add(1))
 
-	emitNeighborProgress(progressChan, scanner.NeighborProgress{
-		Host:       hostInfo,
+	sendProgress(ctx, progressChan, scanner.NeighborProgress{
+		Host:       host,
 		TotalHosts: totalHosts,
 		Seen:       currentSeen,
 		Total:      totalNeighbors,
 	})
 }
 
-func processSweepJob(ifaceName, currentIP string, ports []int, skipIPs map[string]struct{}, totalHosts int, scanned *atomic.Int64, progressChan chan<- scanner.ProgressUpdate) {
-	hostInfo := ""
-	if len(ports) > 0 {
+func (s *NetScanner) processSweepJob(ctx context.Context, ifaceName, currentIP string, ports probePorts, skipIPs map[string]struct{}, totalHosts int, scanned *atomic.Int64, progressChan chan<- scanner.ProgressUpdate) {
+	if ctx.Err() != nil {
+		return
+	}
+	var host *scanner.HostResult
+	if !ports.empty() && !excludedHost(s.Exclude, currentIP, "") {
 		if _, alreadyFound := skipIPs[currentIP]; !alreadyFound {
-			hostInfo = scanHost(ifaceName, currentIP, ports)
+			host = scanHost(ctx, ifaceName, currentIP, ports)
 		}
 	}
+	if host != nil {
+		resolveNames(ctx, ifaceName, host)
+		s.Classifier.Classify(host)
+	}
 
 	currentScanned := int(scanned.Add(1))
 
-	progressChan <- scanner.SweepProgress{
-		Host:       hostInfo,
+	sendProgress(ctx, progressChan, scanner.SweepProgress{
+		Host:       host,
 		TotalHosts: totalHosts,
 		Scanned:    currentScanned,
 		Total:      totalHosts,
-	}
+	})
 }
 
-func buildSkipMap(neighbors []NeighborEntry) map[string]struct{} {
-	skipIPs := make(map[string]struct{}, len(neighbors))
+// allowedNeighbors drops neighbors matching the exclude list.
+func (s *NetScanner) allowedNeighbors(neighbors []NeighborEntry) []NeighborEntry {
+	if s.Exclude == nil {
+		return neighbors
+	}
+	allowed := make([]NeighborEntry, 0, len(neighbors))
 	for _, neighbor := range neighbors {
-		skipIPs[neighbor.IP] = struct{}{}
+		if !excludedHost(s.Exclude, neighbor.IP, neighbor.MAC) {
+			allowed = append(allowed, neighbor)
+		}
 	}
-	return skipIPs
+	return allowed
 }
 
-func formatHost(neighbor NeighborEntry) string {
-	return scanner.FormatHost(scanner.HostResult{
-		IP:       neighbor.IP,
-		Hardware: VendorFromMac(neighbor.MAC),
-	})
-}
+// resolveExcludedMACs learns the MAC of every on-link target before anything
+// else is sent, so MAC excludes also hold for hosts the OS has not cached.
+// The ARP request is all an excluded host gets. On-link targets whose MAC
+// stays unknown are dropped as well, as they cannot be told apart from an
+// excluded device, e.g. when ARP needs privileges nibble lacks. Routed
+// targets are kept, their MAC is never visible. It returns the remaining
+// targets and the cached or answering neighbors among them.
+func (s *NetScanner) resolveExcludedMACs(ctx context.Context, ifaceName string, targets target.Set, cached []NeighborEntry) (target.Set, []NeighborEntry) {
+	var link onLink
+	if iface, err := net.InterfaceByName(ifaceName); err == nil {
+		link = linkNets(iface)
+	}
+	found := mergeNeighbors(cached, arpSweep(ctx, ifaceName, targets))
+	macs := make(map[netip.Addr]string, len(found))
+	for _, neighbor := range found {
+		if addr, err := netip.ParseAddr(neighbor.IP); err == nil {
+			macs[addr] = neighbor.MAC
+		}
+	}
 
-func emitNeighborProgress(progressChan chan<- scanner.ProgressUpdate, progress scanner.NeighborProgress) {
-	select {
-	case progressChan <- progress:
-	default:
+	targets = keepByMAC(targets, link, macs, s.Exclude)
+	var neighbors []NeighborEntry
+	for _, neighbor := range found {
+		if addr, err := netip.ParseAddr(neighbor.IP); err == nil && targets.Contains(addr) {
+			neighbors = append(neighbors, neighbor)
+		}
 	}
+	return targets, neighbors
 }
 
-func incrementIP(ip net.IP) {
-	for i := len(ip) - 1; i >= 0; i-- {
-		ip[i]++
-		if ip[i] > 0 {
-			return
+// keepByMAC drops on-link targets whose MAC is excluded or unknown.
+func keepByMAC(targets target.Set, link onLink, macs map[netip.Addr]string, exclude *scope.Rules) target.Set {
+	return targets.Filter(func(addr netip.Addr) bool {
+		if !link.contains(addr) {
+			return true
 		}
-	}
+		mac, ok := macs[addr]
+		return ok && !exclude.ExcludesMAC(mac)
+	})
 }
 
-func skipIp4(ip net.IP, subnet *net.IPNet) bool {
-	ip4 := ip.To4()
-	base := subnet.IP.To4()
-	mask := subnet.Mask
-	if ip4 == nil || base == nil || len(mask) != net.IPv4len {
+// cachedMAC finds the MAC of an address in the OS cache, tests replace it.
+var cachedMAC = lookupMacFromCache
+
+// excludedHost checks a host against the exclude list. Ping, mDNS, SSDP and
+// sweep targets come without a MAC, which is then only known if the OS
+// already cached it, reading that sends nothing.
+func excludedHost(exclude *scope.Rules, ip, mac string) bool {
+	if exclude.ExcludesIP(ip) {
+		return true
+	}
+	if !exclude.HasMACs() {
 		return false
 	}
-	// loop
-	if ip4.Equal(base.Mask(mask)) {
-		return true
+	if mac == "" {
+		mac = cachedMAC(ip)
+	}
+	return exclude.ExcludesMAC(mac)
+}
+
+func buildSkipMap(neighbors []NeighborEntry) map[string]struct{} {
+	skipIPs := make(map[string]struct{}, len(neighbors))
+	for _, neighbor := range neighbors {
+		skipIPs[neighbor.IP] = struct{}{}
 	}
+	return skipIPs
+}
 
-	broadcast := net.IPv4(
-		base[0]|^mask[0],
-		base[1]|^mask[1],
-		base[2]|^mask[2],
-		base[3]|^mask[3],
-	)
-	// broadcast
-	return ip4.Equal(broadcast)
+// neighborHost describes a neighbor with no open ports. Hosts found by ping
+// carry no MAC, so the ARP cache the ping just populated is consulted.
+func neighborHost(ifaceName string, neighbor NeighborEntry) *scanner.HostResult {
+	mac := resolveHostMac(ifaceName, net.ParseIP(neighbor.IP), neighbor.MAC)
+	return &scanner.HostResult{
+		IP:       neighbor.IP,
+		MAC:      mac,
+		Hardware: VendorFromMac(mac),
+	}
+}
+
+// sendProgress delivers an update unless the scan is cancelled first,
+// so workers never block on a listener that has gone away.
+func sendProgress(ctx context.Context, progressChan chan<- scanner.ProgressUpdate, update scanner.ProgressUpdate) {
+	select {
+	case progressChan <- update:
+	case <-ctx.Done():
+	}
 }
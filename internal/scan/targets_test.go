@@ -0,0 +1,65 @@
+package scan
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
+)
+
+func TestAllowedNeighborsLooksUpMissingMACs(t *testing.T) {
+	oldMAC := cachedMAC
+	t.Cleanup(func() { cachedMAC = oldMAC })
+	cached := map[string]string{"192.168.1.20": "b8:27:eb:00:00:01"}
+	cachedMAC = func(ip string) string { return cached[ip] }
+
+	exclude, err := scope.Compile([]string{"B8:27:EB", "192.168.1.40"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &NetScanner{Exclude: exclude}
+	neighbors := []NeighborEntry{
+		{IP: "192.168.1.10", MAC: "b8:27:eb:00:00:02"}, // Excluded by its own MAC.
+		{IP: "192.168.1.20"},                           // Found by ping, excluded by the cached MAC.
+		{IP: "192.168.1.30"},                           // Not cached, nothing to exclude it by.
+		{IP: "192.168.1.40"},                           // Excluded by IP.
+		{IP: "192.168.1.50", MAC: "00:11:22:00:00:01"},
+	}
+	got := s.allowedNeighbors(neighbors)
+	if len(got) != 2 || got[0].IP != "192.168.1.30" || got[1].IP != "192.168.1.50" {
+		t.Fatalf("allowed %+v", got)
+	}
+}
+
+func TestKeepByMACWithoutCachedMACs(t *testing.T) {
+	oldMAC := cachedMAC
+	t.Cleanup(func() { cachedMAC = oldMAC })
+	cachedMAC = func(string) string { return "" }
+
+	exclude, err := scope.Compile([]string{"B8:27:EB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, _ := target.Parse("192.168.1.1-4, 10.0.0.5")
+	link := onLink{netip.MustParsePrefix("192.168.1.0/24")}
+	// What ARP found, the OS cache knows none of them.
+	macs := map[netip.Addr]string{
+		netip.MustParseAddr("192.168.1.1"): "00:11:22:00:00:01",
+		netip.MustParseAddr("192.168.1.2"): "b8:27:eb:00:00:02",
+		netip.MustParseAddr("192.168.1.4"): "00:11:22:00:00:04",
+	}
+
+	kept := keepByMAC(targets, link, macs, exclude)
+	var got []string
+	kept.Each(func(addr netip.Addr) bool {
+		got = append(got, addr.String())
+		return true
+	})
+	// .2 is excluded by the MAC ARP found, .3 never answered and the routed
+	// 10.0.0.5 has no MAC to check.
+	if want := []string{"10.0.0.5", "192.168.1.1", "192.168.1.4"}; !slices.Equal(got, want) {
+		t.Fatalf("kept %v, want %v", got, want)
+	}
+}
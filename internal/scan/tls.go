@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+const tlsHandshakeTimeout = time.Second
+
+// tlsPorts go straight to a handshake, a plaintext probe only gets an alert.
+var tlsPorts = map[int]struct{}{
+	443:  {}, // HTTPS
+	465:  {}, // SMTPS
+	636:  {}, // LDAPS
+	853:  {}, // DNS over TLS
+	993:  {}, // IMAPS
+	995:  {}, // POP3S
+	5986: {}, // WinRM HTTPS
+	8443: {}, // Alt HTTPS
+	9443: {}, // Alt HTTPS
+}
+
+// httpsPorts serve HTTP behind the handshake. Other ports only get an HTTP
+// request when the server picked HTTP by ALPN.
+var httpsPorts = map[int]struct{}{
+	443:  {},
+	5986: {},
+	8443: {},
+	9443: {},
+}
+
+// grabTLS completes a handshake without verifying the certificate, records
+// the leaf certificate, then reads the HTTP Server header over TLS on HTTPS
+// ports. It returns a nil TLSInfo when the port does not speak TLS, and
+// reports whether HTTP answered behind the handshake.
+func grabTLS(conn net.Conn, port int) (string, *scanner.TLSInfo, bool) {
+	_ = conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+
+	config := &tls.Config{
+		InsecureSkipVerify: true,
+		// Old appliances only offer legacy versions and ciphers.
+		MinVersion:   tls.VersionTLS10,
+		CipherSuites: allCipherSuites(),
+	}
+	// Mail and directory servers may refuse an ALPN offer of HTTP, ports
+	// nibble knows nothing about are asked whether they speak it.
+	_, https := httpsPorts[port]
+	if _, known := tlsPorts[port]; https || !known {
+		config.NextProtos = []string{"http/1.1"}
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return "", nil, false
+	}
+
+	state := tlsConn.ConnectionState()
+	info := tlsInfo(state)
+	if !https && state.NegotiatedProtocol != "http/1.1" {
+		return "", info, false
+	}
+	_ = conn.SetDeadline(time.Now().Add(bannerReadTimeout))
+	banner, hint := requestHttpBanner(tlsConn)
+	return banner, info, hint == hintHTTP
+}
+
+func tlsInfo(state tls.ConnectionState) *scanner.TLSInfo {
+	info := &scanner.TLSInfo{Version: tls.VersionName(state.Version)}
+	if len(state.PeerCertificates) == 0 {
+		return info
+	}
+	cert := state.PeerCertificates[0]
+	info.Subject = cert.Subject.CommonName
+	info.SANs = certNames(cert)
+	info.Issuer = cert.Issuer.CommonName
+	if info.Issuer == "" && len(cert.Issuer.Organization) > 0 {
+		info.Issuer = cert.Issuer.Organization[0]
+	}
+	info.NotAfter = cert.NotAfter
+	return info
+}
+
+// certNames lists DNS and IP subject alternative names.
+func certNames(cert *x509.Certificate) []string {
+	names := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+func allCipherSuites() []uint16 {
+	var ids []uint16
+	for _, suite := range tls.CipherSuites() {
+		ids = append(ids, suite.ID)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		ids = append(ids, suite.ID)
+	}
+	return ids
+}
+
+// isTLSReply reports a TLS record, usually an alert, in reply to plaintext.
+func isTLSReply(b []byte) bool {
+	return len(b) >= 3 && (b[0] == 0x15 || b[0] == 0x16) && b[1] == 0x03 && b[2] <= 0x04
+}
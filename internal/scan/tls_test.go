@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGrabServiceRetriesTLSOnUnknownPort(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "fake-https/1.0")
+	}))
+	// The plaintext probe makes the server log a handshake error.
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+	dialer := net.Dialer{Timeout: tlsHandshakeTimeout}
+	conn, err := dialer.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	got := grabService(context.Background(), &dialer, conn, addr.Port)
+	if got.tls == nil {
+		t.Fatalf("TLS not detected on port %d", addr.Port)
+	}
+	if got.banner != "fake-https/1.0" {
+		t.Fatalf("banner mismatch: got %q", got.banner)
+	}
+	if got.tls.Version == "" || len(got.tls.SANs) == 0 || got.tls.NotAfter.IsZero() {
+		t.Fatalf("certificate details missing: %+v", got.tls)
+	}
+}
+
+func TestGrabTLSSkipsHTTPOnOtherServices(t *testing.T) {
+	// Borrow the test certificate of an HTTPS server for a TLS service
+	// that does not speak HTTP.
+	https := httptest.NewTLSServer(http.NotFoundHandler())
+	https.Close()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: https.TLS.Certificates})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	requests := make(chan int, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.SetReadDeadline(time.Now().Add(2 * bannerReadTimeout))
+			n, _ := conn.Read(make([]byte, 64))
+			requests <- n
+			conn.Close()
+		}
+	}()
+
+	for _, port := range []int{993, 40000} {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		banner, info, isHTTP := grabTLS(conn, port)
+		conn.Close()
+		if info == nil || banner != "" || isHTTP {
+			t.Fatalf("port %d: banner %q, tls %+v, http %v", port, banner, info, isHTTP)
+		}
+		if n := <-requests; n != 0 {
+			t.Fatalf("port %d: sent %d bytes after the handshake", port, n)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+const (
+	udpReplyTimeout = 400 * time.Millisecond
+	udpReplyGrace   = 50 * time.Millisecond
+)
+
+// scanUDPPorts sends each port its protocol hello and reports the ports that
+// answer. Silent ports are left out since closed and filtered look the same.
+func scanUDPPorts(ctx context.Context, ip string, ports []int) []portResult {
+	var wg sync.WaitGroup
+	var resultMu sync.Mutex
+	results := make([]portResult, 0, len(ports))
+
+	for _, port := range ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			if dialLimiter != nil {
+				select {
+				case dialLimiter <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() {
+					<-dialLimiter // release
+				}()
+			}
+
+			banner, ok := probeUDP(ctx, ip, port)
+			if !ok {
+				return
+			}
+			resultMu.Lock()
+			results = append(results, portResult{port: port, proto: scanner.ProtoUDP, banner: banner})
+			resultMu.Unlock()
+		}(port)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// probeUDP sends the port's probe and parses the replies. The socket is left
+// unconnected because some services, such as TFTP, answer from another port.
+func probeUDP(ctx context.Context, ip string, port int) (string, bool) {
+	dst, err := net.ResolveUDPAddr("udp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return "", false
+	}
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return "", false
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	probe := udpProbeFor(port)
+	for _, request := range probe.requests() {
+		if _, err := conn.WriteTo(request, dst); err != nil {
+			return "", false
+		}
+	}
+
+	// Wait for the first reply, then give multi-packet answers a moment.
+	_ = conn.SetReadDeadline(time.Now().Add(udpReplyTimeout))
+	var replies [][]byte
+	buf := make([]byte, 4096)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		from, ok := peer.(*net.UDPAddr)
+		if !ok || !from.IP.Equal(dst.IP) {
+			continue
+		}
+		if len(replies) == 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(udpReplyGrace))
+		}
+		replies = append(replies, append([]byte(nil), buf[:n]...))
+	}
+	if len(replies) == 0 {
+		return "", false
+	}
+	return probe.parse(replies), true
+}
@@ -0,0 +1,323 @@
+package scan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// udpProbe is a protocol hello for a UDP port and the parser for its replies.
+type udpProbe struct {
+	requests func() [][]byte
+	parse    func(replies [][]byte) string
+}
+
+// genericUDPProbe nudges unknown services with a blank line.
+var genericUDPProbe = udpProbe{
+	requests: func() [][]byte { return [][]byte{[]byte("\r\n\r\n")} },
+	parse:    func(replies [][]byte) string { return cleanBanner(replies[0]) },
+}
+
+// udpProbeFor returns the protocol hello of a UDP port, or the generic one.
+func udpProbeFor(port int) udpProbe {
+	if p := probes[probeKey{port, scanner.ProtoUDP}]; p.udp.requests != nil {
+		return p.udp
+	}
+	return genericUDPProbe
+}
+
+// dnsVersionRequest asks for the CHAOS TXT record version.bind.
+func dnsVersionRequest() [][]byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(rand.Uint32())})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("version.bind."),
+		Type:  dnsmessage.TypeTXT,
+		Class: dnsmessage.ClassCHAOS,
+	})
+	msg, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+	return [][]byte{msg}
+}
+
+func parseDNSVersion(replies [][]byte) string {
+	var p dnsmessage.Parser
+	header, err := p.Start(replies[0])
+	if err != nil {
+		return cleanBanner(replies[0])
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return "DNS"
+	}
+	for {
+		answer, err := p.Answer()
+		if err != nil {
+			break
+		}
+		if txt, ok := answer.Body.(*dnsmessage.TXTResource); ok && len(txt.TXT) > 0 {
+			return cleanBanner([]byte(strings.Join(txt.TXT, " ")))
+		}
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return "DNS, version hidden"
+	}
+	return "DNS"
+}
+
+// tftpRequest reads a file that should not exist; servers answer with an error.
+func tftpRequest() [][]byte {
+	req := []byte{0, 1}
+	req = append(req, "nibble-probe"...)
+	req = append(req, 0)
+	req = append(req, "octet"...)
+	req = append(req, 0)
+	return [][]byte{req}
+}
+
+func parseTFTP(replies [][]byte) string {
+	reply := replies[0]
+	if len(reply) < 4 {
+		return "TFTP"
+	}
+	switch binary.BigEndian.Uint16(reply) {
+	case 3:
+		return "TFTP, serves files without auth"
+	case 5:
+		msg := strings.TrimRight(string(reply[4:]), "\x00")
+		return cleanBanner([]byte("TFTP: " + msg))
+	}
+	return "TFTP"
+}
+
+// ntpRequests sends a mode 6 READVAR for the daemon version and a mode 3
+// client request, which every server answers even with control queries off.
+func ntpRequests() [][]byte {
+	readVar := make([]byte, 12)
+	readVar[0] = 2<<3 | 6 // version 2, mode 6 (control)
+	readVar[1] = 2        // opcode READVAR
+	binary.BigEndian.PutUint16(readVar[2:], uint16(rand.Uint32()))
+
+	client := make([]byte, 48)
+	client[0] = 4<<3 | 3 // version 4, mode 3 (client)
+	return [][]byte{readVar, client}
+}
+
+func parseNTP(replies [][]byte) string {
+	summary := "NTP"
+	for _, reply := range replies {
+		if len(reply) < 12 {
+			continue
+		}
+		switch reply[0] & 0x07 {
+		case 6:
+			if version := ntpVersionVar(string(reply[12:])); version != "" {
+				return cleanBanner([]byte(version))
+			}
+		case 4:
+			if len(reply) < 48 {
+				continue
+			}
+			summary = fmt.Sprintf("NTPv%d stratum %d", reply[0]>>3&0x07, reply[1])
+			if ref := ntpRefID(reply[1], reply[12:16]); ref != "" {
+				summary += " ref " + ref
+			}
+		}
+	}
+	return summary
+}
+
+// ntpVersionVar extracts version="..." from a READVAR response.
+func ntpVersionVar(vars string) string {
+	i := strings.Index(vars, `version="`)
+	if i < 0 {
+		return ""
+	}
+	rest := vars[i+len(`version="`):]
+	if j := strings.IndexByte(rest, '"'); j >= 0 {
+		return rest[:j]
+	}
+	return ""
+}
+
+// ntpRefID renders the reference ID, an ASCII clock name at stratum 1 and
+// the upstream server address below it.
+func ntpRefID(stratum byte, id []byte) string {
+	if stratum <= 1 {
+		return strings.TrimRight(string(id), "\x00")
+	}
+	return net.IP(id).String()
+}
+
+// snmpSysDescrRequest builds an SNMPv2c GetRequest for sysDescr.0 using the
+// "public" community.
+func snmpSysDescrRequest() [][]byte {
+	sysDescr := []byte{0x2b, 6, 1, 2, 1, 1, 1, 0} // 1.3.6.1.2.1.1.1.0
+	varbind := berTLV(0x30, berTLV(0x06, sysDescr), berTLV(0x05))
+	pdu := berTLV(0xa0,
+		berTLV(0x02, []byte{byte(rand.IntN(0x7f) + 1)}), // request-id
+		berTLV(0x02, []byte{0}),                         // error-status
+		berTLV(0x02, []byte{0}),                         // error-index
+		berTLV(0x30, varbind),
+	)
+	msg := berTLV(0x30,
+		berTLV(0x02, []byte{1}), // version 2c
+		berTLV(0x04, []byte("public")),
+		pdu,
+	)
+	return [][]byte{msg}
+}
+
+func parseSNMPSysDescr(replies [][]byte) string {
+	// message > response PDU > varbind list > varbind > value
+	message, ok := berChildren(replies[0], 0x30)
+	if !ok || len(message) < 3 {
+		return "SNMP"
+	}
+	pdu, ok := berChildren(message[2], 0xa2)
+	if !ok || len(pdu) < 4 {
+		return "SNMP"
+	}
+	varbinds, ok := berChildren(pdu[3], 0x30)
+	if !ok || len(varbinds) == 0 {
+		return "SNMP"
+	}
+	varbind, ok := berChildren(varbinds[0], 0x30)
+	if !ok || len(varbind) < 2 {
+		return "SNMP"
+	}
+	tag, value, _, ok := berRead(varbind[1])
+	if !ok || tag != 0x04 || len(value) == 0 {
+		return "SNMP"
+	}
+	return cleanBanner(value)
+}
+
+// berTLV encodes one BER element with a definite length.
+func berTLV(tag byte, content ...[]byte) []byte {
+	var body []byte
+	for _, c := range content {
+		body = append(body, c...)
+	}
+	out := []byte{tag}
+	switch {
+	case len(body) < 0x80:
+		out = append(out, byte(len(body)))
+	case len(body) <= 0xff:
+		out = append(out, 0x81, byte(len(body)))
+	default:
+		out = append(out, 0x82, byte(len(body)>>8), byte(len(body)))
+	}
+	return append(out, body...)
+}
+
+// berRead splits the first element off b and returns its tag and content.
+func berRead(b []byte) (tag byte, content, rest []byte, ok bool) {
+	if len(b) < 2 {
+		return 0, nil, nil, false
+	}
+	tag = b[0]
+	length := int(b[1])
+	offset := 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 || len(b) < 2+n {
+			return 0, nil, nil, false
+		}
+		length = 0
+		for _, c := range b[2 : 2+n] {
+			length = length<<8 | int(c)
+		}
+		offset += n
+	}
+	if len(b) < offset+length {
+		return 0, nil, nil, false
+	}
+	return tag, b[offset : offset+length], b[offset+length:], true
+}
+
+// berChildren returns the encoded elements inside a constructed element.
+func berChildren(b []byte, wantTag byte) ([][]byte, bool) {
+	tag, content, _, ok := berRead(b)
+	if !ok || tag != wantTag {
+		return nil, false
+	}
+	var children [][]byte
+	for len(content) > 0 {
+		_, _, rest, ok := berRead(content)
+		if !ok {
+			return nil, false
+		}
+		children = append(children, content[:len(content)-len(rest)])
+		content = rest
+	}
+	return children, true
+}
+
+func ssdpSearchRequest() [][]byte {
+	return [][]byte{[]byte("M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: ssdp:all\r\n\r\n")}
+}
+
+func parseSSDP(replies [][]byte) string {
+	return parseHTTPServer(string(replies[0]))
+}
+
+// mdnsServicesRequest asks a host directly for the DNS-SD service types it
+// advertises. Queries from a port other than 5353 get a unicast answer.
+func mdnsServicesRequest() [][]byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("_services._dns-sd._udp.local."),
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET,
+	})
+	msg, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+	return [][]byte{msg}
+}
+
+func parseMDNSServices(replies [][]byte) string {
+	seen := make(map[string]struct{})
+	for _, reply := range replies {
+		var p dnsmessage.Parser
+		if _, err := p.Start(reply); err != nil {
+			continue
+		}
+		if err := p.SkipAllQuestions(); err != nil {
+			continue
+		}
+		for {
+			answer, err := p.Answer()
+			if err != nil {
+				break
+			}
+			if ptr, ok := answer.Body.(*dnsmessage.PTRResource); ok {
+				service := strings.TrimSuffix(ptr.PTR.String(), ".local.")
+				seen[service] = struct{}{}
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return "mDNS"
+	}
+	services := make([]string, 0, len(seen))
+	for service := range seen {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return cleanBanner([]byte("mDNS: " + strings.Join(services, ", ")))
+}
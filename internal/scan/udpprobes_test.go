@@ -0,0 +1,59 @@
+package scan
+
+import (
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseDNSVersion(t *testing.T) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	_ = b.StartAnswers()
+	_ = b.TXTResource(dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName("version.bind."),
+		Class: dnsmessage.ClassCHAOS,
+	}, dnsmessage.TXTResource{TXT: []string{"9.18.24-1-Debian"}})
+	reply, err := b.Finish()
+	if err != nil {
+		t.Fatalf("building reply: %v", err)
+	}
+
+	got := parseDNSVersion([][]byte{reply})
+	if got != "9.18.24-1-Debian" {
+		t.Fatalf("mismatch: got %q", got)
+	}
+}
+
+func TestParseSNMPSysDescr(t *testing.T) {
+	descr := "Linux switch 5.10.0 #1 SMP armv7l"
+	sysDescr := []byte{0x2b, 6, 1, 2, 1, 1, 1, 0}
+	varbind := berTLV(0x30, berTLV(0x06, sysDescr), berTLV(0x04, []byte(descr)))
+	pdu := berTLV(0xa2,
+		berTLV(0x02, []byte{7}),
+		berTLV(0x02, []byte{0}),
+		berTLV(0x02, []byte{0}),
+		berTLV(0x30, varbind),
+	)
+	reply := berTLV(0x30, berTLV(0x02, []byte{1}), berTLV(0x04, []byte("public")), pdu)
+
+	got := parseSNMPSysDescr([][]byte{reply})
+	if got != descr {
+		t.Fatalf("mismatch: got %q want %q", got, descr)
+	}
+}
+
+func TestParseNTPPrefersDaemonVersion(t *testing.T) {
+	client := make([]byte, 48)
+	client[0] = 4<<3 | 4
+	client[1] = 2
+	copy(client[12:], []byte{192, 0, 2, 1})
+	if got := parseNTP([][]byte{client}); got != "NTPv4 stratum 2 ref 192.0.2.1" {
+		t.Fatalf("client reply mismatch: got %q", got)
+	}
+
+	control := append(make([]byte, 12), `version="ntpd 4.2.8p15@1.3728-o", processor="x86_64"`...)
+	control[0] = 2<<3 | 6
+	if got := parseNTP([][]byte{client, control}); got != "ntpd 4.2.8p15@1.3728-o" {
+		t.Fatalf("control reply mismatch: got %q", got)
+	}
+}
@@ -14,3 +14,7 @@ func LookupMAC(ip string) string {
 func Neighbors() []Neighbor {
 	return nil
 }
+
+func Neighbors6() []Neighbor {
+	return nil
+}
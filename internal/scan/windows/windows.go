@@ -71,6 +71,12 @@ func Neighbors() []Neighbor {
 	return out
 }
 
+// Neighbors6 is not backed by the NDP cache on Windows yet; GetIpNetTable only
+// covers IPv4, so IPv6 hosts come from active discovery alone.
+func Neighbors6() []Neighbor {
+	return nil
+}
+
 func readIPNetTable() ([]mibIPNetRow, error) {
 	var size uint32
 	r0, _, _ := procGetIPTable.Call(0, uintptr(unsafe.Pointer(&size)), 0)
@@ -7,13 +7,13 @@ import (
 )
 
 // TotalScanHosts returns the number of IPv4 hosts that will actually be scanned.
+// IPv6 prefixes are never swept, so they report zero.
 func TotalScanHosts(ipnet *net.IPNet) int {
 	ones, bits := ipnet.Mask.Size()
 	hostBits := bits - ones
 
-	// non-ip4 fallback keeps prior behavior.
 	if bits != 32 {
-		return 1 << uint(hostBits)
+		return 0
 	}
 
 	// /32 has one host.
@@ -50,6 +50,31 @@ func FirstIp4(addrs []net.Addr) string {
 	return ""
 }
 
+// FirstIp6 returns the first global IPv6 CIDR string from interface addresses.
+// Link-local addresses are skipped since every interface has one.
+func FirstIp6(addrs []net.Addr) string {
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err != nil {
+			continue
+		}
+		ip := prefix.Addr()
+		if !ip.Is6() || ip.Is4In6() || !ip.IsGlobalUnicast() {
+			continue
+		}
+		return prefix.String()
+	}
+	return ""
+}
+
+// ScanTarget returns the CIDR to scan for an interface, preferring IPv4.
+func ScanTarget(addrs []net.Addr) string {
+	if target := FirstIp4(addrs); target != "" {
+		return target
+	}
+	return FirstIp6(addrs)
+}
+
 func parseAddr(s string) (netip.Addr, bool) {
 	// addresses may be CIDR "192.168.1.10/24"
 	prefix, err := netip.ParsePrefix(s)
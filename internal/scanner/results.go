@@ -2,36 +2,330 @@ package scanner
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// ProtoUDP marks a UDP port, PortInfo.Proto is empty for TCP.
+const ProtoUDP = "udp"
+
 // PortInfo holds a port number and its service banner.
 type PortInfo struct {
-	Port   int
-	Banner string
+	Port    int       `json:"port"`
+	Proto   string    `json:"proto,omitempty"`
+	Banner  string    `json:"banner,omitempty"`
+	Product string    `json:"product,omitempty"` // Identified by a protocol probe, e.g. "MariaDB".
+	Version string    `json:"version,omitempty"`
+	TLS     *TLSInfo  `json:"tls,omitempty"`
+	SSH     *SSHInfo  `json:"ssh,omitempty"`
+	HTTP    *HTTPInfo `json:"http,omitempty"`
+}
+
+// TLSInfo describes the handshake and leaf certificate of a TLS service.
+type TLSInfo struct {
+	Version  string    `json:"version"`
+	Subject  string    `json:"subject,omitempty"` // Certificate common name.
+	SANs     []string  `json:"sans,omitempty"`
+	Issuer   string    `json:"issuer,omitempty"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// Summary renders the TLS details on one line.
+func (t TLSInfo) Summary() string {
+	parts := []string{t.Version}
+	if t.Subject != "" {
+		parts = append(parts, "cn "+t.Subject)
+	}
+	if len(t.SANs) > 0 {
+		parts = append(parts, "san "+strings.Join(t.SANs, ","))
+	}
+	if t.Issuer != "" {
+		parts = append(parts, "issuer "+t.Issuer)
+	}
+	if !t.NotAfter.IsZero() {
+		expiry := "expires " + t.NotAfter.Format("2006-01-02")
+		if time.Now().After(t.NotAfter) {
+			expiry = "expired " + t.NotAfter.Format("2006-01-02")
+		}
+		parts = append(parts, expiry)
+	}
+	return strings.Join(parts, " • ")
+}
+
+// SSHInfo holds what an SSH server offered during key exchange.
+type SSHInfo struct {
+	HostKeyType string   `json:"host_key_type,omitempty"`
+	Fingerprint string   `json:"fingerprint,omitempty"` // SHA256 of the host key, as ssh-keygen -l shows it.
+	Kex         []string `json:"kex"`
+	HostKeys    []string `json:"host_keys"`
+	Ciphers     []string `json:"ciphers"`
+	MACs        []string `json:"macs"`
+	Weak        []string `json:"weak,omitempty"` // Offered algorithms that are broken or deprecated.
+}
+
+// Summary renders the host key and any weak algorithms on one line.
+func (s SSHInfo) Summary() string {
+	var parts []string
+	if s.Fingerprint != "" {
+		parts = append(parts, s.HostKeyType+" "+s.Fingerprint)
+	}
+	if len(s.Weak) > 0 {
+		parts = append(parts, "weak "+strings.Join(s.Weak, ","))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// HTTPInfo describes the root page of a web server.
+type HTTPInfo struct {
+	Status      int      `json:"status"`
+	Title       string   `json:"title,omitempty"`
+	Server      string   `json:"server,omitempty"`
+	PoweredBy   string   `json:"powered_by,omitempty"`
+	Realm       string   `json:"realm,omitempty"` // Basic or Digest auth realm.
+	Redirects   []string `json:"redirects,omitempty"`
+	FaviconHash int32    `json:"favicon_mmh3,omitempty"` // Shodan-style http.favicon.hash.
+}
+
+// Summary renders the page details on one line.
+func (h HTTPInfo) Summary() string {
+	parts := []string{fmt.Sprintf("http %d", h.Status)}
+	if h.Title != "" {
+		parts = append(parts, fmt.Sprintf("%q", h.Title))
+	}
+	if h.Realm != "" {
+		parts = append(parts, fmt.Sprintf("realm %q", h.Realm))
+	}
+	if h.PoweredBy != "" {
+		parts = append(parts, h.PoweredBy)
+	}
+	if len(h.Redirects) > 0 {
+		parts = append(parts, "-> "+h.Redirects[len(h.Redirects)-1])
+	}
+	if h.FaviconHash != 0 {
+		parts = append(parts, fmt.Sprintf("favicon %d", h.FaviconHash))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// Label renders the port number, with a /udp suffix for UDP ports.
+func (p PortInfo) Label() string {
+	if p.Proto == ProtoUDP {
+		return fmt.Sprintf("%d/udp", p.Port)
+	}
+	return fmt.Sprintf("%d", p.Port)
+}
+
+// Device is the inferred kind of host and its likely operating system.
+type Device struct {
+	Type  string `json:"type,omitempty"` // Category key such as "camera" or "printer".
+	Label string `json:"label,omitempty"`
+	Icon  string `json:"icon,omitempty"`
+	OS    string `json:"os,omitempty"`
+}
+
+// Summary renders the label and OS, e.g. "Camera, Embedded Linux".
+func (d Device) Summary() string {
+	var parts []string
+	for _, part := range []string{d.Label, d.OS} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MDNSService is a DNS-SD service instance a host advertises over mDNS.
+type MDNSService struct {
+	Instance     string            `json:"instance"`        // e.g. "Living Room TV"
+	Type         string            `json:"type"`            // e.g. "_googlecast._tcp"
+	Host         string            `json:"host,omitempty"`  // SRV target, e.g. "living-room.local"
+	Port         int               `json:"port,omitempty"`  // SRV port
+	Model        string            `json:"model,omitempty"` // From TXT keys such as md, model or ty.
+	FriendlyName string            `json:"friendly_name,omitempty"`
+	TXT          map[string]string `json:"txt,omitempty"`
+}
+
+// Summary renders the service as "type:port: name, model".
+func (m MDNSService) Summary() string {
+	head := m.Type
+	if m.Port > 0 {
+		head += ":" + strconv.Itoa(m.Port)
+	}
+	name := m.FriendlyName
+	if name == "" {
+		name = m.Instance
+	}
+	var parts []string
+	for _, part := range []string{name, m.Model} {
+		if part != "" && !slices.Contains(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return head
+	}
+	return head + ": " + strings.Join(parts, ", ")
+}
+
+// UPnPDevice is what a device says about itself in its SSDP reply and UPnP
+// description XML.
+type UPnPDevice struct {
+	FriendlyName string `json:"friendly_name,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	ModelName    string `json:"model_name,omitempty"`
+	ModelNumber  string `json:"model_number,omitempty"`
+	DeviceType   string `json:"device_type,omitempty"` // e.g. "urn:schemas-upnp-org:device:MediaRenderer:1"
+	Server       string `json:"server,omitempty"`      // SERVER header of the SSDP reply.
+	Location     string `json:"location,omitempty"`    // URL of the description XML.
+}
+
+// Summary renders the name and model, e.g. "Living Room TV, Samsung QE55Q80T".
+func (u UPnPDevice) Summary() string {
+	var model []string
+	for _, part := range []string{u.Manufacturer, u.ModelName, u.ModelNumber} {
+		if part != "" && !slices.Contains(model, part) {
+			model = append(model, part)
+		}
+	}
+	var parts []string
+	for _, part := range []string{u.FriendlyName, strings.Join(model, " ")} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return u.Server
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Sources of host names.
+const (
+	NameDNS     = "dns"
+	NameMDNS    = "mdns"
+	NameNetBIOS = "netbios"
+	NameDHCP    = "dhcp"  // Host name option of a DHCP request, seen passively.
+	NameLLMNR   = "llmnr" // LLMNR answer a host gave for itself, seen passively.
+)
+
+// HostName is a name a host answered to and the service it came from.
+type HostName struct {
+	Name   string `json:"name"`
+	Source string `json:"source"` // One of the Name* sources.
 }
 
 // HostResult holds all scan info for a single host.
 type HostResult struct {
-	IP       string
-	Hardware string
-	Ports    []PortInfo
+	IP       string        `json:"ip"`
+	Name     string        `json:"name,omitempty"`  // Preferred host name, when one was discovered.
+	Names    []HostName    `json:"names,omitempty"` // Every name found, in order of preference.
+	MAC      string        `json:"mac,omitempty"`
+	Hardware string        `json:"vendor,omitempty"`
+	Device   *Device       `json:"device,omitempty"`
+	RTT      time.Duration `json:"rtt_ns,omitempty"` // ICMP echo round trip, zero when not pinged.
+	TTL      int           `json:"ttl,omitempty"`    // TTL of the echo reply, zero when unknown.
+	Ports    []PortInfo    `json:"ports,omitempty"`
+	MDNS     []MDNSService `json:"mdns,omitempty"`    // Services advertised over mDNS/DNS-SD.
+	UPnP     *UPnPDevice   `json:"upnp,omitempty"`    // Description of a device answering SSDP.
+	Label    *Label        `json:"label,omitempty"`   // What the user noted about the device in the inventory.
+	Unknown  bool          `json:"unknown,omitempty"` // Not in the device inventory, only set once it has devices.
+}
+
+// Label is what the user noted about a device in the inventory.
+type Label struct {
+	Name  string   `json:"name,omitempty"` // Shown before the MAC vendor.
+	Owner string   `json:"owner,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Notes string   `json:"notes,omitempty"`
+}
+
+// UpsertHost appends a new host, or replaces the entry with the same IP when
+// a scan sends a host again with more detail, as passive listening does.
+func UpsertHost(hosts []HostResult, host HostResult) []HostResult {
+	for i, h := range hosts {
+		if h.IP == host.IP {
+			hosts[i] = host
+			return hosts
+		}
+	}
+	return append(hosts, host)
 }
 
 // FormatHost renders a HostResult into the display string.
 func FormatHost(h HostResult) string {
 	var lines []string
-	if h.Hardware != "" {
-		lines = append(lines, fmt.Sprintf("%s - %s", h.IP, h.Hardware))
-	} else {
-		lines = append(lines, h.IP)
+	first := h.IP
+	if h.Device != nil && h.Device.Icon != "" {
+		first = h.Device.Icon + " " + first
+	}
+	if h.Name != "" {
+		first += " " + h.Name
+	}
+	if vendor := hostVendor(h); vendor != "" {
+		first = fmt.Sprintf("%s - %s", first, vendor)
+	}
+	if h.RTT > 0 {
+		first += fmt.Sprintf(" (%.1fms)", float64(h.RTT)/float64(time.Millisecond))
+	}
+	if h.Device != nil {
+		if kind := h.Device.Summary(); kind != "" {
+			first += " [" + kind + "]"
+		}
+	}
+	if h.Unknown {
+		first += " ⚠ unknown"
+	}
+	lines = append(lines, first)
+	if l := h.Label; l != nil {
+		if l.Owner != "" {
+			lines = append(lines, "owner "+l.Owner)
+		}
+		if len(l.Tags) > 0 {
+			lines = append(lines, "tags "+strings.Join(l.Tags, ", "))
+		}
+		if l.Notes != "" {
+			lines = append(lines, "notes "+l.Notes)
+		}
 	}
 	for _, p := range h.Ports {
 		if p.Banner != "" {
-			lines = append(lines, fmt.Sprintf("port %d: %s", p.Port, p.Banner))
+			lines = append(lines, fmt.Sprintf("port %s: %s", p.Label(), p.Banner))
 		} else {
-			lines = append(lines, fmt.Sprintf("port %d", p.Port))
+			lines = append(lines, fmt.Sprintf("port %s", p.Label()))
+		}
+		if p.TLS != nil {
+			lines = append(lines, "  "+p.TLS.Summary())
+		}
+		if p.HTTP != nil {
+			lines = append(lines, "  "+p.HTTP.Summary())
+		}
+		if p.SSH != nil {
+			if summary := p.SSH.Summary(); summary != "" {
+				lines = append(lines, "  "+summary)
+			}
+		}
+	}
+	for _, m := range h.MDNS {
+		lines = append(lines, "mdns "+m.Summary())
+	}
+	if h.UPnP != nil {
+		if summary := h.UPnP.Summary(); summary != "" {
+			lines = append(lines, "upnp "+summary)
 		}
 	}
 	return strings.Join(lines, "\n")
 }
+
+// hostVendor is the user's label for the device followed by the MAC vendor,
+// or whichever of the two is known.
+func hostVendor(h HostResult) string {
+	if h.Label == nil || h.Label.Name == "" {
+		return h.Hardware
+	}
+	if h.Hardware == "" {
+		return h.Label.Name
+	}
+	return fmt.Sprintf("%s (%s)", h.Label.Name, h.Hardware)
+}
@@ -1,30 +1,40 @@
 package scanner
 
+import "context"
+
+// Discovery phases reported alongside structured host results.
+const (
+	PhaseNeighbor = "neighbor"
+	PhaseSweep    = "sweep"
+)
+
 type ProgressUpdate interface {
 	isProgressUpdate()
 }
 
 // NeighborProgress represents progress during the neighbor discovery phase.
 type NeighborProgress struct {
-	Host       string // Optional host line found during neighbor discovery.
-	TotalHosts int    // Overall total hosts in the subnet sweep.
-	Seen       int    // Neighbors processed so far.
-	Total      int    // Total neighbors to process.
+	Host       *HostResult // Optional host found during neighbor discovery.
+	TotalHosts int         // Overall total hosts in the subnet sweep.
+	Seen       int         // Neighbors processed so far.
+	Total      int         // Total neighbors to process.
 }
 
 func (NeighborProgress) isProgressUpdate() {}
 
 // SweepProgress represents progress during the subnet sweep phase.
 type SweepProgress struct {
-	Host       string // Optional host line found during sweep.
-	TotalHosts int    // Overall total hosts in the subnet sweep.
-	Scanned    int    // Hosts scanned so far in sweep.
-	Total      int    // Total hosts in sweep phase.
+	Host       *HostResult // Optional host found during sweep.
+	TotalHosts int         // Overall total hosts in the subnet sweep.
+	Scanned    int         // Hosts scanned so far in sweep.
+	Total      int         // Total hosts in sweep phase.
 }
 
 func (SweepProgress) isProgressUpdate() {}
 
 // Scanner abstracts network scanning so real and demo modes share the same code path.
+// targets is a target spec such as a CIDR, an address range or a comma list.
+// ScanNetwork closes progressChan when the scan finishes or ctx is cancelled.
 type Scanner interface {
-	ScanNetwork(ifaceName, subnet string, progressChan chan<- ProgressUpdate)
+	ScanNetwork(ctx context.Context, ifaceName, targets string, progressChan chan<- ProgressUpdate)
 }
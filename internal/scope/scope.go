@@ -0,0 +1,216 @@
+// Package scope keeps scans inside agreed limits: hosts that must never be
+// touched and a cap on how many addresses one sweep may cover.
+package scope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/target"
+)
+
+// DefaultMaxHosts allows a /20 without confirmation and stops a /16.
+const DefaultMaxHosts = 4096
+
+// Config is the saved scope, stored next to ports.json.
+type Config struct {
+	Exclude  []string `json:"exclude"`   // IPs, CIDRs and MAC prefixes such as 00:1b:63.
+	MaxHosts int      `json:"max_hosts"` // Sweeps above this need confirmation, 0 uses DefaultMaxHosts.
+}
+
+// Limit returns the configured host cap, falling back to DefaultMaxHosts.
+func (cfg Config) Limit() int {
+	if cfg.MaxHosts <= 0 {
+		return DefaultMaxHosts
+	}
+	return cfg.MaxHosts
+}
+
+func ConfigPath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "nibble", "scope.json"), nil
+}
+
+func LoadConfig() (Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func SaveConfig(cfg Config) error {
+	path, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Rules is a compiled exclude list. A nil *Rules excludes nothing.
+type Rules struct {
+	Prefixes []netip.Prefix
+	MACs     []string // Lowercase colon separated prefixes.
+}
+
+// Compile parses exclude entries. Single IPs become /32 or /128 prefixes and
+// anything made of hex octets is treated as a MAC prefix.
+func Compile(entries []string) (*Rules, error) {
+	rules := &Rules{}
+	var invalid []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			rules.Prefixes = append(rules.Prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.WithZone("").Unmap()
+			rules.Prefixes = append(rules.Prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		if mac, ok := macPrefix(entry); ok {
+			rules.MACs = append(rules.MACs, mac)
+			continue
+		}
+		invalid = append(invalid, entry)
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("invalid excludes: %s", strings.Join(invalid, ","))
+	}
+	return rules, nil
+}
+
+// ExcludesIP reports whether ip is covered by an excluded address or CIDR.
+func (r *Rules) ExcludesIP(ip string) bool {
+	if r == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.WithZone("").Unmap()
+	for _, prefix := range r.Prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludesMAC reports whether mac starts with an excluded prefix.
+func (r *Rules) ExcludesMAC(mac string) bool {
+	if r == nil || mac == "" {
+		return false
+	}
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return false
+	}
+	normalized := hw.String()
+	for _, prefix := range r.MACs {
+		if strings.HasPrefix(normalized, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Excludes reports whether a host must be left alone.
+func (r *Rules) Excludes(ip, mac string) bool {
+	return r.ExcludesIP(ip) || r.ExcludesMAC(mac)
+}
+
+// HasMACs reports whether any MAC prefixes are excluded, so callers can skip
+// cache lookups when there is nothing to match.
+func (r *Rules) HasMACs() bool {
+	return r != nil && len(r.MACs) > 0
+}
+
+// SweepSize returns how many addresses a target spec sweeps once excluded
+// hosts are removed. Large IPv6 prefixes are not swept and count as zero.
+func SweepSize(spec string, rules *Rules) (int, error) {
+	if _, ok := target.Prefix6(spec); ok {
+		return 0, nil
+	}
+	targets, err := target.Parse(spec)
+	if err != nil {
+		return 0, err
+	}
+	if rules != nil {
+		targets = targets.Exclude(rules.Prefixes)
+	}
+	return targets.Count(), nil
+}
+
+// CheckLimit refuses sweeps larger than limit unless the user confirmed them.
+func CheckLimit(hosts, limit int, confirmed bool) error {
+	if confirmed || limit <= 0 || hosts <= limit {
+		return nil
+	}
+	return &LimitError{Hosts: hosts, Limit: limit}
+}
+
+// LimitError reports a sweep that needs confirmation.
+type LimitError struct {
+	Hosts int
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("refusing to sweep %d hosts, the limit is %d", e.Hosts, e.Limit)
+}
+
+// macPrefix normalizes "00:1B:63", "00-1b-63" or "001b63" to "00:1b:63".
+func macPrefix(s string) (string, bool) {
+	clean := strings.NewReplacer(":", "", "-", "", ".", "").Replace(strings.ToLower(s))
+	if len(clean) < 2 || len(clean) > 12 || len(clean)%2 != 0 {
+		return "", false
+	}
+	octets := make([]string, 0, len(clean)/2)
+	for i := 0; i < len(clean); i += 2 {
+		octet := clean[i : i+2]
+		for _, c := range octet {
+			if !strings.ContainsRune("0123456789abcdef", c) {
+				return "", false
+			}
+		}
+		octets = append(octets, octet)
+	}
+	return strings.Join(octets, ":"), true
+}
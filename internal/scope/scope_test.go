@@ -0,0 +1,65 @@
+package scope
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompileExcludes(t *testing.T) {
+	rules, err := Compile([]string{"10.0.0.1", "10.1.0.0/16", "00-1B-63", "fd00::/64", ""})
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+
+	for _, ip := range []string{"10.0.0.1", "10.1.200.3", "fd00::5"} {
+		if !rules.ExcludesIP(ip) {
+			t.Fatalf("expected %s to be excluded", ip)
+		}
+	}
+	if rules.ExcludesIP("10.0.0.2") {
+		t.Fatalf("unexpected exclusion of 10.0.0.2")
+	}
+	if !rules.ExcludesMAC("00:1b:63:84:45:e6") || rules.ExcludesMAC("00:1c:63:84:45:e6") {
+		t.Fatalf("MAC prefix mismatch")
+	}
+}
+
+func TestRejectInvalidExcludes(t *testing.T) {
+	_, err := Compile([]string{"10.0.0.300", "printer.lan"})
+	if err == nil {
+		t.Fatalf("expected Compile error")
+	}
+
+	want := "invalid excludes: 10.0.0.300,printer.lan"
+	if err.Error() != want {
+		t.Fatalf("unexpected error: got %q want %q", err.Error(), want)
+	}
+}
+
+func TestSweepSizeSkipsExcluded(t *testing.T) {
+	rules, err := Compile([]string{"192.168.1.0/28", "192.168.1.100"})
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+
+	got, err := SweepSize("192.168.1.0/24", rules)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if got != 254-15-1 {
+		t.Fatalf("size mismatch: got %d want %d", got, 254-15-1)
+	}
+}
+
+func TestCheckLimit(t *testing.T) {
+	var limitErr *LimitError
+	if err := CheckLimit(65534, DefaultMaxHosts, false); !errors.As(err, &limitErr) {
+		t.Fatalf("expected LimitError, got %v", err)
+	}
+	if err := CheckLimit(65534, DefaultMaxHosts, true); err != nil {
+		t.Fatalf("confirmed sweep refused: %v", err)
+	}
+	if err := CheckLimit(254, DefaultMaxHosts, false); err != nil {
+		t.Fatalf("small sweep refused: %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+// Package target parses scan target specs: CIDRs, address ranges, single
+// addresses, hostnames and @file lists, separated by commas or whitespace.
+package target
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lookupHost resolves hostnames, replaced in tests.
+var lookupHost = net.LookupHost
+
+// Range is an inclusive span of addresses from one family.
+type Range struct {
+	First netip.Addr
+	Last  netip.Addr
+}
+
+// Set is a sorted list of non-overlapping address ranges.
+type Set []Range
+
+// Parse turns a target spec such as "10.0.0.0/24, 10.0.1.5-20 nas.lan @hosts.txt"
+// into a Set. CIDRs skip their network and broadcast addresses like a subnet scan.
+// Zones such as the %eth0 of fe80::1%eth0 are dropped, see Zone.
+func Parse(spec string) (Set, error) {
+	ranges, err := parseTokens(spec, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no targets")
+	}
+	for i := range ranges {
+		ranges[i].First = ranges[i].First.WithZone("")
+		ranges[i].Last = ranges[i].Last.WithZone("")
+	}
+	return normalize(ranges), nil
+}
+
+// Zone returns the interface the addresses in spec are scoped to, as in
+// fe80::1%eth0, or "" when they name none. Link-local targets are dialed
+// through the scanned interface, so a spec may only name that one.
+func Zone(spec string) (string, error) {
+	if _, ok := Prefix6(spec); ok {
+		return "", nil
+	}
+	ranges, err := parseTokens(spec, true)
+	if err != nil {
+		return "", err
+	}
+	zone := ""
+	for _, r := range ranges {
+		switch z := r.First.Zone(); {
+		case z == "" || z == zone:
+		case zone == "":
+			zone = z
+		default:
+			return "", fmt.Errorf("targets scoped to both %s and %s", zone, z)
+		}
+	}
+	return zone, nil
+}
+
+// CheckZone rejects a spec with addresses scoped to another interface than
+// iface.
+func CheckZone(spec, iface string) error {
+	zone, err := Zone(spec)
+	if err != nil {
+		return err
+	}
+	if zone != "" && zone != iface {
+		return fmt.Errorf("targets scoped to %s cannot be scanned on %s", zone, iface)
+	}
+	return nil
+}
+
+// Count returns the number of addresses in the set.
+func (s Set) Count() int {
+	total := 0
+	for _, r := range s {
+		total += r.count()
+	}
+	return total
+}
+
+// Contains reports whether addr is part of the set.
+func (s Set) Contains(addr netip.Addr) bool {
+	addr = addr.WithZone("").Unmap()
+	for _, r := range s {
+		if r.First.Compare(addr) <= 0 && addr.Compare(r.Last) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Each calls fn for every address in order until fn returns false.
+func (s Set) Each(fn func(netip.Addr) bool) {
+	for _, r := range s {
+		for addr := r.First; addr.IsValid() && addr.Compare(r.Last) <= 0; addr = addr.Next() {
+			if !fn(addr) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns the set with only the addresses keep reports true for.
+func (s Set) Filter(keep func(netip.Addr) bool) Set {
+	var out Set
+	s.Each(func(addr netip.Addr) bool {
+		if !keep(addr) {
+			return true
+		}
+		if n := len(out); n > 0 && out[n-1].Last.Next() == addr {
+			out[n-1].Last = addr
+		} else {
+			out = append(out, Range{First: addr, Last: addr})
+		}
+		return true
+	})
+	return out
+}
+
+// First returns the lowest address in the set.
+func (s Set) First() netip.Addr {
+	if len(s) == 0 {
+		return netip.Addr{}
+	}
+	return s[0].First
+}
+
+func (r Range) count() int {
+	if r.First.Is4() {
+		return int(ip4Uint(r.Last)-ip4Uint(r.First)) + 1
+	}
+	a, b := r.First.As16(), r.Last.As16()
+	// IPv6 ranges are capped at a /112, so the low 32 bits hold the span.
+	lo := uint32(b[12])<<24 | uint32(b[13])<<16 | uint32(b[14])<<8 | uint32(b[15])
+	hi := uint32(a[12])<<24 | uint32(a[13])<<16 | uint32(a[14])<<8 | uint32(a[15])
+	return int(lo-hi) + 1
+}
+
+func parseTokens(spec string, allowFiles bool) ([]Range, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	var out []Range
+	var invalid []string
+	for _, field := range fields {
+		if strings.HasPrefix(field, "@") {
+			if !allowFiles {
+				invalid = append(invalid, field)
+				continue
+			}
+			ranges, err := parseFile(field[1:])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ranges...)
+			continue
+		}
+		ranges, err := parseToken(field)
+		if err != nil {
+			invalid = append(invalid, field)
+			continue
+		}
+		out = append(out, ranges...)
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("invalid targets: %s", strings.Join(invalid, ","))
+	}
+	return out, nil
+}
+
+// parseFile reads one or more targets per line, ignoring # comments.
+func parseFile(path string) ([]Range, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return parseTokens(b.String(), false)
+}
+
+func parseToken(token string) ([]Range, error) {
+	if strings.Contains(token, "/") {
+		prefix, err := netip.ParsePrefix(token)
+		if err != nil {
+			return nil, err
+		}
+		r, err := prefixRange(prefix.Masked())
+		if err != nil {
+			return nil, err
+		}
+		return []Range{r}, nil
+	}
+
+	// Ranges go first as the zone of fe80::1%eth0-fe80::5 would otherwise
+	// swallow the end, while fe80::1%br-lan is still a single address.
+	addr, addrErr := netip.ParseAddr(token)
+	if i := strings.LastIndex(token, "-"); i > 0 {
+		if first, err := netip.ParseAddr(token[:i]); err == nil {
+			r, err := addrRange(first.Unmap(), token[i+1:])
+			if err == nil {
+				return []Range{r}, nil
+			}
+			if addrErr != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if addrErr == nil {
+		addr = addr.Unmap()
+		return []Range{{First: addr, Last: addr}}, nil
+	}
+
+	return resolveHost(token)
+}
+
+// prefixRange expands a CIDR, dropping network and broadcast for IPv4 subnets
+// larger than /31 so it matches scanner.TotalScanHosts.
+func prefixRange(prefix netip.Prefix) (Range, error) {
+	first := prefix.Addr()
+	hostBits := first.BitLen() - prefix.Bits()
+	if first.Is6() && hostBits > 16 {
+		// Callers check Prefix6 first and discover large prefixes through NDP.
+		return Range{}, fmt.Errorf("IPv6 prefix too large to sweep")
+	}
+
+	last := lastAddr(prefix)
+	if first.Is4() && hostBits >= 2 {
+		first = first.Next()
+		last = last.Prev()
+	}
+	return Range{First: first, Last: last}, nil
+}
+
+// lastAddr returns the highest address in prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	hostBits := len(b)*8 - prefix.Bits()
+	for i := 0; i < hostBits; i++ {
+		b[len(b)-1-i/8] |= 1 << (i % 8)
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
+
+// addrRange parses the end of "a.b.c.d-e" or "a.b.c.d-a.b.c.e".
+func addrRange(first netip.Addr, end string) (Range, error) {
+	last, err := netip.ParseAddr(end)
+	if err != nil {
+		if !first.Is4() {
+			return Range{}, err
+		}
+		octet, convErr := strconv.Atoi(end)
+		if convErr != nil || octet < 0 || octet > 255 {
+			return Range{}, fmt.Errorf("invalid range end")
+		}
+		b := first.As4()
+		b[3] = byte(octet)
+		last = netip.AddrFrom4(b)
+	}
+	last = last.Unmap().WithZone(first.Zone())
+	if first.BitLen() != last.BitLen() || last.Less(first) {
+		return Range{}, fmt.Errorf("invalid range")
+	}
+	r := Range{First: first, Last: last}
+	if first.Is6() && !within6(r) {
+		return Range{}, fmt.Errorf("IPv6 range too large to sweep")
+	}
+	return r, nil
+}
+
+func resolveHost(name string) ([]Range, error) {
+	addrs, err := lookupHost(name)
+	if err != nil {
+		return nil, err
+	}
+	var out []Range
+	for _, a := range addrs {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			continue
+		}
+		addr = addr.WithZone("").Unmap()
+		out = append(out, Range{First: addr, Last: addr})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", name)
+	}
+	return out, nil
+}
+
+// normalize sorts ranges and merges overlapping or adjacent spans.
+func normalize(ranges []Range) Set {
+	sort.Slice(ranges, func(i, j int) bool {
+		if c := ranges[i].First.Compare(ranges[j].First); c != 0 {
+			return c < 0
+		}
+		return ranges[i].Last.Less(ranges[j].Last)
+	})
+
+	merged := make(Set, 0, len(ranges))
+	for _, curr := range ranges {
+		if len(merged) == 0 {
+			merged = append(merged, curr)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		next := last.Last.Next()
+		if last.First.BitLen() == curr.First.BitLen() && (curr.First.Compare(last.Last) <= 0 || curr.First == next) {
+			if last.Last.Less(curr.Last) {
+				last.Last = curr.Last
+			}
+			continue
+		}
+		merged = append(merged, curr)
+	}
+	return merged
+}
+
+// within6 reports whether an IPv6 range fits in one /112 and can be swept.
+func within6(r Range) bool {
+	a, b := r.First.As16(), r.Last.As16()
+	for i := 0; i < 14; i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func ip4Uint(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// Prefix6 returns spec as an IPv6 prefix when it is a single prefix too large
+// to sweep. Such targets are discovered through NDP and multicast instead.
+func Prefix6(spec string) (netip.Prefix, bool) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(spec))
+	if err != nil || !prefix.Addr().Is6() || prefix.Addr().Is4In6() || prefix.Bits() >= 112 {
+		return netip.Prefix{}, false
+	}
+	return prefix.Masked(), true
+}
+
+// Exclude returns the set without any address covered by prefixes.
+func (s Set) Exclude(prefixes []netip.Prefix) Set {
+	out := s
+	for _, prefix := range prefixes {
+		first := prefix.Masked().Addr()
+		last := lastAddr(prefix)
+		var next Set
+		for _, r := range out {
+			if r.First.BitLen() != first.BitLen() || r.Last.Less(first) || last.Less(r.First) {
+				next = append(next, r)
+				continue
+			}
+			if r.First.Less(first) {
+				next = append(next, Range{First: r.First, Last: first.Prev()})
+			}
+			if last.Less(r.Last) {
+				next = append(next, Range{First: last.Next(), Last: r.Last})
+			}
+		}
+		out = next
+	}
+	return out
+}
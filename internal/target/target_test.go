@@ -0,0 +1,167 @@
+package target
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCIDRSkipsNetworkAndBroadcast(t *testing.T) {
+	got, err := Parse("192.168.1.0/24")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if got.Count() != 254 {
+		t.Fatalf("count mismatch: got %d want 254", got.Count())
+	}
+	if got.Contains(netip.MustParseAddr("192.168.1.0")) || got.Contains(netip.MustParseAddr("192.168.1.255")) {
+		t.Fatalf("network or broadcast address included")
+	}
+}
+
+func TestParseRangesAndLists(t *testing.T) {
+	got, err := Parse("10.0.0.5-10, 10.0.0.8-10.0.0.12 10.0.0.20")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if got.Count() != 9 {
+		t.Fatalf("count mismatch: got %d want 9", got.Count())
+	}
+
+	var addrs []string
+	got.Each(func(addr netip.Addr) bool {
+		addrs = append(addrs, addr.String())
+		return true
+	})
+	if addrs[0] != "10.0.0.5" || addrs[7] != "10.0.0.12" || addrs[8] != "10.0.0.20" {
+		t.Fatalf("unexpected order: %v", addrs)
+	}
+}
+
+func TestParseHostname(t *testing.T) {
+	orig := lookupHost
+	lookupHost = func(name string) ([]string, error) {
+		return []string{"192.0.2.7", "2001:db8::7"}, nil
+	}
+	t.Cleanup(func() { lookupHost = orig })
+
+	got, err := Parse("nas.lan")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if !got.Contains(netip.MustParseAddr("192.0.2.7")) || !got.Contains(netip.MustParseAddr("2001:db8::7")) {
+		t.Fatalf("resolved addresses missing: %v", got)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.txt")
+	content := "# lab\n10.1.0.1\n10.1.0.2-3 # printers\n\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	got, err := Parse("@" + path)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if got.Count() != 3 {
+		t.Fatalf("count mismatch: got %d want 3", got.Count())
+	}
+}
+
+func TestRejectInvalidTargets(t *testing.T) {
+	_, err := Parse("10.0.0.9-3,10.0.0.1-300,fd00::/64")
+	if err == nil {
+		t.Fatalf("expected Parse error")
+	}
+
+	want := "invalid targets: 10.0.0.9-3,10.0.0.1-300,fd00::/64"
+	if err.Error() != want {
+		t.Fatalf("unexpected error: got %q want %q", err.Error(), want)
+	}
+}
+
+func TestPrefix6(t *testing.T) {
+	if _, ok := Prefix6("fd00::2/64"); !ok {
+		t.Fatalf("expected /64 to be discovered through NDP")
+	}
+	if _, ok := Prefix6("fd00::/120"); ok {
+		t.Fatalf("expected /120 to be swept")
+	}
+	if _, ok := Prefix6("10.0.0.0/8"); ok {
+		t.Fatalf("expected IPv4 prefix to be rejected")
+	}
+}
+
+func TestExcludeSplitsRanges(t *testing.T) {
+	got, err := Parse("10.0.0.0/24")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	got = got.Exclude([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.16/28"),
+		netip.MustParsePrefix("10.0.0.254/32"),
+	})
+
+	if got.Count() != 254-16-1 {
+		t.Fatalf("count mismatch: got %d want %d", got.Count(), 254-16-1)
+	}
+	if got.Contains(netip.MustParseAddr("10.0.0.20")) || !got.Contains(netip.MustParseAddr("10.0.0.32")) {
+		t.Fatalf("unexpected membership: %v", got)
+	}
+}
+
+func TestFilterKeepsRanges(t *testing.T) {
+	set, _ := Parse("10.0.0.1-10, 10.0.1.1")
+	odd := set.Filter(func(addr netip.Addr) bool { return addr.As4()[3]%2 == 1 || addr.As4()[3] > 6 })
+	want := "10.0.0.1,10.0.0.3,10.0.0.5,10.0.0.7-10.0.0.10,10.0.1.1"
+	var got []string
+	for _, r := range odd {
+		if r.First == r.Last {
+			got = append(got, r.First.String())
+		} else {
+			got = append(got, r.First.String()+"-"+r.Last.String())
+		}
+	}
+	if strings.Join(got, ",") != want {
+		t.Fatalf("got %s want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestZone(t *testing.T) {
+	set, err := Parse("fe80::1%eth0, fe80::10%eth0-fe80::12, fe80::20%br-lan, 192.168.1.5")
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if !set.Contains(netip.MustParseAddr("fe80::11")) || set.First().Zone() != "" {
+		t.Fatalf("zoned targets parsed as %v", set)
+	}
+
+	tests := []struct {
+		spec, zone string
+		ok         bool
+	}{
+		{"fe80::1%eth0, 192.168.1.5", "eth0", true},
+		{"fe80::1%eth0-fe80::1", "eth0", true},
+		{"fe80::1%br-lan", "br-lan", true},
+		{"fe80::1, 192.168.1.0/24", "", true},
+		{"2001:db8::/64", "", true},
+		{"fe80::1%eth0, fe80::2%wlan0", "", false},
+	}
+	for _, tt := range tests {
+		zone, err := Zone(tt.spec)
+		if zone != tt.zone || (err == nil) != tt.ok {
+			t.Errorf("Zone(%q) = %q, %v", tt.spec, zone, err)
+		}
+	}
+
+	if err := CheckZone("fe80::1%eth0", "eth0"); err != nil {
+		t.Fatalf("same interface: %v", err)
+	}
+	if err := CheckZone("fe80::1%wlan0", "eth0"); err == nil {
+		t.Fatal("accepted a zone naming another interface")
+	}
+}
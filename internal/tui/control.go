@@ -4,14 +4,23 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"time"
 
+	"github.com/backendsystems/nibble/internal/classify"
 	"github.com/backendsystems/nibble/internal/demo"
+	"github.com/backendsystems/nibble/internal/history"
+	"github.com/backendsystems/nibble/internal/inventory"
 	"github.com/backendsystems/nibble/internal/ports"
 	"github.com/backendsystems/nibble/internal/scan"
 	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/scope"
+	confirmview "github.com/backendsystems/nibble/internal/tui/views/confirm"
+	devicesview "github.com/backendsystems/nibble/internal/tui/views/devices"
+	historyview "github.com/backendsystems/nibble/internal/tui/views/history"
 	mainview "github.com/backendsystems/nibble/internal/tui/views/main"
 	portsview "github.com/backendsystems/nibble/internal/tui/views/ports"
 	scanview "github.com/backendsystems/nibble/internal/tui/views/scan"
+	targetsview "github.com/backendsystems/nibble/internal/tui/views/targets"
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
@@ -24,6 +33,10 @@ const (
 	viewMain activeView = iota
 	viewPorts
 	viewScan
+	viewTargets
+	viewConfirm
+	viewHistory
+	viewDevices
 )
 
 type model struct {
@@ -33,27 +46,74 @@ type model struct {
 	main    mainview.Model
 	ports   portsview.Model
 	scan    scanview.Model
+	targets targetsview.Model
+	confirm confirmview.Model
+	history historyview.Model
+	devices devicesview.Model
+	labels  *inventory.Inventory
+	exclude *scope.Rules
+	limit   int
+	pending pendingScan
+}
+
+// pendingScan is a scan waiting for the large sweep confirmation.
+type pendingScan struct {
+	iface    net.Interface
+	addrs    []net.Addr
+	targets  string
+	returnTo activeView
 }
 
 func Run(networkScanner scanner.Scanner, ifaces []net.Interface, addrsByIface map[string][]net.Addr) error {
 	cfg, _ := ports.LoadConfig()
-	pack := cfg.Mode
-	if pack == "" || !ports.IsValidPack(pack) {
-		pack = "default"
-	}
-	if resolvedPorts, err := ports.Resolve(pack, cfg.Custom, ""); err == nil {
+	pack := cfg.Pack()
+	resolvedPorts, err := ports.Resolve(pack, cfg.Custom, "")
+	resolvedUDP, udpErr := ports.ResolveUDP(pack, cfg.Custom, "")
+	if err == nil && udpErr == nil {
 		switch typed := networkScanner.(type) {
 		case *scan.NetScanner:
 			typed.Ports = resolvedPorts
+			typed.UDPPorts = resolvedUDP
 		case *demo.DemoScanner:
 			typed.Ports = resolvedPorts
+			typed.UDPPorts = resolvedUDP
 		}
 	}
 
+	scopeCfg, err := scope.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading scope config: %w", err)
+	}
+	exclude, err := scope.Compile(scopeCfg.Exclude)
+	if err != nil {
+		return err
+	}
+	classifier, err := classify.Load()
+	if err != nil {
+		return err
+	}
+	labels, err := inventory.Load()
+	if err != nil {
+		return fmt.Errorf("loading inventory: %w", err)
+	}
+	passive := false
+	switch typed := networkScanner.(type) {
+	case *scan.NetScanner:
+		typed.Exclude = exclude
+		typed.Classifier = classifier
+		passive = typed.Passive > 0
+	case *demo.DemoScanner:
+		typed.Exclude = exclude
+		typed.Classifier = classifier
+	}
+
 	initialWindowW, initialWindowH, initialCardsPerRow := initialLayoutMetrics()
 
 	initialModel := model{
 		active:  viewMain,
+		labels:  labels,
+		exclude: exclude,
+		limit:   scopeCfg.Limit(),
 		windowW: initialWindowW,
 		windowH: initialWindowH,
 		main: mainview.Model{
@@ -68,12 +128,16 @@ func Run(networkScanner scanner.Scanner, ifaces []net.Interface, addrsByIface ma
 		},
 		scan: scanview.Model{
 			NetworkScan: networkScanner,
+			Passive:     passive,
+			Devices:     labels,
 			Progress: progress.New(
 				progress.WithScaledGradient("#FFD700", "#B8B000"),
 			),
 		},
 	}
 	initialModel.scan = initialModel.scan.SetViewportSize(scanViewWidth(initialModel.windowW), initialModel.windowH)
+	initialModel.history = initialModel.history.SetViewportSize(scanViewWidth(initialModel.windowW), initialModel.windowH)
+	initialModel.devices = initialModel.devices.SetViewportSize(scanViewWidth(initialModel.windowW), initialModel.windowH)
 
 	prog := tea.NewProgram(initialModel)
 	finalModel, err := prog.Run()
@@ -90,9 +154,39 @@ func Run(networkScanner scanner.Scanner, ifaces []net.Interface, addrsByIface ma
 	}
 
 	fmt.Printf("%s\n", scanview.FinalOutput(finalState.scan))
+	if err := saveScan(finalState.scan); err != nil {
+		fmt.Println("Error saving scan history:", err)
+	}
 	return nil
 }
 
+// saveScan records a scan that ran to the end in the history store. Demo
+// scans are not kept, their hosts would show up in diffs against the real
+// network.
+func saveScan(m scanview.Model) error {
+	netScanner, ok := m.NetworkScan.(*scan.NetScanner)
+	if !ok || m.Interrupted {
+		return nil
+	}
+	store, err := history.Open()
+	if err != nil {
+		return err
+	}
+	hosts := m.FinalHosts
+	if len(hosts) == 0 {
+		hosts = m.FoundHosts
+	}
+	return store.Save(&history.Scan{
+		Iface:    m.SelectedIface.Name,
+		Targets:  m.TargetAddr,
+		Ports:    netScanner.Ports,
+		UDPPorts: netScanner.UDPPorts,
+		Started:  m.Started,
+		Finished: time.Now(),
+		Hosts:    hosts,
+	})
+}
+
 func (m model) Init() tea.Cmd {
 	if m.ports.PortPack == "" {
 		m.ports.PortPack = "default"
@@ -114,6 +208,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.windowH = resize.Height
 		m.main.CardsPerRow = mainview.CardsPerRow(resize.Width)
 		m.scan = m.scan.SetViewportSize(scanViewWidth(m.windowW), m.windowH)
+		m.history = m.history.SetViewportSize(scanViewWidth(m.windowW), m.windowH)
+		m.devices = m.devices.SetViewportSize(scanViewWidth(m.windowW), m.windowH)
 		return m, nil
 	}
 
@@ -143,6 +239,69 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.active = viewMain
 		}
 		return m, nil
+	case viewTargets:
+		key, ok := msg.(tea.KeyMsg)
+		if !ok {
+			return m, nil
+		}
+		result := m.targets.Update(key)
+		m.targets = result.Model
+		if result.Quit {
+			return m, tea.Quit
+		}
+		if result.Done {
+			m.active = viewMain
+			return m, nil
+		}
+		if result.StartScan {
+			return m.requestScan(pendingScan{iface: m.targets.Iface, addrs: m.targets.Addrs, targets: m.targets.Targets, returnTo: viewTargets})
+		}
+		return m, nil
+	case viewHistory:
+		key, ok := msg.(tea.KeyMsg)
+		if !ok {
+			return m, nil
+		}
+		result := m.history.Update(key)
+		m.history = result.Model
+		if result.Quit {
+			return m, tea.Quit
+		}
+		if result.Done {
+			m.active = viewMain
+		}
+		return m, nil
+	case viewDevices:
+		key, ok := msg.(tea.KeyMsg)
+		if !ok {
+			return m, nil
+		}
+		result := m.devices.Update(key)
+		m.devices = result.Model
+		if result.Quit {
+			return m, tea.Quit
+		}
+		if result.Done {
+			m.active = viewMain
+		}
+		return m, nil
+	case viewConfirm:
+		key, ok := msg.(tea.KeyMsg)
+		if !ok {
+			return m, nil
+		}
+		result := m.confirm.Update(key)
+		if result.Quit {
+			return m, tea.Quit
+		}
+		if result.Cancel {
+			m.active = m.pending.returnTo
+			return m, nil
+		}
+		if result.Confirm {
+			return m.startScan(m.pending.iface, m.pending.addrs, m.confirm.Hosts, m.pending.targets)
+		}
+		return m, nil
 	case viewMain:
 		key, ok := msg.(tea.KeyMsg)
 		if !ok {
@@ -159,18 +318,45 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.active = viewPorts
 			return m, nil
 		}
+		if result.OpenHistory {
+			scans, err := listScans(m.labels)
+			if err != nil {
+				m.main.ErrorMsg = err.Error()
+				return m, nil
+			}
+			m.main.ErrorMsg = ""
+			m.history = m.history.Open(scans)
+			m.active = viewHistory
+			return m, nil
+		}
+		if result.OpenDevices {
+			scans, err := listScans(m.labels)
+			if err != nil {
+				m.main.ErrorMsg = err.Error()
+				return m, nil
+			}
+			hosts := make([][]scanner.HostResult, len(scans))
+			for i, scan := range scans {
+				hosts[i] = scan.Hosts
+			}
+			m.main.ErrorMsg = ""
+			m.devices = m.devices.Open(hosts, m.labels)
+			m.active = viewDevices
+			return m, nil
+		}
+		if result.OpenTargets {
+			m.targets = m.targets.Open(result.Selection.Iface, result.Selection.Addrs, result.Selection.TargetAddr)
+			m.active = viewTargets
+			return m, nil
+		}
 		if result.StartScan {
 			m.main.ErrorMsg = ""
-			nextScan, cmd := m.scan.Start(
-				result.Selection.Iface,
-				result.Selection.Addrs,
-				result.Selection.TotalHosts,
-				result.Selection.TargetAddr,
-			)
-			nextScan = nextScan.SetViewportSize(scanViewWidth(m.windowW), m.windowH)
-			m.scan = nextScan
-			m.active = viewScan
-			return m, tea.Sequence(exitAltScreenCmd(), cmd)
+			return m.requestScan(pendingScan{
+				iface:    result.Selection.Iface,
+				addrs:    result.Selection.Addrs,
+				targets:  result.Selection.TargetAddr,
+				returnTo: viewMain,
+			})
 		}
 		return m, nil
 	default:
@@ -185,11 +371,85 @@ func (m model) View() string {
 		return scanview.Render(m.scan, maxWidth)
 	case viewPorts:
 		return portsview.Render(m.ports, maxWidth)
+	case viewTargets:
+		return targetsview.Render(m.targets, maxWidth)
+	case viewConfirm:
+		return confirmview.Render(m.confirm, maxWidth)
+	case viewHistory:
+		return historyview.Render(m.history, maxWidth)
+	case viewDevices:
+		return devicesview.Render(m.devices, maxWidth)
 	default:
 		return mainview.Render(m.main, maxWidth)
 	}
 }
 
+// listScans returns the saved scans, newest first, with their hosts
+// labeled from the current inventory.
+func listScans(labels *inventory.Inventory) ([]history.Scan, error) {
+	store, err := history.Open()
+	if err != nil {
+		return nil, err
+	}
+	scans, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, scan := range scans {
+		labels.ApplyAll(scan.Hosts)
+	}
+	return scans, nil
+}
+
+// requestScan sizes the sweep without excluded hosts and asks for
+// confirmation when it is over the saved limit. Passive listening sends
+// nothing, so it has no sweep to size.
+func (m model) requestScan(req pendingScan) (tea.Model, tea.Cmd) {
+	if m.scan.Passive {
+		return m.startScan(req.iface, req.addrs, 0, req.targets)
+	}
+	hosts, err := scope.SweepSize(req.targets, m.exclude)
+	if err != nil {
+		m.main.ErrorMsg = err.Error()
+		m.targets.ErrorMsg = err.Error()
+		return m, nil
+	}
+	if scope.CheckLimit(hosts, m.limit, false) != nil {
+		m.pending = req
+		m.confirm = confirmview.Model{Targets: req.targets, Hosts: hosts, Limit: m.limit}
+		m.active = viewConfirm
+		return m, nil
+	}
+	return m.startScan(req.iface, req.addrs, hosts, req.targets)
+}
+
+// startScan switches to the scan view and leaves the alt screen so results stay in the scrollback.
+func (m model) startScan(iface net.Interface, addrs []net.Addr, totalHosts int, targets string) (tea.Model, tea.Cmd) {
+	m.scan.Previous = previousScan(m.scan.NetworkScan, targets)
+	if m.scan.Previous != nil {
+		m.labels.ApplyAll(m.scan.Previous.Hosts)
+	}
+	nextScan, cmd := m.scan.Start(iface, addrs, totalHosts, targets)
+	nextScan = nextScan.SetViewportSize(scanViewWidth(m.windowW), m.windowH)
+	m.scan = nextScan
+	m.active = viewScan
+	return m, tea.Sequence(exitAltScreenCmd(), cmd)
+}
+
+// previousScan returns the last saved scan of the same targets to mark the
+// new results against. Demo scans are never saved, so they have none.
+func previousScan(networkScanner scanner.Scanner, targets string) *history.Scan {
+	if _, ok := networkScanner.(*scan.NetScanner); !ok {
+		return nil
+	}
+	store, err := history.Open()
+	if err != nil {
+		return nil
+	}
+	previous, _ := store.Previous(targets)
+	return previous
+}
+
 func initialLayoutMetrics() (windowW int, windowH int, cardsPerRow int) {
 	cardsPerRow = 1
 	fd := os.Stdout.Fd()
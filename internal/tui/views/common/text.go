@@ -1,6 +1,10 @@
 package common
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 func WrapWords(s string, maxWidth int) string {
 	if maxWidth <= 0 || len(s) <= maxWidth {
@@ -22,3 +26,15 @@ func WrapWords(s string, maxWidth int) string {
 	}
 	return strings.Join(lines, "\n")
 }
+
+// Truncate shortens s to maxWidth cells, ending it with an ellipsis.
+func Truncate(s string, maxWidth int) string {
+	if maxWidth <= 1 || lipgloss.Width(s) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes)) > maxWidth-1 {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
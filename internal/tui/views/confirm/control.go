@@ -0,0 +1,46 @@
+package confirmview
+
+import tea "github.com/charmbracelet/bubbletea"
+
+const confirmHelpText = "y/enter: scan anyway • n/esc: back • ctrl+c: quit"
+
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionQuit
+	ActionConfirm
+	ActionCancel
+)
+
+type Result struct {
+	Quit    bool
+	Confirm bool
+	Cancel  bool
+}
+
+func HandleKey(key string) Action {
+	switch key {
+	case "ctrl+c", "q":
+		return ActionQuit
+	case "y", "Y", "enter":
+		return ActionConfirm
+	case "n", "N", "esc":
+		return ActionCancel
+	default:
+		return ActionNone
+	}
+}
+
+func (m Model) Update(msg tea.KeyMsg) Result {
+	switch HandleKey(msg.String()) {
+	case ActionQuit:
+		return Result{Quit: true}
+	case ActionConfirm:
+		return Result{Confirm: true}
+	case ActionCancel:
+		return Result{Cancel: true}
+	default:
+		return Result{}
+	}
+}
@@ -0,0 +1,30 @@
+package confirmview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/tui/views/common"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func Render(m Model, maxWidth int) string {
+	var b strings.Builder
+
+	b.WriteString(common.TitleStyle.Render("Large Sweep") + "\n")
+
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+	b.WriteString(warnStyle.Render(fmt.Sprintf("%s covers %d hosts, the limit is %d.", m.Targets, m.Hosts, m.Limit)) + "\n")
+
+	noteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	note := "change max_hosts in scope.json to raise the limit"
+	if path, err := scope.ConfigPath(); err == nil {
+		note = "change max_hosts in " + path + " to raise the limit"
+	}
+	b.WriteString(noteStyle.Render(common.WrapWords(note, maxWidth)) + "\n")
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString("\n" + helpStyle.Render(common.WrapWords(confirmHelpText, maxWidth)))
+	return b.String()
+}
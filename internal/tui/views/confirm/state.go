@@ -0,0 +1,8 @@
+package confirmview
+
+// Model asks before sweeping more hosts than the saved limit allows.
+type Model struct {
+	Targets string
+	Hosts   int
+	Limit   int
+}
@@ -0,0 +1,215 @@
+package devicesview
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/backendsystems/nibble/internal/inventory"
+	"github.com/backendsystems/nibble/internal/scanner"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	listHelpText   = "↑/↓ j/k: select • enter: edit • esc: back • ?: help • q: quit"
+	detailHelpText = "type to edit • tab/↑/↓: field • delete: clear field • enter: save • esc: cancel • ctrl+c: quit"
+	minListHeight  = 3
+)
+
+type Result struct {
+	Model Model
+	Quit  bool
+	Done  bool
+	Saved bool // The inventory changed, labels shown elsewhere are stale.
+}
+
+// Open lists the hosts of the saved scans, newest first and each device
+// once, followed by saved devices none of the scans found.
+func (m Model) Open(scans [][]scanner.HostResult, devices *inventory.Inventory) Model {
+	m.Devices = devices
+	m.Hosts = nil
+	seen := make(map[string]bool)
+	for _, hosts := range scans {
+		for _, host := range hosts {
+			key := inventory.For(host).Key()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			m.Hosts = append(m.Hosts, host)
+		}
+	}
+	m.Found = len(m.Hosts)
+	for _, device := range devices.Devices {
+		if !seen[device.Key()] {
+			m.Hosts = append(m.Hosts, scanner.HostResult{IP: device.IP, MAC: device.MAC})
+		}
+	}
+	devices.ApplyAll(m.Hosts)
+
+	m.Editing = false
+	m.ShowHelp = false
+	m.ErrorMsg = ""
+	if m.Cursor >= len(m.Hosts) {
+		m.Cursor = 0
+	}
+	return m
+}
+
+// SetViewportSize fits the list to the window.
+func (m Model) SetViewportSize(maxWidth, windowHeight int) Model {
+	m.Height = windowHeight - 6 // Title, count, blank line and help.
+	if m.Height < minListHeight {
+		m.Height = minListHeight
+	}
+	return m
+}
+
+func (m Model) Update(msg tea.KeyMsg) Result {
+	if m.Editing {
+		return m.updateDetail(msg)
+	}
+	result := Result{Model: m}
+	if m.ShowHelp {
+		result.Model.ShowHelp = false
+		return result
+	}
+	switch msg.String() {
+	case "ctrl+c", "q":
+		result.Quit = true
+	case "esc":
+		result.Done = true
+	case "?":
+		result.Model.ShowHelp = true
+	case "up", "k":
+		if m.Cursor > 0 {
+			result.Model.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < len(m.Hosts)-1 {
+			result.Model.Cursor++
+		}
+	case "enter":
+		if m.Cursor < len(m.Hosts) {
+			result.Model = m.edit()
+		}
+	}
+	return result
+}
+
+// edit opens the detail view of the selected host with its saved fields.
+func (m Model) edit() Model {
+	device, _ := m.Devices.Find(m.Hosts[m.Cursor])
+	m.Values = [fieldCount]string{device.Label, device.Owner, strings.Join(device.Tags, ", "), device.Notes}
+	m.Editing = true
+	m.ErrorMsg = ""
+	m.Field = FieldLabel
+	m.Pos = len([]rune(m.Values[m.Field]))
+	return m
+}
+
+func (m Model) updateDetail(msg tea.KeyMsg) Result {
+	result := Result{Model: m}
+	value := []rune(m.Values[m.Field])
+	pos := min(max(m.Pos, 0), len(value))
+	switch msg.String() {
+	case "ctrl+c":
+		result.Quit = true
+		return result
+	case "esc":
+		result.Model.Editing = false
+		result.Model.ErrorMsg = ""
+		return result
+	case "enter":
+		return m.save()
+	case "tab", "down":
+		result.Model = m.focus((m.Field + 1) % fieldCount)
+		return result
+	case "shift+tab", "up":
+		result.Model = m.focus((m.Field + fieldCount - 1) % fieldCount)
+		return result
+	case "left":
+		pos = max(pos-1, 0)
+	case "right":
+		pos = min(pos+1, len(value))
+	case "home", "ctrl+a":
+		pos = 0
+	case "end", "ctrl+e":
+		pos = len(value)
+	case "backspace":
+		if pos > 0 {
+			value = append(value[:pos-1], value[pos:]...)
+			pos--
+		}
+	case "delete":
+		value, pos = nil, 0
+	default:
+		if msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace {
+			return result
+		}
+		runes := msg.Runes
+		if msg.Type == tea.KeySpace {
+			runes = []rune{' '}
+		}
+		for _, r := range runes {
+			if !unicode.IsPrint(r) {
+				continue
+			}
+			value = append(value[:pos], append([]rune{r}, value[pos:]...)...)
+			pos++
+		}
+	}
+	result.Model.Values[m.Field] = string(value)
+	result.Model.Pos = pos
+	return result
+}
+
+func (m Model) focus(field int) Model {
+	m.Field = field
+	m.Pos = len([]rune(m.Values[field]))
+	return m
+}
+
+// save stores the fields in the inventory, or forgets the device when they
+// are all empty, and relabels the list.
+func (m Model) save() Result {
+	device := inventory.For(m.Hosts[m.Cursor])
+	device.Label = strings.TrimSpace(m.Values[FieldLabel])
+	device.Owner = strings.TrimSpace(m.Values[FieldOwner])
+	device.Tags = ParseTags(m.Values[FieldTags])
+	device.Notes = strings.TrimSpace(m.Values[FieldNotes])
+
+	previous := append([]inventory.Device(nil), m.Devices.Devices...)
+	m.Devices.Set(device)
+	if err := m.Devices.Save(); err != nil {
+		m.Devices.Devices = previous
+		m.ErrorMsg = err.Error()
+		return Result{Model: m}
+	}
+	m.Devices.ApplyAll(m.Hosts)
+	m.Editing = false
+	m.ErrorMsg = ""
+	return Result{Model: m, Saved: true}
+}
+
+// ParseTags splits a comma separated tag list, dropping empty and repeated
+// tags.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsFold(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package devicesview
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func renderHelpOverlay(view string) string {
+	helpBox := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("226")).
+		Padding(0, 1).
+		Width(56).
+		Foreground(lipgloss.Color("15"))
+
+	helpTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true).
+		Render("Devices")
+
+	iconStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true)
+
+	titleWidth := 54
+	icon := iconStyle.Render("❓")
+	spacer := strings.Repeat(" ", titleWidth-lipgloss.Width(helpTitle)-lipgloss.Width(icon))
+	titleRow := helpTitle + spacer + icon
+
+	helpContent := strings.Join([]string{
+		titleRow,
+		"Label the devices on your network. They are keyed",
+		"by MAC, or by IP for hosts without one.",
+		"• enter: edit label, owner, tags and notes",
+		"• labels show before the MAC vendor in scans,",
+		"  history, diffs, watch and exports",
+		"• ⚠ unknown: not in the inventory yet",
+		"• clear every field to forget a device",
+		"• ↑/↓ j/k: select • esc: back • q: quit",
+		"• saved in inventory.json next to ports.json",
+		"",
+		"any key: close",
+	}, "\n")
+
+	helpOverlay := helpBox.Render(helpContent)
+	return lipgloss.Place(
+		lipgloss.Width(view),
+		lipgloss.Height(view),
+		lipgloss.Center,
+		lipgloss.Top,
+		helpOverlay,
+		lipgloss.WithWhitespaceChars(" "),
+	)
+}
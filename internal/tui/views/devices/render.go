@@ -0,0 +1,151 @@
+package devicesview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/tui/views/common"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var fieldNames = [fieldCount]string{"label", "owner", "tags", "notes"}
+
+func Render(m Model, maxWidth int) string {
+	if m.Editing && m.Cursor < len(m.Hosts) {
+		return renderDetail(m, maxWidth)
+	}
+
+	var b strings.Builder
+	b.WriteString(common.TitleStyle.Render("Devices") + "\n")
+
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	if len(m.Hosts) == 0 {
+		emptyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("239")).Italic(true)
+		b.WriteString(emptyStyle.Render("No devices yet, hosts of completed scans appear here.") + "\n")
+	} else {
+		count := fmt.Sprintf("%d devices", len(m.Hosts))
+		if len(m.Hosts) == 1 {
+			count = "1 device"
+		}
+		b.WriteString(infoStyle.Render(fmt.Sprintf("%s, %d in the inventory", count, len(m.Devices.Devices))) + "\n")
+	}
+
+	// Keep the selection on screen when the list is longer than the window.
+	visible := m.Height
+	if visible < minListHeight {
+		visible = len(m.Hosts)
+	}
+	first := 0
+	if m.Cursor >= visible {
+		first = m.Cursor - visible + 1
+	}
+	rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("250"))
+	unknownStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+	for i := first; i < len(m.Hosts) && i < first+visible; i++ {
+		host := m.Hosts[i]
+		line := "  " + rowText(host)
+		style := rowStyle
+		switch {
+		case i == m.Cursor:
+			line = "▸ " + rowText(host)
+			style = selectedStyle
+		case host.Unknown:
+			style = unknownStyle
+		case i >= m.Found:
+			style = infoStyle
+		}
+		if i >= m.Found {
+			line += " (not in saved scans)"
+		}
+		b.WriteString(style.Render(common.Truncate(line, maxWidth)) + "\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString("\n" + helpStyle.Render(common.WrapWords(listHelpText, maxWidth)))
+
+	view := b.String()
+	if m.ShowHelp {
+		return renderHelpOverlay(view)
+	}
+	return view
+}
+
+// rowText is the first line nibble shows for the host, led by the MAC for
+// saved devices no scan found.
+func rowText(host scanner.HostResult) string {
+	first, _, _ := strings.Cut(scanner.FormatHost(host), "\n")
+	if host.IP == "" {
+		return host.MAC + first
+	}
+	if host.MAC != "" {
+		first += " " + host.MAC
+	}
+	return first
+}
+
+func renderDetail(m Model, maxWidth int) string {
+	host := m.Hosts[m.Cursor]
+	var b strings.Builder
+
+	title := host.IP
+	if title == "" {
+		title = host.MAC
+	}
+	b.WriteString(common.TitleStyle.Render("Device: "+title) + "\n")
+
+	// What the scans found, without the label being edited below.
+	found := host
+	found.Label = nil
+	found.Unknown = false
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	lines := strings.Split(scanner.FormatHost(found), "\n")
+	if host.MAC != "" {
+		lines = append([]string{lines[0], "mac " + host.MAC}, lines[1:]...)
+	}
+	maxLines := max(m.Height-len(fieldNames)-2, 3)
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines-1], fmt.Sprintf("… %d more", len(lines)-maxLines+1))
+	}
+	for _, line := range lines {
+		b.WriteString(infoStyle.Render(common.Truncate(line, maxWidth)) + "\n")
+	}
+	if host.Unknown {
+		unknownStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Bold(true)
+		b.WriteString(unknownStyle.Render("⚠ not in the inventory yet") + "\n")
+	}
+
+	b.WriteString("\n")
+	fieldStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("250"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+	for field, name := range fieldNames {
+		value := m.Values[field]
+		style := fieldStyle
+		if field == m.Field {
+			value = withCursor(value, m.Pos)
+			style = selectedStyle
+		}
+		line := fmt.Sprintf("%-6s %s", name+":", value)
+		b.WriteString(style.Render(common.Truncate(line, maxWidth)) + "\n")
+	}
+	if m.Field == FieldTags {
+		hintStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
+		b.WriteString(hintStyle.Render("  • comma separated, e.g. iot, kitchen") + "\n")
+	}
+
+	if m.ErrorMsg != "" {
+		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+		b.WriteString("\n" + errorStyle.Render("Error: "+m.ErrorMsg) + "\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString("\n" + helpStyle.Render(common.WrapWords(detailHelpText, maxWidth)))
+	return b.String()
+}
+
+func withCursor(s string, pos int) string {
+	runes := []rune(s)
+	pos = min(max(pos, 0), len(runes))
+	return string(runes[:pos]) + "|" + string(runes[pos:])
+}
@@ -0,0 +1,29 @@
+package devicesview
+
+import (
+	"github.com/backendsystems/nibble/internal/inventory"
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+// Fields of the host detail view.
+const (
+	FieldLabel = iota
+	FieldOwner
+	FieldTags
+	FieldNotes
+	fieldCount
+)
+
+type Model struct {
+	ShowHelp bool
+	Hosts    []scanner.HostResult // Hosts of the saved scans, then saved devices none of them found.
+	Found    int                  // How many of Hosts the saved scans found.
+	Cursor   int
+	Editing  bool // Showing the detail view of Hosts[Cursor].
+	Field    int
+	Values   [fieldCount]string
+	Pos      int // Cursor in Values[Field], in runes.
+	ErrorMsg string
+	Height   int // Rows of the list that fit the window.
+	Devices  *inventory.Inventory
+}
@@ -0,0 +1,137 @@
+package historyview
+
+import (
+	"github.com/backendsystems/nibble/internal/history"
+	scanview "github.com/backendsystems/nibble/internal/tui/views/scan"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	listHelpText  = "↑/↓ j/k: select • enter: open • esc: back • ?: help • q: quit"
+	scanHelpText  = "↑/↓ j/k: scroll • esc: back to list • q: quit"
+	minListHeight = 3
+)
+
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionQuit
+	ActionBack
+	ActionCloseHelp
+	ActionOpenHelp
+	ActionMoveUp
+	ActionMoveDown
+	ActionOpen
+)
+
+type Result struct {
+	Model Model
+	Quit  bool
+	Done  bool
+}
+
+func HandleKey(showHelp, opened bool, key string) Action {
+	if showHelp {
+		return ActionCloseHelp
+	}
+
+	switch key {
+	case "ctrl+c", "q":
+		return ActionQuit
+	case "esc":
+		return ActionBack
+	case "?":
+		if opened {
+			return ActionNone
+		}
+		return ActionOpenHelp
+	case "up", "k":
+		return ActionMoveUp
+	case "down", "j":
+		return ActionMoveDown
+	case "enter":
+		return ActionOpen
+	default:
+		return ActionNone
+	}
+}
+
+// Open shows the stored scans, newest first, keeping the selection when
+// the list is reopened.
+func (m Model) Open(scans []history.Scan) Model {
+	m.Scans = scans
+	m.Opened = false
+	m.ShowHelp = false
+	m.ErrorMsg = ""
+	if m.Cursor >= len(scans) {
+		m.Cursor = 0
+	}
+	return m
+}
+
+// SetViewportSize fits the host list of an opened scan to the window.
+func (m Model) SetViewportSize(maxWidth, windowHeight int) Model {
+	height := windowHeight - 6 // Title, scan summary and help line.
+	if height < minListHeight {
+		height = minListHeight
+	}
+	if m.Results.Width == 0 || m.Results.Height == 0 {
+		m.Results = viewport.New(maxWidth, height)
+	} else {
+		m.Results.Width = maxWidth
+		m.Results.Height = height
+	}
+	return m
+}
+
+func (m Model) Update(msg tea.KeyMsg) Result {
+	result := Result{Model: m}
+	action := HandleKey(m.ShowHelp, m.Opened, msg.String())
+	switch action {
+	case ActionQuit:
+		result.Quit = true
+	case ActionCloseHelp:
+		result.Model.ShowHelp = false
+	case ActionOpenHelp:
+		result.Model.ShowHelp = true
+	case ActionBack:
+		if m.Opened {
+			result.Model.Opened = false
+			return result
+		}
+		result.Done = true
+	case ActionOpen:
+		if m.Opened || m.Cursor >= len(m.Scans) {
+			return result
+		}
+		result.Model.Opened = true
+		result.Model.Results.SetContent(renderHosts(m.Scans, m.Cursor))
+		result.Model.Results.GotoTop()
+	case ActionMoveUp, ActionMoveDown:
+		if m.Opened {
+			result.Model.Results, _ = m.Results.Update(msg)
+			return result
+		}
+		if action == ActionMoveUp && m.Cursor > 0 {
+			result.Model.Cursor--
+		}
+		if action == ActionMoveDown && m.Cursor < len(m.Scans)-1 {
+			result.Model.Cursor++
+		}
+	}
+	return result
+}
+
+// renderHosts lists the hosts of scans[i], marked against the scan of the
+// same targets before it when there is one.
+func renderHosts(scans []history.Scan, i int) string {
+	for _, older := range scans[i+1:] {
+		if older.Targets == scans[i].Targets {
+			return scanview.RenderHostDiff(scans[i].Hosts, older.Hosts, true)
+		}
+	}
+	return scanview.RenderHostList(scans[i].Hosts)
+}
@@ -0,0 +1,53 @@
+package historyview
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func renderHelpOverlay(view string) string {
+	helpBox := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("226")).
+		Padding(0, 1).
+		Width(56).
+		Foreground(lipgloss.Color("15"))
+
+	helpTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true).
+		Render("Scan History")
+
+	iconStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true)
+
+	titleWidth := 54
+	icon := iconStyle.Render("❓")
+	spacer := strings.Repeat(" ", titleWidth-lipgloss.Width(helpTitle)-lipgloss.Width(icon))
+	titleRow := helpTitle + spacer + icon
+
+	helpContent := strings.Join([]string{
+		titleRow,
+		"Every completed scan is saved, newest first.",
+		"• enter: show the hosts the scan found, marked",
+		"  + new, ~ changed, - gone since the scan before",
+		"• ↑/↓ j/k: select a scan, or scroll its hosts",
+		"• esc: back • q: quit",
+		"• nibble history lists them from the shell",
+		"• retention: history.json next to ports.json",
+		"",
+		"any key: close",
+	}, "\n")
+
+	helpOverlay := helpBox.Render(helpContent)
+	return lipgloss.Place(
+		lipgloss.Width(view),
+		lipgloss.Height(view),
+		lipgloss.Center,
+		lipgloss.Top,
+		helpOverlay,
+		lipgloss.WithWhitespaceChars(" "),
+	)
+}
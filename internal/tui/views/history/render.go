@@ -0,0 +1,82 @@
+package historyview
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/tui/views/common"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func Render(m Model, maxWidth int) string {
+	if m.Opened && m.Cursor < len(m.Scans) {
+		return renderScan(m, maxWidth)
+	}
+
+	var b strings.Builder
+	b.WriteString(common.TitleStyle.Render("Scan History") + "\n")
+
+	if len(m.Scans) == 0 {
+		emptyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("239")).Italic(true)
+		b.WriteString(emptyStyle.Render("No scans saved yet, completed scans appear here.") + "\n")
+	}
+
+	// Keep the selection on screen when the list is longer than the window.
+	visible := m.Results.Height
+	if visible < minListHeight {
+		visible = len(m.Scans)
+	}
+	first := 0
+	if m.Cursor >= visible {
+		first = m.Cursor - visible + 1
+	}
+	rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("250"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+	for i := first; i < len(m.Scans) && i < first+visible; i++ {
+		line := "  " + m.Scans[i].Summary()
+		style := rowStyle
+		if i == m.Cursor {
+			line = "▸ " + m.Scans[i].Summary()
+			style = selectedStyle
+		}
+		b.WriteString(style.Render(common.Truncate(line, maxWidth)) + "\n")
+	}
+
+	if m.ErrorMsg != "" {
+		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+		b.WriteString("\n" + errorStyle.Render("Error: "+m.ErrorMsg) + "\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString("\n" + helpStyle.Render(common.WrapWords(listHelpText, maxWidth)))
+
+	view := b.String()
+	if m.ShowHelp {
+		return renderHelpOverlay(view)
+	}
+	return view
+}
+
+func renderScan(m Model, maxWidth int) string {
+	scan := m.Scans[m.Cursor]
+	var b strings.Builder
+
+	title := fmt.Sprintf("Scan %s", scan.Started.Local().Format("2006-01-02 15:04"))
+	if scan.Iface != "" {
+		title += ": " + scan.Iface
+	}
+	b.WriteString(common.TitleStyle.Render(title) + "\n")
+
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	info := fmt.Sprintf("Network: %s, took %s", scan.Targets, scan.Finished.Sub(scan.Started).Round(time.Second))
+	b.WriteString(infoStyle.Render(info) + "\n")
+
+	foundStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+	b.WriteString(foundStyle.Render(fmt.Sprintf("%d active:", len(scan.Hosts))) + "\n")
+	b.WriteString(m.Results.View() + "\n")
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString("\n" + helpStyle.Render(common.WrapWords(scanHelpText, maxWidth)))
+	return b.String()
+}
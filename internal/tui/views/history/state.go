@@ -0,0 +1,15 @@
+package historyview
+
+import (
+	"github.com/backendsystems/nibble/internal/history"
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+type Model struct {
+	ShowHelp bool
+	Scans    []history.Scan
+	Cursor   int
+	Opened   bool // Showing the hosts of Scans[Cursor].
+	ErrorMsg string
+	Results  viewport.Model
+}
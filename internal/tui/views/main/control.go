@@ -9,7 +9,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-const selectionHelpText = "←/→/↑/↓ a/d/w/s h/j/k/l • p: ports • ?: help • q: quit"
+const selectionHelpText = "←/→/↑/↓ a/d/w/s h/j/k/l • p: ports • t: targets • r: history • i: devices • ?: help • q: quit"
 
 type Action int
 
@@ -19,6 +19,9 @@ const (
 	ActionCloseHelp
 	ActionOpenHelp
 	ActionOpenPorts
+	ActionOpenTargets
+	ActionOpenHistory
+	ActionOpenDevices
 	ActionMoveLeft
 	ActionMoveRight
 	ActionMoveUp
@@ -34,11 +37,14 @@ type ScanSelection struct {
 }
 
 type UpdateResult struct {
-	Model     Model
-	Quit      bool
-	OpenPorts bool
-	StartScan bool
-	Selection ScanSelection
+	Model       Model
+	Quit        bool
+	OpenPorts   bool
+	OpenTargets bool
+	OpenHistory bool
+	OpenDevices bool
+	StartScan   bool
+	Selection   ScanSelection
 }
 
 func HandleKey(showHelp bool, key string) Action {
@@ -53,6 +59,12 @@ func HandleKey(showHelp bool, key string) Action {
 		return ActionOpenHelp
 	case "p":
 		return ActionOpenPorts
+	case "t":
+		return ActionOpenTargets
+	case "r":
+		return ActionOpenHistory
+	case "i":
+		return ActionOpenDevices
 	case "left", "a", "h":
 		return ActionMoveLeft
 	case "right", "d", "l":
@@ -110,9 +122,9 @@ func ResolveScanSelection(interfaces []net.Interface, cursor int, addrsByIface m
 		return ScanSelection{}, fmt.Errorf("interface %s has no IP addresses", selection.Iface.Name)
 	}
 
-	selection.TargetAddr = scanner.FirstIp4(selection.Addrs)
+	selection.TargetAddr = scanner.ScanTarget(selection.Addrs)
 	if selection.TargetAddr == "" {
-		return ScanSelection{}, fmt.Errorf("interface %s has no valid IPv4 addresses", selection.Iface.Name)
+		return ScanSelection{}, fmt.Errorf("interface %s has no valid IPv4 or IPv6 addresses", selection.Iface.Name)
 	}
 
 	_, ipnet, _ := net.ParseCIDR(selection.TargetAddr)
@@ -124,7 +136,8 @@ func ResolveScanSelection(interfaces []net.Interface, cursor int, addrsByIface m
 func (m Model) Update(msg tea.KeyMsg) UpdateResult {
 	result := UpdateResult{Model: m}
 
-	switch HandleKey(m.ShowHelp, msg.String()) {
+	action := HandleKey(m.ShowHelp, msg.String())
+	switch action {
 	case ActionQuit:
 		result.Quit = true
 	case ActionCloseHelp:
@@ -133,6 +146,10 @@ func (m Model) Update(msg tea.KeyMsg) UpdateResult {
 		result.Model.ShowHelp = true
 	case ActionOpenPorts:
 		result.OpenPorts = true
+	case ActionOpenHistory:
+		result.OpenHistory = true
+	case ActionOpenDevices:
+		result.OpenDevices = true
 	case ActionMoveLeft:
 		result.Model.Cursor = MoveCursorLeft(result.Model.Cursor)
 	case ActionMoveRight:
@@ -141,7 +158,7 @@ func (m Model) Update(msg tea.KeyMsg) UpdateResult {
 		result.Model.Cursor = MoveCursorUp(result.Model.Cursor, result.Model.CardsPerRow)
 	case ActionMoveDown:
 		result.Model.Cursor = MoveCursorDown(result.Model.Cursor, result.Model.CardsPerRow, len(result.Model.Interfaces)-1)
-	case ActionStartScan:
+	case ActionOpenTargets, ActionStartScan:
 		selection, err := ResolveScanSelection(result.Model.Interfaces, result.Model.Cursor, result.Model.InterfaceMap)
 		if err != nil {
 			result.Model.ErrorMsg = err.Error()
@@ -151,7 +168,8 @@ func (m Model) Update(msg tea.KeyMsg) UpdateResult {
 			return result
 		}
 		result.Model.ErrorMsg = ""
-		result.StartScan = true
+		result.OpenTargets = action == ActionOpenTargets
+		result.StartScan = action == ActionStartScan
 		result.Selection = selection
 	}
 
@@ -33,6 +33,9 @@ func renderHelpOverlay(view string) string {
 		"Scans local networks for active hosts.",
 		"• Scans TCP ports",
 		"  • Press p to configure ports",
+		"• Press t to scan custom targets (ranges, lists, hosts)",
+		"• Press r to browse and reopen past scans",
+		"• Press i to label devices, ⚠ marks unknown ones",
 		"• Grabs service banners (SSH, HTTP Server)",
 		"• Identifies hardware via MAC OUI (IEEE)",
 		"",
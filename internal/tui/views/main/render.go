@@ -75,7 +75,7 @@ func renderInterfaceCard(m Model, icons map[string]string, index int, iface net.
 	}
 	cardContent.WriteString(nameStyle.Render(icon+" "+name) + "\n")
 
-	addrs := ipv4Labels(m.InterfaceMap, name)
+	addrs := addrLabels(m.InterfaceMap, name)
 	addrStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	if len(addrs) > 0 {
 		cardContent.WriteString(addrStyle.Render(addrs[0]))
@@ -84,14 +84,22 @@ func renderInterfaceCard(m Model, icons map[string]string, index int, iface net.
 	return style.Render(cardContent.String())
 }
 
-// ipv4Labels returns IPv4 labels for an interface
-func ipv4Labels(addrsByIface map[string][]net.Addr, name string) []string {
+// addrLabels returns IPv4 labels for an interface, followed by global IPv6 labels
+func addrLabels(addrsByIface map[string][]net.Addr, name string) []string {
 	labels := make([]string, 0)
+	var ip6Labels []string
 	for _, addr := range addrsByIface[name] {
-		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
-			ones, _ := ipnet.Mask.Size()
-			labels = append(labels, fmt.Sprintf("%s/%d", ipnet.IP.String(), ones))
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ones, _ := ipnet.Mask.Size()
+		label := fmt.Sprintf("%s/%d", ipnet.IP.String(), ones)
+		if ipnet.IP.To4() != nil {
+			labels = append(labels, label)
+		} else if ipnet.IP.IsGlobalUnicast() {
+			ip6Labels = append(ip6Labels, label)
 		}
 	}
-	return labels
+	return append(labels, ip6Labels...)
 }
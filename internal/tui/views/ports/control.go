@@ -10,7 +10,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-const portsHelpText = "tab • ←/→ a/d h/l • type • backspace: remove • delete: clear all • enter • ?: help • q: quit"
+const portsHelpText = "tab • ←/→ a/d h/l • type (u:53 for UDP) • backspace: remove • delete: clear all • enter • ?: help • q: quit"
 
 type Action struct {
 	Handled    bool
@@ -115,7 +115,10 @@ func InsertRunes(value string, cursor int, runes []rune) (string, int) {
 			cursor++
 			continue
 		}
-		if r == ',' {
+		if r == ',' || r == 'u' || r == ':' {
+			if r != ',' && !canInsertUDPMarker(value, cursor, r) {
+				continue
+			}
 			s := string(r)
 			value = value[:cursor] + s + value[cursor:]
 			cursor++
@@ -124,10 +127,27 @@ func InsertRunes(value string, cursor int, runes []rune) (string, int) {
 	return value, cursor
 }
 
+// canInsertUDPMarker allows "u:" only at the start of a token.
+func canInsertUDPMarker(s string, cursor int, ch rune) bool {
+	start, end := currentTokenBounds(s, cursor)
+	pos := cursor - start
+	token := s[start:end]
+	if ch == 'u' {
+		return pos == 0 && !strings.HasPrefix(token, "u")
+	}
+	return pos == 1 && token == "u"
+}
+
 func canInsertPortChar(s string, cursor int, ch rune) bool {
 	start, end := currentTokenBounds(s, cursor)
 	pos := cursor - start
 	token := s[start:end]
+	if strings.HasPrefix(token, ports.UDPPrefix) {
+		if pos < len(ports.UDPPrefix) {
+			return false
+		}
+		token, pos = token[len(ports.UDPPrefix):], pos-len(ports.UDPPrefix)
+	}
 	next := token[:pos] + string(ch) + token[pos:]
 
 	if strings.Count(next, "-") > 1 {
@@ -183,6 +203,11 @@ func applyConfig(m Model) (Model, bool) {
 		m.ErrorMsg = err.Error()
 		return m, false
 	}
+	resolvedUDP, err := ports.ResolveUDP(m.PortPack, addPorts, "")
+	if err != nil {
+		m.ErrorMsg = err.Error()
+		return m, false
+	}
 	if err := ports.SaveConfig(ports.Config{Mode: m.PortPack, Custom: addPorts}); err != nil {
 		m.ErrorMsg = err.Error()
 		return m, false
@@ -190,8 +215,10 @@ func applyConfig(m Model) (Model, bool) {
 	switch typed := m.NetworkScan.(type) {
 	case *scan.NetScanner:
 		typed.Ports = resolvedPorts
+		typed.UDPPorts = resolvedUDP
 	case *demo.DemoScanner:
 		typed.Ports = resolvedPorts
+		typed.UDPPorts = resolvedUDP
 	}
 	m.ErrorMsg = ""
 	return m, true
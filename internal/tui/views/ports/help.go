@@ -34,6 +34,7 @@ func renderHelpOverlay(view string) string {
 		"• tab: switch default/custom mode",
 		"• ←/→ or a/d or h/l: move cursor in custom list",
 		"• type digits, commas, and ranges (e.g. 8000-9000)",
+		"• prefix u: for UDP ports (e.g. u:53,u:161)",
 		"• backspace: remove",
 		"• delete: clear all",
 		"• q: quit",
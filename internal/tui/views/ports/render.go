@@ -22,7 +22,11 @@ func Render(m Model, maxWidth int) string {
 		customStyle = customStyle.Foreground(lipgloss.Color("226")).Bold(true)
 	}
 
-	defaultLine := wrapPortList("default: ", formatPortList(ports.DefaultPorts()), maxWidth)
+	defaultList := formatPortList(ports.DefaultPorts())
+	if udp := ports.DefaultUDPPorts(); len(udp) > 0 {
+		defaultList += "," + ports.UDPPrefix + strings.ReplaceAll(formatPortList(udp), ",", ","+ports.UDPPrefix)
+	}
+	defaultLine := wrapPortList("default: ", defaultList, maxWidth)
 	b.WriteString(defaultStyle.Render(defaultLine) + "\n")
 	customContent := m.CustomPorts
 	if m.PortPack == "custom" {
@@ -36,7 +40,7 @@ func Render(m Model, maxWidth int) string {
 		b.WriteString(customStyle.Render(customLine) + "\n")
 	}
 	if m.PortPack == "custom" && strings.TrimSpace(m.CustomPorts) == "" {
-		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true).Render("  • enter ports e.g. 22,80,443,8000-9000 or empty = hosts only scan") + "\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true).Render("  • enter ports e.g. 22,80,443,8000-9000,u:53 or empty = hosts only scan") + "\n")
 	}
 
 	if m.PortConfigLoc != "" {
@@ -1,8 +1,9 @@
 package scanview
 
 import (
+	"context"
 	"net"
-	"strings"
+	"time"
 
 	"github.com/backendsystems/nibble/internal/scanner"
 
@@ -11,23 +12,10 @@ import (
 
 const scanHelpText = "j/k or ↑/↓: scroll • q: quit"
 
-// appendIfNew appends host to hosts only if no existing entry has the same IP.
-func appendIfNew(hosts []string, host string) []string {
-	newIP := hostIP(host)
-	for _, h := range hosts {
-		if hostIP(h) == newIP {
-			return hosts
-		}
-	}
-	return append(hosts, host)
-}
-
-// hostIP extracts the IP address from the first line of a host string.
-// Host format is "IP - vendor" or just "IP".
-func hostIP(host string) string {
-	line, _, _ := strings.Cut(host, "\n")
-	ip, _, _ := strings.Cut(line, " - ")
-	return strings.TrimSpace(ip)
+// label returns the host with what the device inventory notes about it.
+func (m Model) label(host scanner.HostResult) scanner.HostResult {
+	m.Devices.Apply(&host)
+	return host
 }
 
 type Action int
@@ -75,9 +63,9 @@ func ListenForProgress(progressChan <-chan scanner.ProgressUpdate) tea.Cmd {
 	}
 }
 
-func PerformScan(networkScanner scanner.Scanner, ifaceName, targetAddr string, progressChan chan scanner.ProgressUpdate) tea.Cmd {
+func PerformScan(ctx context.Context, networkScanner scanner.Scanner, ifaceName, targetAddr string, progressChan chan scanner.ProgressUpdate) tea.Cmd {
 	return func() tea.Msg {
-		go networkScanner.ScanNetwork(ifaceName, targetAddr, progressChan)
+		go networkScanner.ScanNetwork(ctx, ifaceName, targetAddr, progressChan)
 		return ListenForProgress(progressChan)()
 	}
 }
@@ -85,9 +73,12 @@ func PerformScan(networkScanner scanner.Scanner, ifaceName, targetAddr string, p
 func (m Model) Start(iface net.Interface, addrs []net.Addr, totalHosts int, targetAddr string) (Model, tea.Cmd) {
 	m.SelectedIface = iface
 	m.SelectedAddrs = addrs
+	m.TargetAddr = targetAddr
 	m.TotalHosts = totalHosts
 	m.Scanning = true
 	m.ScanComplete = false
+	m.Interrupted = false
+	m.Started = time.Now()
 	m.ShouldPrintFinal = false
 	m.FoundHosts = nil
 	m.FinalHosts = nil
@@ -95,8 +86,10 @@ func (m Model) Start(iface net.Interface, addrs []net.Addr, totalHosts int, targ
 	m.NeighborSeen = 0
 	m.NeighborTotal = 0
 	m.ProgressChan = make(chan scanner.ProgressUpdate, 256)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Cancel = cancel
 	m = m.RefreshResults(false)
-	return m, PerformScan(m.NetworkScan, iface.Name, targetAddr, m.ProgressChan)
+	return m, PerformScan(ctx, m.NetworkScan, iface.Name, targetAddr, m.ProgressChan)
 }
 
 func (m Model) Update(msg tea.Msg) Result {
@@ -106,9 +99,11 @@ func (m Model) Update(msg tea.Msg) Result {
 		result.Handled = true
 		switch HandleKey(m.Scanning, m.ScanComplete, typed.String()) {
 		case ActionQuitAndComplete:
+			result.Model = stopScan(result.Model)
 			result.Model = prepareForExit(result.Model, true)
 			result.Model.Scanning = false
 			result.Model.ScanComplete = true
+			result.Model.Interrupted = true
 			result.Cmd = sendQuitMsg()
 			return result
 		case ActionQuit:
@@ -121,7 +116,7 @@ func (m Model) Update(msg tea.Msg) Result {
 		return result
 	case ProgressMsg:
 		result.Handled = true
-		hostAdded := false
+		hostChanged := false
 		switch p := typed.Update.(type) {
 		case scanner.NeighborProgress:
 			if p.TotalHosts > 0 {
@@ -129,29 +124,28 @@ func (m Model) Update(msg tea.Msg) Result {
 			}
 			result.Model.NeighborSeen = p.Seen
 			result.Model.NeighborTotal = p.Total
-			if p.Host != "" {
-				before := len(result.Model.FoundHosts)
-				result.Model.FoundHosts = appendIfNew(result.Model.FoundHosts, p.Host)
-				hostAdded = len(result.Model.FoundHosts) > before
+			if p.Host != nil {
+				result.Model.FoundHosts = scanner.UpsertHost(result.Model.FoundHosts, m.label(*p.Host))
+				hostChanged = true
 			}
 		case scanner.SweepProgress:
 			if p.TotalHosts > 0 {
 				result.Model.TotalHosts = p.TotalHosts
 			}
 			result.Model.ScannedCount = p.Scanned
-			if p.Host != "" {
-				before := len(result.Model.FoundHosts)
-				result.Model.FoundHosts = appendIfNew(result.Model.FoundHosts, p.Host)
-				hostAdded = len(result.Model.FoundHosts) > before
+			if p.Host != nil {
+				result.Model.FoundHosts = scanner.UpsertHost(result.Model.FoundHosts, m.label(*p.Host))
+				hostChanged = true
 			}
 		}
-		if hostAdded {
+		if hostChanged {
 			result.Model = result.Model.RefreshResults(true)
 		}
 		result.Cmd = ListenForProgress(m.ProgressChan)
 		return result
 	case CompleteMsg:
 		result.Handled = true
+		result.Model = stopScan(result.Model)
 		result.Model = prepareForExit(result.Model, true)
 		result.Model.Scanning = false
 		result.Model.ScanComplete = true
@@ -166,6 +160,15 @@ func (m Model) Update(msg tea.Msg) Result {
 	}
 }
 
+// stopScan cancels the running scan so its workers and dials exit promptly.
+func stopScan(m Model) Model {
+	if m.Cancel != nil {
+		m.Cancel()
+		m.Cancel = nil
+	}
+	return m
+}
+
 func sendQuitMsg() tea.Cmd {
 	return func() tea.Msg { return QuitMsg{} }
 }
@@ -175,7 +178,7 @@ func sendQuitMsg() tea.Cmd {
 func prepareForExit(m Model, shouldPrint bool) Model {
 	m.ShouldPrintFinal = shouldPrint
 	if len(m.FinalHosts) == 0 && len(m.FoundHosts) > 0 {
-		m.FinalHosts = append([]string(nil), m.FoundHosts...)
+		m.FinalHosts = append([]scanner.HostResult(nil), m.FoundHosts...)
 	}
 	m.FoundHosts = nil
 	m.Results.SetContent("")
@@ -2,9 +2,9 @@ package scanview
 
 import (
 	"fmt"
-	"net"
 	"strings"
 
+	"github.com/backendsystems/nibble/internal/target"
 	"github.com/backendsystems/nibble/internal/tui/views/common"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -15,22 +15,38 @@ func Render(m Model, maxWidth int) string {
 	b.WriteString(common.TitleStyle.Render(fmt.Sprintf("Scanning: %s", m.SelectedIface.Name)))
 	b.WriteString("\n")
 
-	for _, addr := range m.SelectedAddrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
-			infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-			b.WriteString(infoStyle.Render(fmt.Sprintf("Network: %s", ipnet.String())) + "\n")
-			break
-		}
+	if m.TargetAddr != "" {
+		infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+		b.WriteString(infoStyle.Render(fmt.Sprintf("Network: %s", m.TargetAddr)) + "\n")
+	}
+	if m.Previous != nil {
+		infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+		b.WriteString(infoStyle.Render("Compared to the scan of "+m.Previous.Started.Local().Format("2006-01-02 15:04")) + "\n")
 	}
 
 	statsStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	b.WriteString(statsStyle.Render(fmt.Sprintf("Neighbor discovery %d/%d", m.NeighborSeen, m.NeighborTotal)) + "\n")
-
-	b.WriteString(statsStyle.Render(fmt.Sprintf("Subnet sweep %d/%d", m.ScannedCount, m.TotalHosts)) + "\n")
+	if m.Passive {
+		b.WriteString(statsStyle.Render(fmt.Sprintf("Listening %ds/%ds, %d hosts seen", m.NeighborSeen, m.NeighborTotal, len(m.FoundHosts))) + "\n")
+	} else {
+		b.WriteString(statsStyle.Render(fmt.Sprintf("Neighbor discovery %d/%d", m.NeighborSeen, m.NeighborTotal)) + "\n")
+	}
 
 	sweepPercent := 0.0
-	if m.TotalHosts > 0 {
-		sweepPercent = float64(m.ScannedCount) / float64(m.TotalHosts)
+	if m.Passive {
+		if m.NeighborTotal > 0 {
+			sweepPercent = float64(m.NeighborSeen) / float64(m.NeighborTotal)
+		}
+	} else if _, ipv6 := target.Prefix6(m.TargetAddr); ipv6 {
+		// IPv6 prefixes are not swept, so progress follows neighbor discovery.
+		b.WriteString(statsStyle.Render("Subnet sweep skipped (IPv6)") + "\n")
+		if m.NeighborTotal > 0 {
+			sweepPercent = float64(m.NeighborSeen) / float64(m.NeighborTotal)
+		}
+	} else {
+		b.WriteString(statsStyle.Render(fmt.Sprintf("Subnet sweep %d/%d", m.ScannedCount, m.TotalHosts)) + "\n")
+		if m.TotalHosts > 0 {
+			sweepPercent = float64(m.ScannedCount) / float64(m.TotalHosts)
+		}
 	}
 	progressModel := m.Progress
 	progressModel.Width = 50
@@ -58,9 +74,12 @@ func FinalOutput(m Model) string {
 		hosts = m.FinalHosts
 	}
 	if len(hosts) == 0 {
-		return "No hosts found"
+		if m.Previous == nil || m.Interrupted {
+			return "No hosts found"
+		}
+		return "No hosts found\n" + renderResults(m)
 	}
 
 	foundStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
-	return fmt.Sprintf("%s\n%s", foundStyle.Render(fmt.Sprintf("%d active:", len(hosts))), renderHostList(hosts))
+	return fmt.Sprintf("%s\n%s", foundStyle.Render(fmt.Sprintf("%d active:", len(hosts))), renderResults(m))
 }
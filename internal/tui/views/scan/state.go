@@ -1,8 +1,12 @@
 package scanview
 
 import (
+	"context"
 	"net"
+	"time"
 
+	"github.com/backendsystems/nibble/internal/history"
+	"github.com/backendsystems/nibble/internal/inventory"
 	"github.com/backendsystems/nibble/internal/scanner"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -12,16 +16,23 @@ type Model struct {
 	NetworkScan      scanner.Scanner
 	SelectedIface    net.Interface
 	SelectedAddrs    []net.Addr
+	TargetAddr       string
 	Scanning         bool
 	ScanComplete     bool
+	Interrupted      bool // Stopped by the user before the scanner finished.
+	Started          time.Time
 	ShouldPrintFinal bool
-	FoundHosts       []string
-	FinalHosts       []string
+	FoundHosts       []scanner.HostResult
+	FinalHosts       []scanner.HostResult
 	ScannedCount     int
 	TotalHosts       int
 	NeighborSeen     int
 	NeighborTotal    int
+	Passive          bool                 // Listening only, neighbor progress counts seconds.
+	Previous         *history.Scan        // Last saved scan of the same targets, results are marked against it.
+	Devices          *inventory.Inventory // Labels the hosts found, nil leaves them as they are.
 	ProgressChan     chan scanner.ProgressUpdate
+	Cancel           context.CancelFunc
 	Progress         progress.Model
 	Results          viewport.Model
 }
@@ -1,8 +1,11 @@
 package scanview
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/backendsystems/nibble/internal/diff"
+	"github.com/backendsystems/nibble/internal/scanner"
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -26,6 +29,9 @@ func (m Model) SetViewportSize(maxWidth, windowHeight int) Model {
 		if m.Scanning {
 			reserved = 12
 		}
+		if m.Previous != nil {
+			reserved++ // The line naming the scan results are compared to.
+		}
 		height = windowHeight - reserved
 	}
 	if height < minResultsHeight {
@@ -47,30 +53,93 @@ func (m Model) SetViewportSize(maxWidth, windowHeight int) Model {
 
 func (m Model) RefreshResults(stickToBottom bool) Model {
 	atBottom := m.Results.AtBottom()
-	m.Results.SetContent(renderHostList(m.FoundHosts))
+	m.Results.SetContent(renderResults(m))
 	if stickToBottom && atBottom {
 		m.Results.GotoBottom()
 	}
 	return m
 }
 
-func renderHostList(hosts []string) string {
-	hostStyle := lipgloss.NewStyle().Bold(true)
-	portStyle := lipgloss.NewStyle()
+// renderResults lists the found hosts, marked against the previous scan of
+// the same targets when there is one. Hosts missing from this scan are only
+// listed once it has run to the end.
+func renderResults(m Model) string {
+	hosts := m.FoundHosts
+	if len(m.FinalHosts) > 0 {
+		hosts = m.FinalHosts
+	}
+	if m.Previous == nil {
+		return RenderHostList(hosts)
+	}
+	showGone := m.ScanComplete && !m.Interrupted
+	return RenderHostDiff(hosts, m.Previous.Hosts, showGone)
+}
 
-	var b strings.Builder
-	for i, host := range hosts {
-		lines := strings.Split(host, "\n")
-		b.WriteString(hostStyle.Render("• " + lines[0]))
-		if i < len(hosts)-1 || len(lines) > 1 {
-			b.WriteString("\n")
+// RenderHostList formats hosts for display, one bullet per host with its ports below.
+func RenderHostList(hosts []scanner.HostResult) string {
+	var lines []string
+	for _, host := range hosts {
+		hostLines := strings.Split(scanner.FormatHost(host), "\n")
+		lines = append(lines, hostStyle(host).Render("• "+hostLines[0]))
+		for _, line := range hostLines[1:] {
+			lines = append(lines, "    "+line)
 		}
-		for j, line := range lines[1:] {
-			b.WriteString(portStyle.Render("    " + line))
-			if i < len(hosts)-1 || j < len(lines[1:])-1 {
-				b.WriteString("\n")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RenderHostDiff formats hosts like RenderHostList and marks what changed
+// since the previous scan: + for new hosts, ~ with the changes below for
+// changed ones and, with showGone, the hosts that were not found again.
+func RenderHostDiff(hosts, previous []scanner.HostResult, showGone bool) string {
+	report := diff.Compare(previous, hosts)
+	newIPs := make(map[string]bool, len(report.New))
+	for _, host := range report.New {
+		newIPs[host.IP] = true
+	}
+	changes := make(map[string]diff.HostChange, len(report.Changed))
+	for _, change := range report.Changed {
+		changes[change.Host.IP] = change
+	}
+
+	newStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+	changedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+	changeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	goneStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var lines []string
+	for _, host := range hosts {
+		hostLines := strings.Split(scanner.FormatHost(host), "\n")
+		change, changed := changes[host.IP]
+		switch {
+		case newIPs[host.IP]:
+			lines = append(lines, newStyle.Render("+ "+hostLines[0]+" (new)"))
+		case changed:
+			lines = append(lines, changedStyle.Render("~ "+hostLines[0]))
+			for _, line := range change.Lines() {
+				lines = append(lines, changeStyle.Render("    ! "+line))
 			}
+		default:
+			lines = append(lines, hostStyle(host).Render("• "+hostLines[0]))
+		}
+		for _, line := range hostLines[1:] {
+			lines = append(lines, "    "+line)
+		}
+	}
+	if showGone && len(report.Gone) > 0 {
+		lines = append(lines, goneStyle.Bold(true).Render(fmt.Sprintf("%d gone:", len(report.Gone))))
+		for _, host := range report.Gone {
+			first, _, _ := strings.Cut(scanner.FormatHost(host), "\n")
+			lines = append(lines, goneStyle.Render("- "+first))
 		}
 	}
-	return b.String()
+	return strings.Join(lines, "\n")
+}
+
+// hostStyle highlights hosts missing from the device inventory.
+func hostStyle(host scanner.HostResult) lipgloss.Style {
+	if host.Unknown {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Bold(true)
+	}
+	return lipgloss.NewStyle().Bold(true)
 }
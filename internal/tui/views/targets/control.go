@@ -0,0 +1,149 @@
+package targetsview
+
+import (
+	"net"
+
+	"github.com/backendsystems/nibble/internal/scope"
+	"github.com/backendsystems/nibble/internal/target"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const targetsHelpText = "type targets • ←/→ • backspace: remove • delete: clear all • enter: scan • esc: back • ?: help • ctrl+c: quit"
+
+type Action struct {
+	Handled   bool
+	Quit      bool
+	Back      bool
+	CloseHelp bool
+	OpenHelp  bool
+	Apply     bool
+	MoveLeft  bool
+	MoveRight bool
+	MoveHome  bool
+	MoveEnd   bool
+	Backspace bool
+	DeleteAll bool
+}
+
+type Result struct {
+	Model     Model
+	Quit      bool
+	Done      bool
+	StartScan bool
+}
+
+// HandleKey maps keys to actions. Letters are part of hostnames, so only
+// control keys and ? are bound here.
+func HandleKey(showHelp bool, key string) Action {
+	if showHelp {
+		return Action{Handled: true, CloseHelp: true}
+	}
+
+	switch key {
+	case "ctrl+c":
+		return Action{Handled: true, Quit: true}
+	case "esc":
+		return Action{Handled: true, Back: true}
+	case "?":
+		return Action{Handled: true, OpenHelp: true}
+	case "enter":
+		return Action{Handled: true, Apply: true}
+	case "left":
+		return Action{Handled: true, MoveLeft: true}
+	case "right":
+		return Action{Handled: true, MoveRight: true}
+	case "home", "ctrl+a":
+		return Action{Handled: true, MoveHome: true}
+	case "end", "ctrl+e":
+		return Action{Handled: true, MoveEnd: true}
+	case "backspace":
+		return Action{Handled: true, Backspace: true}
+	case "delete":
+		return Action{Handled: true, DeleteAll: true}
+	default:
+		return Action{}
+	}
+}
+
+// Open prepares the view for an interface, prefilled with its default subnet.
+func (m Model) Open(iface net.Interface, addrs []net.Addr, defaultTargets string) Model {
+	if m.Iface.Name != iface.Name || m.Targets == "" {
+		m.Targets = defaultTargets
+	}
+	m.Iface = iface
+	m.Addrs = addrs
+	m.Cursor = len(m.Targets)
+	m.ShowHelp = false
+	m.ErrorMsg = ""
+	return m
+}
+
+func InsertRunes(value string, cursor int, runes []rune) (string, int) {
+	cursor = clampCursor(cursor, len(value))
+	for _, r := range runes {
+		if r < ' ' || r > '~' {
+			continue
+		}
+		value = value[:cursor] + string(r) + value[cursor:]
+		cursor++
+	}
+	return value, cursor
+}
+
+func clampCursor(cursor, valueLen int) int {
+	if cursor < 0 {
+		return 0
+	}
+	if cursor > valueLen {
+		return valueLen
+	}
+	return cursor
+}
+
+func (m Model) Update(msg tea.KeyMsg) Result {
+	result := Result{Model: m}
+	action := HandleKey(m.ShowHelp, msg.String())
+	switch {
+	case action.Quit:
+		result.Quit = true
+	case action.Back:
+		result.Done = true
+	case action.CloseHelp:
+		result.Model.ShowHelp = false
+	case action.OpenHelp:
+		result.Model.ShowHelp = true
+	case action.Apply:
+		if _, err := scope.SweepSize(result.Model.Targets, nil); err != nil {
+			result.Model.ErrorMsg = err.Error()
+			return result
+		}
+		if err := target.CheckZone(result.Model.Targets, m.Iface.Name); err != nil {
+			result.Model.ErrorMsg = err.Error()
+			return result
+		}
+		result.Model.ErrorMsg = ""
+		result.StartScan = true
+	case action.MoveLeft:
+		result.Model.Cursor = clampCursor(result.Model.Cursor-1, len(result.Model.Targets))
+	case action.MoveRight:
+		result.Model.Cursor = clampCursor(result.Model.Cursor+1, len(result.Model.Targets))
+	case action.MoveHome:
+		result.Model.Cursor = 0
+	case action.MoveEnd:
+		result.Model.Cursor = len(result.Model.Targets)
+	case action.Backspace:
+		if cursor := clampCursor(result.Model.Cursor, len(result.Model.Targets)); cursor > 0 {
+			result.Model.Targets = result.Model.Targets[:cursor-1] + result.Model.Targets[cursor:]
+			result.Model.Cursor = cursor - 1
+		}
+	case action.DeleteAll:
+		result.Model.Targets = ""
+		result.Model.Cursor = 0
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			result.Model.Targets, result.Model.Cursor = InsertRunes(result.Model.Targets, result.Model.Cursor, msg.Runes)
+		}
+	}
+	return result
+}
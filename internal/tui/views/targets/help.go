@@ -0,0 +1,56 @@
+package targetsview
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func renderHelpOverlay(view string) string {
+	helpBox := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("226")).
+		Padding(0, 1).
+		Width(56).
+		Foreground(lipgloss.Color("15"))
+
+	helpTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true).
+		Render("Scan Targets")
+
+	iconStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true)
+
+	titleWidth := 54
+	icon := iconStyle.Render("❓")
+	spacer := strings.Repeat(" ", titleWidth-lipgloss.Width(helpTitle)-lipgloss.Width(icon))
+	titleRow := helpTitle + spacer + icon
+
+	helpContent := strings.Join([]string{
+		titleRow,
+		"Choose what to scan from the selected interface.",
+		"• CIDR: 192.168.1.0/24",
+		"• range: 192.168.1.10-50 or 10.0.0.1-10.0.0.9",
+		"• list: comma or space separated",
+		"• hostname: resolved before the scan",
+		"• @file: targets read from a file, # comments",
+		"• routed targets are scanned without MAC lookup",
+		"• ←/→ home/end: move cursor",
+		"• backspace: remove • delete: clear all",
+		"• enter: scan • esc: back • ctrl+c: quit",
+		"",
+		"any key: close",
+	}, "\n")
+
+	helpOverlay := helpBox.Render(helpContent)
+	return lipgloss.Place(
+		lipgloss.Width(view),
+		lipgloss.Height(view),
+		lipgloss.Center,
+		lipgloss.Top,
+		helpOverlay,
+		lipgloss.WithWhitespaceChars(" "),
+	)
+}
@@ -0,0 +1,43 @@
+package targetsview
+
+import (
+	"strings"
+
+	"github.com/backendsystems/nibble/internal/tui/views/common"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func Render(m Model, maxWidth int) string {
+	var b strings.Builder
+
+	b.WriteString(common.TitleStyle.Render("Scan Targets") + "\n")
+
+	ifaceStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString(ifaceStyle.Render("interface: "+m.Iface.Name) + "\n")
+
+	inputStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+	b.WriteString(inputStyle.Render("targets: "+withCursor(m.Targets, m.Cursor)) + "\n")
+	if strings.TrimSpace(m.Targets) == "" {
+		hintStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
+		b.WriteString(hintStyle.Render("  • e.g. 10.0.0.0/24, 10.0.1.5-20 nas.lan @hosts.txt") + "\n")
+	}
+
+	if m.ErrorMsg != "" {
+		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+		b.WriteString("\n" + errorStyle.Render("Error: "+m.ErrorMsg) + "\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString("\n" + helpStyle.Render(common.WrapWords(targetsHelpText, maxWidth)))
+
+	view := b.String()
+	if m.ShowHelp {
+		return renderHelpOverlay(view)
+	}
+	return view
+}
+
+func withCursor(s string, cursor int) string {
+	cursor = clampCursor(cursor, len(s))
+	return s[:cursor] + "|" + s[cursor:]
+}
@@ -0,0 +1,12 @@
+package targetsview
+
+import "net"
+
+type Model struct {
+	ShowHelp bool
+	Iface    net.Interface
+	Addrs    []net.Addr
+	Targets  string
+	Cursor   int
+	ErrorMsg string
+}
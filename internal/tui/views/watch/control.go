@@ -0,0 +1,134 @@
+package watchview
+
+import (
+	"context"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/watch"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const watchHelpText = "↑/↓ j/k: scroll hosts • ?: help • q: quit"
+
+// eventLines is how many recent events are shown below the host list.
+const eventLines = 8
+
+type RoundMsg struct {
+	Round watch.Round
+}
+
+type DoneMsg struct{}
+
+type TickMsg time.Time
+
+type Result struct {
+	Model Model
+	Quit  bool
+	Cmd   tea.Cmd
+}
+
+// Start runs the watcher in the background and returns the commands that
+// feed its rounds and a once a second tick to the dashboard.
+func Start(w *watch.Watcher) (Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rounds := make(chan watch.Round)
+	go w.Run(ctx, rounds)
+
+	misses := watch.DefaultMisses
+	if w.Inventory != nil && w.Inventory.Misses > 0 {
+		misses = w.Inventory.Misses
+	}
+	m := Model{
+		Iface:    w.Iface,
+		Targets:  w.Targets,
+		Interval: w.Interval,
+		Misses:   misses,
+		Now:      time.Now(),
+		Rounds:   rounds,
+		Cancel:   cancel,
+	}
+	return m, tea.Batch(ListenForRounds(rounds), tick())
+}
+
+func ListenForRounds(rounds <-chan watch.Round) tea.Cmd {
+	return func() tea.Msg {
+		round, ok := <-rounds
+		if !ok {
+			return DoneMsg{}
+		}
+		return RoundMsg{Round: round}
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return TickMsg(t) })
+}
+
+// SetViewportSize leaves room for the header, the recent events and help.
+func (m Model) SetViewportSize(maxWidth, windowHeight int) Model {
+	height := 10
+	if windowHeight > 0 {
+		height = windowHeight - 8 - eventLines
+	}
+	if height < 3 {
+		height = 3
+	}
+	if m.Results.Width == 0 || m.Results.Height == 0 {
+		m.Results = viewport.New(maxWidth, height)
+	} else {
+		m.Results.Width = maxWidth
+		m.Results.Height = height
+	}
+	m.Results.SetContent(renderHosts(m))
+	return m
+}
+
+func (m Model) Update(msg tea.Msg) Result {
+	result := Result{Model: m}
+	switch typed := msg.(type) {
+	case tea.KeyMsg:
+		if m.ShowHelp {
+			result.Model.ShowHelp = false
+			return result
+		}
+		switch typed.String() {
+		case "ctrl+c", "q":
+			if m.Cancel != nil {
+				m.Cancel()
+			}
+			result.Quit = true
+		case "?":
+			result.Model.ShowHelp = true
+		default:
+			result.Model.Results, result.Cmd = m.Results.Update(typed)
+		}
+	case RoundMsg:
+		round := typed.Round
+		result.Model.Scans++
+		result.Model.LastScan = round.Finished
+		result.Model.Next = round.Next
+		result.Model.Hosts = round.Hosts
+		result.Model.ErrorMsg = ""
+		if round.Err != nil {
+			result.Model.ErrorMsg = "Error sending notifications: " + round.Err.Error()
+		}
+		for _, event := range round.Events {
+			if event.Type != watch.EventScan {
+				result.Model.Events = append(result.Model.Events, event)
+			}
+		}
+		if extra := len(result.Model.Events) - maxEvents; extra > 0 {
+			result.Model.Events = result.Model.Events[extra:]
+		}
+		result.Model.Results.SetContent(renderHosts(result.Model))
+		result.Cmd = ListenForRounds(m.Rounds)
+	case TickMsg:
+		result.Model.Now = time.Time(typed)
+		result.Cmd = tick()
+	case DoneMsg:
+		result.Quit = true
+	}
+	return result
+}
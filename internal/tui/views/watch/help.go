@@ -0,0 +1,54 @@
+package watchview
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func renderHelpOverlay(view string) string {
+	helpBox := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("226")).
+		Padding(0, 1).
+		Width(56).
+		Foreground(lipgloss.Color("15"))
+
+	helpTitle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true).
+		Render("Watch")
+
+	iconStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("226")).
+		Bold(true)
+
+	titleWidth := 54
+	icon := iconStyle.Render("❓")
+	spacer := strings.Repeat(" ", titleWidth-lipgloss.Width(helpTitle)-lipgloss.Width(icon))
+	titleRow := helpTitle + spacer + icon
+
+	helpContent := strings.Join([]string{
+		titleRow,
+		"Rescans the network and shows what changed.",
+		"• the first scan is the baseline, no events",
+		"• green: joined, port opened",
+		"• red: left, port closed",
+		"• orange: moved to another IP, banner changed",
+		"• ? hosts were missed by the last scans and",
+		"  only leave after the set number of misses",
+		"• ↑/↓ j/k: scroll hosts • q: quit",
+		"",
+		"any key: close",
+	}, "\n")
+
+	helpOverlay := helpBox.Render(helpContent)
+	return lipgloss.Place(
+		lipgloss.Width(view),
+		lipgloss.Height(view),
+		lipgloss.Center,
+		lipgloss.Top,
+		helpOverlay,
+		lipgloss.WithWhitespaceChars(" "),
+	)
+}
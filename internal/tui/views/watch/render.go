@@ -0,0 +1,108 @@
+package watchview
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+	"github.com/backendsystems/nibble/internal/tui/views/common"
+	"github.com/backendsystems/nibble/internal/watch"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func Render(m Model, maxWidth int) string {
+	var b strings.Builder
+
+	title := "Watching"
+	if m.Iface != "" {
+		title += ": " + m.Iface
+	}
+	b.WriteString(common.TitleStyle.Render(title) + "\n")
+
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString(infoStyle.Render(fmt.Sprintf("Network: %s, every %s, gone after %d missed scans", m.Targets, m.Interval, m.Misses)) + "\n")
+	status := "First scan running..."
+	if m.Scans > 0 {
+		status = fmt.Sprintf("Scan %d done at %s", m.Scans, m.LastScan.Local().Format("15:04:05"))
+		if wait := m.Next.Sub(m.Now).Round(time.Second); wait > 0 {
+			status += fmt.Sprintf(", next in %s", wait)
+		} else {
+			status += ", scanning..."
+		}
+	}
+	b.WriteString(infoStyle.Render(status) + "\n")
+	if m.ErrorMsg != "" {
+		// Joined errors are one per line, the first is enough here.
+		first, _, _ := strings.Cut(m.ErrorMsg, "\n")
+		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+		b.WriteString(errorStyle.Render(common.Truncate(first, maxWidth)) + "\n")
+	}
+
+	foundStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+	b.WriteString(foundStyle.Render(fmt.Sprintf("%d hosts:", len(m.Hosts))) + "\n")
+	b.WriteString(m.Results.View() + "\n")
+
+	b.WriteString(foundStyle.Render("Recent events:") + "\n")
+	events := m.Events
+	if len(events) > eventLines {
+		events = events[len(events)-eventLines:]
+	}
+	if len(events) == 0 {
+		emptyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("239")).Italic(true)
+		b.WriteString(emptyStyle.Render("No changes yet") + "\n")
+	}
+	for _, event := range events {
+		line := event.Time.Local().Format("15:04:05") + " " + event.Summary()
+		b.WriteString(eventStyle(event.Type).Render(common.Truncate(line, maxWidth)) + "\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	b.WriteString("\n" + helpStyle.Render(common.WrapWords(watchHelpText, maxWidth)))
+
+	view := b.String()
+	if m.ShowHelp {
+		return renderHelpOverlay(view)
+	}
+	return view
+}
+
+// renderHosts lists the inventory one host per line, dimming hosts the
+// last scans missed.
+func renderHosts(m Model) string {
+	hostStyle := lipgloss.NewStyle().Bold(true)
+	unknownStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Bold(true)
+	missedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	var lines []string
+	for _, tracked := range m.Hosts {
+		first, _, _ := strings.Cut(scanner.FormatHost(tracked.Host), "\n")
+		ports := make([]string, 0, len(tracked.Host.Ports))
+		for _, p := range tracked.Host.Ports {
+			ports = append(ports, p.Label())
+		}
+		if len(ports) > 0 {
+			first += " • " + strings.Join(ports, ",")
+		}
+		if tracked.Missed > 0 {
+			lines = append(lines, missedStyle.Render(fmt.Sprintf("? %s (missed %d/%d)", first, tracked.Missed, m.Misses)))
+			continue
+		}
+		if tracked.Host.Unknown {
+			lines = append(lines, unknownStyle.Render("• "+first))
+			continue
+		}
+		lines = append(lines, hostStyle.Render("• "+first))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func eventStyle(eventType string) lipgloss.Style {
+	switch eventType {
+	case watch.EventJoined, watch.EventPortOpened:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	case watch.EventLeft, watch.EventPortClosed:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	default:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	}
+}
@@ -0,0 +1,30 @@
+package watchview
+
+import (
+	"context"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/watch"
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// maxEvents is how many recent events the dashboard keeps.
+const maxEvents = 200
+
+type Model struct {
+	ShowHelp bool
+	Iface    string
+	Targets  string
+	Interval time.Duration
+	Misses   int
+	Scans    int // Finished scans.
+	LastScan time.Time
+	Next     time.Time
+	Now      time.Time
+	Hosts    []watch.Tracked
+	Events   []watch.Event // Oldest first, without scan events.
+	ErrorMsg string        // Notifications the last scan could not send.
+	Rounds   <-chan watch.Round
+	Cancel   context.CancelFunc
+	Results  viewport.Model
+}
@@ -0,0 +1,54 @@
+package tui
+
+import (
+	watchview "github.com/backendsystems/nibble/internal/tui/views/watch"
+	"github.com/backendsystems/nibble/internal/watch"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// watchModel shows a running watch as a live dashboard.
+type watchModel struct {
+	windowW int
+	watch   watchview.Model
+	start   tea.Cmd
+}
+
+// Watch runs w until the user quits, showing the inventory and the events
+// as they happen.
+func Watch(w *watch.Watcher) error {
+	dashboard, start := watchview.Start(w)
+	windowW, windowH, _ := initialLayoutMetrics()
+	m := watchModel{
+		windowW: windowW,
+		watch:   dashboard.SetViewportSize(scanViewWidth(windowW), windowH),
+		start:   start,
+	}
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if dashboard.Cancel != nil {
+		dashboard.Cancel()
+	}
+	return err
+}
+
+func (m watchModel) Init() tea.Cmd {
+	return m.start
+}
+
+func (m watchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if resize, ok := msg.(tea.WindowSizeMsg); ok {
+		m.windowW = resize.Width
+		m.watch = m.watch.SetViewportSize(scanViewWidth(m.windowW), resize.Height)
+		return m, nil
+	}
+	result := m.watch.Update(msg)
+	m.watch = result.Model
+	if result.Quit {
+		return m, tea.Quit
+	}
+	return m, result.Cmd
+}
+
+func (m watchModel) View() string {
+	return watchview.Render(m.watch, scanViewWidth(m.windowW))
+}
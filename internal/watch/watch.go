@@ -0,0 +1,347 @@
+// Package watch rescans a network on an interval, keeps the current host
+// inventory and reports what changed between scans as events.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/diff"
+	"github.com/backendsystems/nibble/internal/inventory"
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+// DefaultMisses rides out a host that skips a scan or two, as phones and
+// laptops on Wi-Fi do when they doze.
+const DefaultMisses = 3
+
+const (
+	// NotifyTimeout caps how long the Notifier may take for the events of
+	// one scan.
+	NotifyTimeout = time.Minute
+	// notifyBacklog is how many scans may wait for the Notifier before the
+	// events of new ones are dropped.
+	notifyBacklog = 16
+)
+
+// Event types.
+const (
+	EventScan          = "scan" // A scan finished, Hosts says how many hosts are known.
+	EventJoined        = "host_joined"
+	EventLeft          = "host_left"
+	EventMoved         = "host_moved" // The same MAC answered on another IP.
+	EventPortOpened    = "port_opened"
+	EventPortClosed    = "port_closed"
+	EventBannerChanged = "banner_changed"
+)
+
+// Event is one change to the inventory.
+type Event struct {
+	Type   string              `json:"type"`
+	Time   time.Time           `json:"time"`
+	IP     string              `json:"ip,omitempty"`
+	MAC    string              `json:"mac,omitempty"`
+	Host   *scanner.HostResult `json:"host,omitempty"`   // The host as last seen.
+	Port   *scanner.PortInfo   `json:"port,omitempty"`   // The port that opened, closed or changed.
+	Old    string              `json:"old,omitempty"`    // Previous banner, or IP for host_moved.
+	New    string              `json:"new,omitempty"`    // Current banner, or IP for host_moved.
+	Hosts  int                 `json:"hosts,omitempty"`  // Known hosts, for scan events.
+	Missed int                 `json:"missed,omitempty"` // Scans in a row the host was not found, for host_left.
+}
+
+// Summary describes the event on one line, e.g. "192.168.1.1 opened port 443".
+func (e Event) Summary() string {
+	host := e.IP
+	if e.Host != nil {
+		host, _, _ = strings.Cut(scanner.FormatHost(*e.Host), "\n")
+	}
+	switch e.Type {
+	case EventScan:
+		return fmt.Sprintf("scan done, %d hosts", e.Hosts)
+	case EventJoined:
+		return "joined: " + host
+	case EventLeft:
+		return fmt.Sprintf("left: %s, missed %d scans", host, e.Missed)
+	case EventMoved:
+		return fmt.Sprintf("%s moved from %s to %s", e.MAC, e.Old, e.New)
+	case EventPortOpened:
+		return fmt.Sprintf("%s opened port %s", e.IP, e.Port.Label())
+	case EventPortClosed:
+		return fmt.Sprintf("%s closed port %s", e.IP, e.Port.Label())
+	case EventBannerChanged:
+		return fmt.Sprintf("%s port %s: %q -> %q", e.IP, e.Port.Label(), e.Old, e.New)
+	default:
+		return e.Type + " " + host
+	}
+}
+
+// Tracked is a host in the inventory.
+type Tracked struct {
+	Host   scanner.HostResult
+	Missed int // Scans in a row it was not found in, zero when it was in the last one.
+}
+
+// Inventory is the set of hosts currently on the network. A host is only
+// declared gone after Misses scans in a row without it.
+type Inventory struct {
+	Misses int // Zero uses DefaultMisses.
+	hosts  []Tracked
+	seeded bool
+}
+
+// Hosts returns the hosts currently known: those the last scan found, then
+// the ones it missed.
+func (inv *Inventory) Hosts() []Tracked {
+	return append([]Tracked(nil), inv.hosts...)
+}
+
+// Update merges the hosts of a finished scan and returns what changed. The
+// first scan only seeds the inventory, so starting a watch does not report
+// every host as joined.
+func (inv *Inventory) Update(found []scanner.HostResult, now time.Time) []Event {
+	known := make([]scanner.HostResult, len(inv.hosts))
+	for i, tracked := range inv.hosts {
+		known[i] = tracked.Host
+	}
+	matches := diff.Match(known, found)
+
+	// The hosts found come first, in scan order, followed by the missed
+	// ones that still have chances left. A missed host is told apart by
+	// its place in the inventory, as another device may have its IP now.
+	var events []Event
+	var next []Tracked
+	matched := make([]bool, len(inv.hosts))
+	for j, host := range found {
+		next = append(next, Tracked{Host: host})
+		i := matches[j]
+		if i < 0 {
+			if inv.seeded {
+				events = append(events, Event{Type: EventJoined, Time: now, IP: host.IP, MAC: host.MAC, Host: &host})
+			}
+			continue
+		}
+		matched[i] = true
+		if change, changed := diff.CompareHost(known[i], host); changed && inv.seeded {
+			events = append(events, changeEvents(change, now)...)
+		}
+	}
+	for i, tracked := range inv.hosts {
+		if matched[i] {
+			continue
+		}
+		tracked.Missed++
+		if tracked.Missed < inv.misses() {
+			next = append(next, tracked)
+			continue
+		}
+		host := tracked.Host
+		events = append(events, Event{Type: EventLeft, Time: now, IP: host.IP, MAC: host.MAC, Host: &host, Missed: tracked.Missed})
+	}
+	inv.hosts = next
+	inv.seeded = true
+	return append(events, Event{Type: EventScan, Time: now, Hosts: len(next)})
+}
+
+func (inv *Inventory) misses() int {
+	if inv.Misses <= 0 {
+		return DefaultMisses
+	}
+	return inv.Misses
+}
+
+func changeEvents(change diff.HostChange, now time.Time) []Event {
+	host := change.Host
+	event := func(eventType string) Event {
+		return Event{Type: eventType, Time: now, IP: host.IP, MAC: host.MAC, Host: &host}
+	}
+	var events []Event
+	if change.OldIP != "" {
+		e := event(EventMoved)
+		e.Old, e.New = change.OldIP, host.IP
+		events = append(events, e)
+	}
+	for _, p := range change.Opened {
+		e := event(EventPortOpened)
+		e.Port = &p
+		events = append(events, e)
+	}
+	for _, p := range change.Closed {
+		e := event(EventPortClosed)
+		e.Port = &p
+		events = append(events, e)
+	}
+	for _, b := range change.Banners {
+		e := event(EventBannerChanged)
+		e.Port = &b.Port
+		e.Old, e.New = b.Old, b.New
+		events = append(events, e)
+	}
+	return events
+}
+
+// Round is the outcome of one scan.
+type Round struct {
+	Started  time.Time
+	Finished time.Time
+	Next     time.Time // When the next scan starts.
+	Hosts    []Tracked // The inventory after the scan.
+	Events   []Event
+	Err      error // Failures of the Notifier since the previous round.
+}
+
+// Notifier is told about the events of every finished scan, e.g. to send
+// them on as notifications.
+type Notifier interface {
+	Notify(ctx context.Context, events []Event) error
+}
+
+// Watcher runs a scan every Interval until its context ends.
+type Watcher struct {
+	Scanner   scanner.Scanner
+	Iface     string
+	Targets   string
+	Interval  time.Duration
+	Inventory *Inventory
+	Devices   *inventory.Inventory // Labels the hosts found, nil leaves them as they are.
+	Notifier  Notifier             // Optional.
+}
+
+// Run scans until ctx ends and sends every finished scan to rounds, which
+// it closes on return. A scan cut short by ctx is not merged, its missing
+// hosts would count as misses. The Notifier gets the events after the round
+// is sent, in the background, so a slow sink does not hold up the watch.
+func (w *Watcher) Run(ctx context.Context, rounds chan<- Round) {
+	defer close(rounds)
+	if w.Inventory == nil {
+		w.Inventory = &Inventory{}
+	}
+	var queue *notifyQueue
+	if w.Notifier != nil {
+		queue = startNotify(ctx, w.Notifier)
+		defer queue.stop()
+	}
+	for {
+		started := time.Now()
+		found := w.scan(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		finished := time.Now()
+		round := Round{
+			Started:  started,
+			Finished: finished,
+			Next:     started.Add(w.Interval),
+			Events:   w.Inventory.Update(found, finished),
+			Hosts:    w.Inventory.Hosts(),
+		}
+		if round.Next.Before(finished) {
+			round.Next = finished // The scan took longer than the interval.
+		}
+		if queue != nil {
+			round.Err = queue.failures()
+		}
+		select {
+		case rounds <- round:
+		case <-ctx.Done():
+			return
+		}
+		if queue != nil {
+			queue.push(round.Events)
+		}
+
+		timer := time.NewTimer(time.Until(round.Next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// notifyQueue hands the events of each scan to a Notifier on its own
+// goroutine and keeps the failures for the next round.
+type notifyQueue struct {
+	scans chan []Event
+	done  chan struct{}
+
+	mu     sync.Mutex
+	failed []error
+}
+
+func startNotify(ctx context.Context, notifier Notifier) *notifyQueue {
+	q := &notifyQueue{
+		scans: make(chan []Event, notifyBacklog),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(q.done)
+		for events := range q.scans {
+			sendCtx, cancel := context.WithTimeout(ctx, NotifyTimeout)
+			err := notifier.Notify(sendCtx, events)
+			cancel()
+			if err != nil {
+				q.fail(err)
+			}
+		}
+	}()
+	return q
+}
+
+// push queues the events of a scan, or drops them when the Notifier is too
+// far behind.
+func (q *notifyQueue) push(events []Event) {
+	select {
+	case q.scans <- events:
+	default:
+		q.fail(errors.New("notifications are behind, dropped the events of a scan"))
+	}
+}
+
+func (q *notifyQueue) fail(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.failed = append(q.failed, err)
+}
+
+// failures returns the errors since the last call.
+func (q *notifyQueue) failures() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	err := errors.Join(q.failed...)
+	q.failed = nil
+	return err
+}
+
+// stop waits for the queued scans, which fail fast once ctx has ended.
+func (q *notifyQueue) stop() {
+	close(q.scans)
+	<-q.done
+}
+
+// scan runs one scan and returns the hosts it found, each in its final form.
+func (w *Watcher) scan(ctx context.Context) []scanner.HostResult {
+	progressChan := make(chan scanner.ProgressUpdate, 256)
+	go w.Scanner.ScanNetwork(ctx, w.Iface, w.Targets, progressChan)
+
+	var hosts []scanner.HostResult
+	for update := range progressChan {
+		var host *scanner.HostResult
+		switch p := update.(type) {
+		case scanner.NeighborProgress:
+			host = p.Host
+		case scanner.SweepProgress:
+			host = p.Host
+		}
+		if host != nil {
+			labeled := *host
+			w.Devices.Apply(&labeled)
+			hosts = scanner.UpsertHost(hosts, labeled)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,156 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/backendsystems/nibble/internal/scanner"
+)
+
+func eventTypes(events []Event) []string {
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func sameTypes(got []Event, want ...string) bool {
+	return slices.Equal(eventTypes(got), want)
+}
+
+func TestInventoryUpdate(t *testing.T) {
+	inv := &Inventory{Misses: 2}
+	now := time.Now()
+	router := scanner.HostResult{IP: "192.168.1.1", MAC: "aa:00:00:00:00:01", Ports: []scanner.PortInfo{{Port: 80}}}
+	phone := scanner.HostResult{IP: "192.168.1.20", MAC: "aa:00:00:00:00:20"}
+
+	if events := inv.Update([]scanner.HostResult{router, phone}, now); !sameTypes(events, EventScan) || events[0].Hosts != 2 {
+		t.Fatalf("seeding scan reported %v", eventTypes(events))
+	}
+
+	upgraded := router
+	upgraded.Ports = []scanner.PortInfo{{Port: 443}}
+	plug := scanner.HostResult{IP: "192.168.1.50", MAC: "24:0a:c4:00:00:01"}
+	events := inv.Update([]scanner.HostResult{upgraded, plug}, now)
+	if !sameTypes(events, EventPortOpened, EventPortClosed, EventJoined, EventScan) {
+		t.Fatalf("second scan: %v", eventTypes(events))
+	}
+	if events[0].Port.Port != 443 || events[1].Port.Port != 80 || events[2].IP != plug.IP || events[3].Hosts != 3 {
+		t.Fatalf("second scan events: %+v", events)
+	}
+	if hosts := inv.Hosts(); len(hosts) != 3 || hosts[2].Host.IP != phone.IP || hosts[2].Missed != 1 {
+		t.Fatalf("inventory after one miss: %+v", hosts)
+	}
+
+	// The phone comes back before it runs out of misses, then leaves.
+	if events := inv.Update([]scanner.HostResult{upgraded, plug, phone}, now); !sameTypes(events, EventScan) {
+		t.Fatalf("returning host: %v", eventTypes(events))
+	}
+	inv.Update([]scanner.HostResult{upgraded, plug}, now)
+	events = inv.Update([]scanner.HostResult{upgraded, plug}, now)
+	if !sameTypes(events, EventLeft, EventScan) || events[0].IP != phone.IP || events[0].Missed != 2 || events[1].Hosts != 2 {
+		t.Fatalf("leaving host: %+v", events)
+	}
+}
+
+func TestInventoryUpdateReusedIP(t *testing.T) {
+	inv := &Inventory{Misses: 3}
+	now := time.Now()
+	laptop := scanner.HostResult{IP: "192.168.1.5", MAC: "aa:00:00:00:00:05"}
+	phone := scanner.HostResult{IP: "192.168.1.5", MAC: "bb:00:00:00:00:05"}
+	inv.Update([]scanner.HostResult{laptop}, now)
+
+	// DHCP hands the laptop's address to the phone. Only the laptop misses
+	// scans, the phone is never reported as leaving.
+	var left []Event
+	for round := range 5 {
+		events := inv.Update([]scanner.HostResult{phone}, now)
+		if round == 0 && !sameTypes(events, EventJoined, EventScan) {
+			t.Fatalf("phone joining: %v", eventTypes(events))
+		}
+		for _, e := range events {
+			if e.Type == EventLeft {
+				left = append(left, e)
+			}
+		}
+	}
+	if len(left) != 1 || left[0].MAC != laptop.MAC || left[0].Missed != 3 {
+		t.Fatalf("left events: %+v", left)
+	}
+	if hosts := inv.Hosts(); len(hosts) != 1 || hosts[0].Host.MAC != phone.MAC || hosts[0].Missed != 0 {
+		t.Fatalf("inventory: %+v", hosts)
+	}
+}
+
+// sequenceScanner finds the next host list on every scan.
+type sequenceScanner struct {
+	scans [][]scanner.HostResult
+	n     int
+}
+
+func (s *sequenceScanner) ScanNetwork(ctx context.Context, ifaceName, spec string, progressChan chan<- scanner.ProgressUpdate) {
+	defer close(progressChan)
+	hosts := s.scans[min(s.n, len(s.scans)-1)]
+	s.n++
+	for i := range hosts {
+		progressChan <- scanner.SweepProgress{Host: &hosts[i]}
+	}
+}
+
+// failingNotifier keeps the events it is told about and fails to send them,
+// once release is closed.
+type failingNotifier struct {
+	release chan struct{}
+	events  []Event
+}
+
+func (n *failingNotifier) Notify(ctx context.Context, events []Event) error {
+	<-n.release
+	n.events = append(n.events, events...)
+	return errors.New("webhook down")
+}
+
+func TestWatcherRun(t *testing.T) {
+	nas := scanner.HostResult{IP: "192.168.1.5", MAC: "aa:00:00:00:00:05"}
+	camera := scanner.HostResult{IP: "192.168.1.9", MAC: "aa:00:00:00:00:09"}
+	notifier := &failingNotifier{release: make(chan struct{})}
+	w := &Watcher{
+		Scanner:   &sequenceScanner{scans: [][]scanner.HostResult{{nas}, {nas, camera}}},
+		Interval:  time.Millisecond,
+		Inventory: &Inventory{},
+		Notifier:  notifier,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rounds := make(chan Round)
+	go w.Run(ctx, rounds)
+
+	// A stuck notifier does not hold back the rounds.
+	first := <-rounds
+	second := <-rounds
+	if !sameTypes(first.Events, EventScan) || len(first.Hosts) != 1 {
+		t.Fatalf("first round: %+v", first)
+	}
+	if !sameTypes(second.Events, EventJoined, EventScan) || second.Events[0].IP != camera.IP || len(second.Hosts) != 2 {
+		t.Fatalf("second round: %+v", second)
+	}
+
+	// Its failures show up in a later round.
+	close(notifier.release)
+	var failed error
+	for round := range rounds {
+		if failed = round.Err; failed != nil {
+			break
+		}
+	}
+	cancel()
+	for range rounds {
+	}
+	if notified := notifier.events; len(notified) < 3 || !sameTypes(notified[:3], EventScan, EventJoined, EventScan) || failed == nil {
+		t.Fatalf("notified %v, error %v", eventTypes(notified), failed)
+	}
+}
@@ -1,24 +1,62 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
+	"time"
 
+	"github.com/backendsystems/nibble/internal/cli"
 	"github.com/backendsystems/nibble/internal/demo"
 	"github.com/backendsystems/nibble/internal/scan"
 	"github.com/backendsystems/nibble/internal/scanner"
 	"github.com/backendsystems/nibble/internal/tui"
+	"github.com/backendsystems/nibble/internal/watch"
 )
 
 var version = "dev"
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "history":
+			runCommand(historyCommand(os.Args[2:]))
+			return
+		case "diff":
+			runCommand(diffCommand(os.Args[2:]))
+			return
+		case "watch":
+			runCommand(watchCommand(os.Args[2:]))
+			return
+		case "notify":
+			runCommand(notifyCommand(os.Args[2:]))
+			return
+		}
+	}
+
 	var demoMode bool
 	var showVersion bool
+	var scanOpts cli.Options
+	var arpSweep, pingSweep, mdnsBrowse, ssdpDiscover bool
+	var passive time.Duration
+	var capturePath string
 	flag.BoolVar(&demoMode, "demo", false, "use demo interfaces")
 	flag.BoolVar(&showVersion, "version", false, "print version and exit")
+	flag.StringVar(&scanOpts.Iface, "iface", "", "scan this interface without the TUI")
+	flag.StringVar(&scanOpts.Targets, "target", "", "scan these targets without the TUI: CIDR, a.b.c.d-e, comma list, hostname or @file")
+	flag.StringVar(&scanOpts.Targets, "cidr", "", "alias for --target")
+	flag.StringVar(&scanOpts.Exclude, "exclude", "", "never probe these IPs, CIDRs or MAC prefixes, added to the saved exclude list")
+	flag.BoolVar(&scanOpts.Confirm, "confirm-large", false, "allow headless sweeps larger than the saved host limit")
+	flag.BoolVar(&arpSweep, "arp", false, "broadcast ARP across the subnet to find hosts with no open ports (needs CAP_NET_RAW)")
+	flag.BoolVar(&pingSweep, "ping", false, "ping every address first and show round trip times")
+	flag.BoolVar(&mdnsBrowse, "mdns", false, "browse mDNS/DNS-SD services to find advertising devices and show their services")
+	flag.BoolVar(&ssdpDiscover, "ssdp", false, "send an SSDP M-SEARCH and show each UPnP device's name, manufacturer and model")
+	flag.DurationVar(&passive, "passive", 0, "only listen for ARP, DHCP, mDNS, SSDP, LLMNR and NetBIOS traffic for this long, e.g. 2m, without probing")
+	flag.StringVar(&capturePath, "pcap", "", "read hosts from a pcap or pcapng capture instead of the network, without the TUI")
+	flag.StringVar(&scanOpts.Output, "output", cli.OutputText, "headless output format: text, json or jsonl")
 	flag.Parse()
 
 	if showVersion {
@@ -26,6 +64,46 @@ func main() {
 		return
 	}
 
+	if capturePath != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := cli.Analyze(ctx, capturePath, scanOpts, os.Stdout)
+		stop()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ifaces, addrsByIface := loadInterfaces(demoMode)
+
+	var networkScanner scanner.Scanner
+	if demoMode {
+		networkScanner = &demo.DemoScanner{}
+	} else {
+		networkScanner = &scan.NetScanner{ARPSweep: arpSweep, PingSweep: pingSweep, MDNSBrowse: mdnsBrowse, SSDPDiscover: ssdpDiscover, Passive: passive}
+	}
+
+	if scanOpts.Iface != "" || scanOpts.Targets != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := cli.Scan(ctx, networkScanner, ifaces, addrsByIface, scanOpts, os.Stdout)
+		stop()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := tui.Run(networkScanner, ifaces, addrsByIface); err != nil {
+		fmt.Printf("Error starting the program: %v", err)
+		os.Exit(1)
+	}
+}
+
+// loadInterfaces returns the interfaces to scan, or the fake demo ones,
+// and exits when there are none.
+func loadInterfaces(demoMode bool) ([]net.Interface, map[string][]net.Addr) {
 	var ifaces []net.Interface
 	var addrsByIface map[string][]net.Addr
 
@@ -48,19 +126,120 @@ func main() {
 	}
 
 	if len(ifaces) == 0 {
-		fmt.Println("No valid network interfaces found with IPv4 addresses")
+		fmt.Println("No valid network interfaces found with IPv4 or IPv6 addresses")
 		os.Exit(1)
 	}
+	return ifaces, addrsByIface
+}
+
+// runCommand exits non-zero when a subcommand failed.
+func runCommand(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// historyCommand lists saved scans, or prints one: nibble history [id].
+func historyCommand(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	output := flags.String("output", cli.OutputText, "output format for a scan's hosts: text, json or jsonl")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: nibble history [--output text|json|jsonl] [scan id or latest~N]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	return cli.History(flags.Arg(0), *output, os.Stdout)
+}
 
+// diffCommand compares two saved scans: nibble diff older newer.
+func diffCommand(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	output := flags.String("output", cli.OutputText, "output format: text or json")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: nibble diff [--output text|json] <older scan> <newer scan>")
+		fmt.Fprintln(flags.Output(), "Scans are IDs from nibble history, or latest and latest~N.")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	switch flags.NArg() {
+	case 0:
+		return cli.Diff("latest~1", "latest", *output, os.Stdout)
+	case 2:
+		return cli.Diff(flags.Arg(0), flags.Arg(1), *output, os.Stdout)
+	default:
+		flags.Usage()
+		os.Exit(2)
+		return nil
+	}
+}
+
+// notifyCommand checks the notification setup: nibble notify test.
+func notifyCommand(args []string) error {
+	flags := flag.NewFlagSet("notify", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: nibble notify test")
+		fmt.Fprintln(flags.Output(), "Sends a test event to every webhook, command and desktop sink in notify.json.")
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 || flags.Arg(0) != "test" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return cli.NotifyTest(ctx, os.Stdout)
+}
+
+// watchCommand rescans a network on an interval and reports what changed:
+// nibble watch --iface eth0 --interval 5m.
+func watchCommand(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	var opts cli.Options
+	flags.StringVar(&opts.Iface, "iface", "", "watch the subnet of this interface")
+	flags.StringVar(&opts.Targets, "target", "", "watch these targets: CIDR, a.b.c.d-e, comma list, hostname or @file")
+	flags.StringVar(&opts.Targets, "cidr", "", "alias for --target")
+	flags.StringVar(&opts.Exclude, "exclude", "", "never probe these IPs, CIDRs or MAC prefixes, added to the saved exclude list")
+	flags.BoolVar(&opts.Confirm, "confirm-large", false, "allow sweeps larger than the saved host limit")
+	demoMode := flags.Bool("demo", false, "watch the demo network")
+	arpSweep := flags.Bool("arp", false, "broadcast ARP across the subnet on every scan (needs CAP_NET_RAW)")
+	pingSweep := flags.Bool("ping", false, "ping every address first on every scan")
+	mdnsBrowse := flags.Bool("mdns", false, "browse mDNS/DNS-SD services on every scan")
+	ssdpDiscover := flags.Bool("ssdp", false, "send an SSDP M-SEARCH on every scan")
+	interval := flags.Duration("interval", 5*time.Minute, "time from the start of one scan to the start of the next")
+	misses := flags.Int("misses", watch.DefaultMisses, "scans in a row a host must be missing from before it is reported as left")
+	dashboard := flags.Bool("tui", false, "show a live dashboard instead of writing JSON lines")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: nibble watch [--iface name | --target spec] [--interval 5m] [--misses 3] [--tui]")
+		fmt.Fprintln(flags.Output(), "Writes one JSON line per event: host_joined, host_left, host_moved, port_opened, port_closed, banner_changed and scan.")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() > 0 || (opts.Iface == "" && opts.Targets == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ifaces, addrsByIface := loadInterfaces(*demoMode)
 	var networkScanner scanner.Scanner
-	if demoMode {
+	if *demoMode {
 		networkScanner = &demo.DemoScanner{}
 	} else {
-		networkScanner = &scan.NetScanner{}
+		networkScanner = &scan.NetScanner{ARPSweep: *arpSweep, PingSweep: *pingSweep, MDNSBrowse: *mdnsBrowse, SSDPDiscover: *ssdpDiscover}
 	}
-
-	if err := tui.Run(networkScanner, ifaces, addrsByIface); err != nil {
-		fmt.Printf("Error starting the program: %v", err)
-		os.Exit(1)
+	w, err := cli.NewWatcher(networkScanner, ifaces, addrsByIface, opts, *interval, *misses)
+	if err != nil {
+		return err
+	}
+	if *dashboard {
+		return tui.Watch(w)
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return cli.Watch(ctx, w, os.Stdout, os.Stderr)
 }